@@ -0,0 +1,334 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: metrics/v1/metrics.proto
+
+package metricsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GatherRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// names 只返回指定名称的指标族，为空时返回全部
+	Names         []string `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GatherRequest) Reset() {
+	*x = GatherRequest{}
+	mi := &file_metrics_v1_metrics_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GatherRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GatherRequest) ProtoMessage() {}
+
+func (x *GatherRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_metrics_v1_metrics_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GatherRequest.ProtoReflect.Descriptor instead.
+func (*GatherRequest) Descriptor() ([]byte, []int) {
+	return file_metrics_v1_metrics_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GatherRequest) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+type GatherResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// text Prometheus 文本格式，与 GET /metrics 的响应相同
+	Text string `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	// families 结构化的指标族
+	Families      []*MetricFamily `protobuf:"bytes,2,rep,name=families,proto3" json:"families,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GatherResponse) Reset() {
+	*x = GatherResponse{}
+	mi := &file_metrics_v1_metrics_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GatherResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GatherResponse) ProtoMessage() {}
+
+func (x *GatherResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_metrics_v1_metrics_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GatherResponse.ProtoReflect.Descriptor instead.
+func (*GatherResponse) Descriptor() ([]byte, []int) {
+	return file_metrics_v1_metrics_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GatherResponse) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *GatherResponse) GetFamilies() []*MetricFamily {
+	if x != nil {
+		return x.Families
+	}
+	return nil
+}
+
+type MetricFamily struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Help  string                 `protobuf:"bytes,2,opt,name=help,proto3" json:"help,omitempty"`
+	// type counter、gauge、summary、histogram、gaugehistogram 或 untyped
+	Type          string    `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Samples       []*Sample `protobuf:"bytes,4,rep,name=samples,proto3" json:"samples,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MetricFamily) Reset() {
+	*x = MetricFamily{}
+	mi := &file_metrics_v1_metrics_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetricFamily) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetricFamily) ProtoMessage() {}
+
+func (x *MetricFamily) ProtoReflect() protoreflect.Message {
+	mi := &file_metrics_v1_metrics_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetricFamily.ProtoReflect.Descriptor instead.
+func (*MetricFamily) Descriptor() ([]byte, []int) {
+	return file_metrics_v1_metrics_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *MetricFamily) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MetricFamily) GetHelp() string {
+	if x != nil {
+		return x.Help
+	}
+	return ""
+}
+
+func (x *MetricFamily) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *MetricFamily) GetSamples() []*Sample {
+	if x != nil {
+		return x.Samples
+	}
+	return nil
+}
+
+type Sample struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Labels map[string]string      `protobuf:"bytes,1,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// value counter、gauge、untyped 为当前值，summary、histogram 为观测值之和
+	Value float64 `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	// count summary、histogram 的观测次数
+	Count         uint64 `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sample) Reset() {
+	*x = Sample{}
+	mi := &file_metrics_v1_metrics_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sample) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sample) ProtoMessage() {}
+
+func (x *Sample) ProtoReflect() protoreflect.Message {
+	mi := &file_metrics_v1_metrics_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sample.ProtoReflect.Descriptor instead.
+func (*Sample) Descriptor() ([]byte, []int) {
+	return file_metrics_v1_metrics_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Sample) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Sample) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *Sample) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+var File_metrics_v1_metrics_proto protoreflect.FileDescriptor
+
+const file_metrics_v1_metrics_proto_rawDesc = "" +
+	"\n" +
+	"\x18metrics/v1/metrics.proto\x12\x0fbase.metrics.v1\"%\n" +
+	"\rGatherRequest\x12\x14\n" +
+	"\x05names\x18\x01 \x03(\tR\x05names\"_\n" +
+	"\x0eGatherResponse\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x129\n" +
+	"\bfamilies\x18\x02 \x03(\v2\x1d.base.metrics.v1.MetricFamilyR\bfamilies\"}\n" +
+	"\fMetricFamily\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04help\x18\x02 \x01(\tR\x04help\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x121\n" +
+	"\asamples\x18\x04 \x03(\v2\x17.base.metrics.v1.SampleR\asamples\"\xac\x01\n" +
+	"\x06Sample\x12;\n" +
+	"\x06labels\x18\x01 \x03(\v2#.base.metrics.v1.Sample.LabelsEntryR\x06labels\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x04R\x05count\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012[\n" +
+	"\x0eMetricsService\x12I\n" +
+	"\x06Gather\x12\x1e.base.metrics.v1.GatherRequest\x1a\x1f.base.metrics.v1.GatherResponseB;Z9github.com/lyonmu/demo/base-demo/api/metrics/v1;metricsv1b\x06proto3"
+
+var (
+	file_metrics_v1_metrics_proto_rawDescOnce sync.Once
+	file_metrics_v1_metrics_proto_rawDescData []byte
+)
+
+func file_metrics_v1_metrics_proto_rawDescGZIP() []byte {
+	file_metrics_v1_metrics_proto_rawDescOnce.Do(func() {
+		file_metrics_v1_metrics_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_metrics_v1_metrics_proto_rawDesc), len(file_metrics_v1_metrics_proto_rawDesc)))
+	})
+	return file_metrics_v1_metrics_proto_rawDescData
+}
+
+var file_metrics_v1_metrics_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_metrics_v1_metrics_proto_goTypes = []any{
+	(*GatherRequest)(nil),  // 0: base.metrics.v1.GatherRequest
+	(*GatherResponse)(nil), // 1: base.metrics.v1.GatherResponse
+	(*MetricFamily)(nil),   // 2: base.metrics.v1.MetricFamily
+	(*Sample)(nil),         // 3: base.metrics.v1.Sample
+	nil,                    // 4: base.metrics.v1.Sample.LabelsEntry
+}
+var file_metrics_v1_metrics_proto_depIdxs = []int32{
+	2, // 0: base.metrics.v1.GatherResponse.families:type_name -> base.metrics.v1.MetricFamily
+	3, // 1: base.metrics.v1.MetricFamily.samples:type_name -> base.metrics.v1.Sample
+	4, // 2: base.metrics.v1.Sample.labels:type_name -> base.metrics.v1.Sample.LabelsEntry
+	0, // 3: base.metrics.v1.MetricsService.Gather:input_type -> base.metrics.v1.GatherRequest
+	1, // 4: base.metrics.v1.MetricsService.Gather:output_type -> base.metrics.v1.GatherResponse
+	4, // [4:5] is the sub-list for method output_type
+	3, // [3:4] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_metrics_v1_metrics_proto_init() }
+func file_metrics_v1_metrics_proto_init() {
+	if File_metrics_v1_metrics_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_metrics_v1_metrics_proto_rawDesc), len(file_metrics_v1_metrics_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_metrics_v1_metrics_proto_goTypes,
+		DependencyIndexes: file_metrics_v1_metrics_proto_depIdxs,
+		MessageInfos:      file_metrics_v1_metrics_proto_msgTypes,
+	}.Build()
+	File_metrics_v1_metrics_proto = out.File
+	file_metrics_v1_metrics_proto_goTypes = nil
+	file_metrics_v1_metrics_proto_depIdxs = nil
+}
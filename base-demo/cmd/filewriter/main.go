@@ -0,0 +1,130 @@
+// filewriter 使用 internal/file 的并发写入将 -src 复制到 -dst，是一个运行后即退出的批处理任务；
+// 进程来不及被 Prometheus 抓取，结束前按 METRICS_PUSH_URL / METRICS_REMOTE_WRITE_URL 推送一次 demo_file_* 等指标
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/internal/file"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/base-demo/pkg/metrics"
+)
+
+func main() {
+	src := flag.String("src", "", "source file")
+	dst := flag.String("dst", "", "destination file")
+	checksum := flag.String("checksum", "", "chunk checksum algorithm: crc32c, xxhash64 or sha256")
+	atomic := flag.Bool("atomic", false, "write to a temp file and rename on success")
+	resume := flag.Bool("resume", false, "resume from <dst>.journal, only copying missing ranges")
+	flag.Parse()
+
+	logger.Init(logger.OptionsFromEnv())
+
+	if *src == "" || *dst == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	exportOpts, err := metrics.ExportOptionsFromEnv()
+	if err != nil {
+		slog.Error("Invalid metrics export config", logger.Err(err))
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	opts := file.WriteOptions{Resume: *resume, Checksum: file.Algorithm(*checksum), Atomic: *atomic}
+	start := time.Now()
+	err = copyFile(ctx, *src, *dst, opts)
+
+	// 失败时同样推送，写入错误与校验失败计数也需要被记录
+	exportCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if exportErr := metrics.Export(exportCtx, exportOpts); exportErr != nil {
+		slog.Error("Failed to export metrics", logger.Err(exportErr))
+	}
+
+	if err != nil {
+		slog.Error("Failed to write file", slog.String("dst", *dst), logger.Err(err))
+		os.Exit(1)
+	}
+	slog.Info("File written", slog.String("dst", *dst), slog.Duration("elapsed", time.Since(start)))
+}
+
+// copyFile 按 file.ChunkSize 读取 src 的分片交给 WriteFileWithOptions；续传时只读取仍缺失的区间
+func copyFile(ctx context.Context, src, dst string, opts file.WriteOptions) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+
+	ranges := []file.Range{{Offset: 0, Length: size}}
+	if opts.Resume {
+		if ranges, err = file.MissingRanges(dst, size, opts); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chunks := make(chan file.Chunk)
+	readErr := make(chan error, 1)
+	go func() {
+		err := readChunks(ctx, f, ranges, opts.Checksum, chunks)
+		if err != nil {
+			// 读取失败时不关闭 chunks 而是取消写入，避免把不完整的数据当作全部分片提交
+			cancel()
+		} else {
+			close(chunks)
+		}
+		readErr <- err
+	}()
+
+	err = file.WriteFileWithOptions(ctx, dst, chunks, size, opts)
+	// 写入先失败时停止读取
+	cancel()
+	if rerr := <-readErr; rerr != nil && !errors.Is(rerr, context.Canceled) {
+		return rerr
+	}
+	return err
+}
+
+// readChunks 将 ranges 切分为不超过 file.ChunkSize 的分片发送到 chunks，alg 不为空时填充校验和
+func readChunks(ctx context.Context, f *os.File, ranges []file.Range, alg file.Algorithm, chunks chan<- file.Chunk) error {
+	for _, r := range ranges {
+		for off := r.Offset; off < r.End(); off += file.ChunkSize {
+			buf := make([]byte, min(file.ChunkSize, r.End()-off))
+			if _, err := f.ReadAt(buf, off); err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			c := file.Chunk{Offset: off, Data: buf}
+			if alg != "" {
+				sum, err := alg.Sum(buf)
+				if err != nil {
+					return err
+				}
+				c.Checksum = sum
+			}
+			select {
+			case chunks <- c:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Package diag 提供受保护的运行时诊断接口：pprof、goroutine 转储、GC / 堆统计、构建信息，
+// 以及按需采集 CPU / 堆 profile 到磁盘（按数量与时长保留）。
+//
+// 诊断接口可以挂载到主端口（必须配置管理员令牌），也可以监听独立地址（未配置令牌时只允许回环地址），
+// 运行时通过 PUT /debug/enabled 启用或关闭，关闭时除该接口外均返回 404
+package diag
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyonmu/demo/base-demo/internal/lifecycle"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+)
+
+// Prefix 诊断接口的路径前缀
+const Prefix = "/debug"
+
+// Options 诊断接口配置
+type Options struct {
+	// Enabled 启动时是否启用，运行时可切换
+	Enabled bool
+	// Token 管理员令牌，请求需携带 Authorization: Bearer <token>；为空时只接受回环地址的请求
+	Token string
+	// Addr 独立监听地址，例如 127.0.0.1:6060；为空时挂载到主端口，此时必须配置 Token
+	Addr string
+	// ProfileDir 采集的 profile 保存目录，默认 <TempDir>/base-demo-profiles
+	ProfileDir string
+	// MaxProfiles 最多保留的 profile 文件数，默认 10
+	MaxProfiles int
+	// MaxProfileAge 超过该时长的 profile 文件被删除，默认 72h
+	MaxProfileAge time.Duration
+	// MaxCPUDuration 单次 CPU 采集的时长上限，默认 60s
+	MaxCPUDuration time.Duration
+}
+
+// OptionsFromEnv 从环境变量读取诊断配置：DIAG_ENABLED、DIAG_TOKEN、DIAG_ADDR、
+// DIAG_PROFILE_DIR、DIAG_PROFILE_MAX、DIAG_PROFILE_MAX_AGE
+func OptionsFromEnv() (Options, error) {
+	opts := Options{
+		Token:      os.Getenv("DIAG_TOKEN"),
+		Addr:       os.Getenv("DIAG_ADDR"),
+		ProfileDir: os.Getenv("DIAG_PROFILE_DIR"),
+	}
+	if v := os.Getenv("DIAG_ENABLED"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return Options{}, fmt.Errorf("DIAG_ENABLED: %w", err)
+		}
+		opts.Enabled = enabled
+	}
+	if v := os.Getenv("DIAG_PROFILE_MAX"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Options{}, fmt.Errorf("DIAG_PROFILE_MAX: %w", err)
+		}
+		opts.MaxProfiles = n
+	}
+	if v := os.Getenv("DIAG_PROFILE_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Options{}, fmt.Errorf("DIAG_PROFILE_MAX_AGE: %w", err)
+		}
+		opts.MaxProfileAge = d
+	}
+	return opts, nil
+}
+
+// Diagnostics 诊断接口，实现 http.Handler
+type Diagnostics struct {
+	opts    Options
+	enabled atomic.Bool
+	mux     *http.ServeMux
+	// capturing 同一时间只允许一次 profile 采集
+	capturing atomic.Bool
+}
+
+// New 校验访问保护并创建 profile 目录
+func New(opts Options) (*Diagnostics, error) {
+	if opts.Token == "" {
+		if opts.Addr == "" {
+			return nil, errors.New("diagnostics on the main port require an admin token")
+		}
+		if !isLoopbackAddr(opts.Addr) {
+			return nil, fmt.Errorf("diagnostics without an admin token must bind to a loopback address, got %q", opts.Addr)
+		}
+	}
+	if opts.ProfileDir == "" {
+		opts.ProfileDir = filepath.Join(os.TempDir(), "base-demo-profiles")
+	}
+	if opts.MaxProfiles <= 0 {
+		opts.MaxProfiles = 10
+	}
+	if opts.MaxProfileAge <= 0 {
+		opts.MaxProfileAge = 72 * time.Hour
+	}
+	if opts.MaxCPUDuration <= 0 {
+		opts.MaxCPUDuration = time.Minute
+	}
+	if err := os.MkdirAll(opts.ProfileDir, 0o700); err != nil {
+		return nil, fmt.Errorf("create profile dir: %w", err)
+	}
+
+	d := &Diagnostics{opts: opts, mux: http.NewServeMux()}
+	d.enabled.Store(opts.Enabled)
+
+	d.mux.HandleFunc("GET "+Prefix+"/enabled", d.getEnabled)
+	d.mux.HandleFunc("PUT "+Prefix+"/enabled", d.setEnabled)
+
+	d.mux.HandleFunc(Prefix+"/pprof/", pprof.Index)
+	d.mux.HandleFunc(Prefix+"/pprof/cmdline", pprof.Cmdline)
+	d.mux.HandleFunc(Prefix+"/pprof/profile", pprof.Profile)
+	d.mux.HandleFunc(Prefix+"/pprof/symbol", pprof.Symbol)
+	d.mux.HandleFunc(Prefix+"/pprof/trace", pprof.Trace)
+
+	d.mux.HandleFunc("GET "+Prefix+"/goroutines", goroutines)
+	d.mux.HandleFunc("GET "+Prefix+"/runtime", runtimeStats)
+	d.mux.HandleFunc("GET "+Prefix+"/build", buildInfo)
+
+	d.mux.HandleFunc("POST "+Prefix+"/profiles/cpu", d.captureCPU)
+	d.mux.HandleFunc("POST "+Prefix+"/profiles/heap", d.captureHeap)
+	d.mux.HandleFunc("GET "+Prefix+"/profiles", d.listProfiles)
+	d.mux.HandleFunc("GET "+Prefix+"/profiles/{name}", d.downloadProfile)
+
+	// 启动时按保留策略清理上次运行留下的文件
+	if err := d.prune(); err != nil {
+		slog.Warn("Failed to prune profiles", logger.Err(err))
+	}
+	return d, nil
+}
+
+// Enabled 返回当前是否启用
+func (d *Diagnostics) Enabled() bool {
+	return d.enabled.Load()
+}
+
+// SetEnabled 运行时启用或关闭诊断接口
+func (d *Diagnostics) SetEnabled(enabled bool) {
+	if d.enabled.Swap(enabled) != enabled {
+		slog.Info("Diagnostics toggled", slog.Bool("enabled", enabled))
+	}
+}
+
+// ServeHTTP 先鉴权，再检查是否启用
+func (d *Diagnostics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !d.authorized(r) {
+		if d.opts.Token != "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="diagnostics"`)
+		}
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+	if r.URL.Path != Prefix+"/enabled" && !d.enabled.Load() {
+		writeError(w, http.StatusNotFound, errors.New("diagnostics disabled"))
+		return
+	}
+	d.mux.ServeHTTP(w, r)
+}
+
+// authorized 配置了令牌时校验令牌，否则只接受回环地址（独立监听地址已限制为回环地址，这里再做一次校验）
+func (d *Diagnostics) authorized(r *http.Request) bool {
+	if d.opts.Token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return ok && subtle.ConstantTimeCompare([]byte(token), []byte(d.opts.Token)) == 1
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Mount 将诊断接口挂载到 gin 引擎上，路径为 /debug/*
+func (d *Diagnostics) Mount(r gin.IRoutes) {
+	r.Any(Prefix+"/*path", gin.WrapH(d))
+}
+
+// Listen 在独立地址上监听，返回的服务由调用方启动与关闭
+func (d *Diagnostics) Listen() (lifecycle.Server, error) {
+	l, err := net.Listen("tcp", d.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Diagnostics listening", slog.String("addr", l.Addr().String()), slog.Bool("enabled", d.Enabled()))
+	return lifecycle.HTTP(&http.Server{Handler: d, ReadHeaderTimeout: 5 * time.Second}, l), nil
+}
+
+func (d *Diagnostics) getEnabled(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": d.enabled.Load()})
+}
+
+func (d *Diagnostics) setEnabled(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&body); err != nil || body.Enabled == nil {
+		writeError(w, http.StatusBadRequest, errors.New(`body must be {"enabled": true|false}`))
+		return
+	}
+	d.SetEnabled(*body.Enabled)
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": *body.Enabled})
+}
+
+// isLoopbackAddr 判断监听地址是否为回环地址，localhost 视为回环
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,165 @@
+package diag
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const token = "s3cret"
+
+func do(t *testing.T, h http.Handler, method, path, body string, auth bool) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if auth {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNewRequiresProtection(t *testing.T) {
+	for _, opts := range []Options{
+		{},
+		{Addr: ":6060"},
+		{Addr: "0.0.0.0:6060"},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("%+v: 未配置令牌且未绑定回环地址时应返回错误", opts)
+		}
+	}
+	for _, opts := range []Options{
+		{Token: token},
+		{Addr: "127.0.0.1:6060"},
+		{Addr: "localhost:6060"},
+		{Addr: "[::1]:6060"},
+	} {
+		opts.ProfileDir = t.TempDir()
+		if _, err := New(opts); err != nil {
+			t.Errorf("%+v: %v", opts, err)
+		}
+	}
+}
+
+func TestAuthAndToggle(t *testing.T) {
+	d, err := New(Options{Token: token, ProfileDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := do(t, d, http.MethodGet, "/debug/runtime", "", false); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("未携带令牌应返回 401: %d", rec.Code)
+	}
+	if rec := do(t, d, http.MethodGet, "/debug/runtime", "", true); rec.Code != http.StatusNotFound {
+		t.Fatalf("未启用时应返回 404: %d", rec.Code)
+	}
+	if rec := do(t, d, http.MethodPut, "/debug/enabled", `{"enabled": true}`, false); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("切换开关同样需要令牌: %d", rec.Code)
+	}
+	if rec := do(t, d, http.MethodPut, "/debug/enabled", `{}`, true); rec.Code != http.StatusBadRequest {
+		t.Fatalf("缺少 enabled 字段应返回 400: %d", rec.Code)
+	}
+	if rec := do(t, d, http.MethodPut, "/debug/enabled", `{"enabled": true}`, true); rec.Code != http.StatusOK || !d.Enabled() {
+		t.Fatalf("启用失败: %d", rec.Code)
+	}
+
+	for path, want := range map[string]string{
+		"/debug/runtime":             `"heap_alloc"`,
+		"/debug/build":               `"go_version"`,
+		"/debug/goroutines":          "goroutine ",
+		"/debug/pprof/":              "goroutine",
+		"/debug/pprof/cmdline":       "",
+		"/debug/pprof/heap?debug=1":  "heap profile",
+		"/debug/pprof/goroutine":     "",
+		"/debug/pprof/threadcreate":  "",
+		"/debug/pprof/allocs?debug=": "",
+	} {
+		rec := do(t, d, http.MethodGet, path, "", true)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("%s: %d %.200s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	d.SetEnabled(false)
+	if rec := do(t, d, http.MethodGet, "/debug/pprof/", "", true); rec.Code != http.StatusNotFound {
+		t.Fatalf("关闭后应返回 404: %d", rec.Code)
+	}
+	if rec := do(t, d, http.MethodGet, "/debug/enabled", "", true); !strings.Contains(rec.Body.String(), `"enabled": false`) {
+		t.Fatalf("状态错误: %s", rec.Body.String())
+	}
+}
+
+func TestLoopbackOnly(t *testing.T) {
+	d, err := New(Options{Enabled: true, Addr: "127.0.0.1:0", ProfileDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/debug/build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("回环地址的请求应放行: %d", resp.StatusCode)
+	}
+
+	// httptest.NewRequest 的 RemoteAddr 为 192.0.2.1
+	if rec := do(t, d, http.MethodGet, "/debug/build", "", false); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("非回环地址的请求应拒绝: %d", rec.Code)
+	}
+}
+
+func TestCaptureAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	d, err := New(Options{Enabled: true, Token: token, ProfileDir: dir, MaxProfiles: 2, MaxCPUDuration: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := do(t, d, http.MethodPost, "/debug/profiles/cpu?seconds=5", "", true); rec.Code != http.StatusBadRequest {
+		t.Fatalf("超过时长上限应返回 400: %d", rec.Code)
+	}
+	var names []string
+	for _, path := range []string{"/debug/profiles/cpu?seconds=1", "/debug/profiles/heap?gc=1", "/debug/profiles/heap"} {
+		rec := do(t, d, http.MethodPost, path, "", true)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("%s: %d %s", path, rec.Code, rec.Body.String())
+		}
+		var p profileInfo
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil || p.Size == 0 {
+			t.Fatalf("%s: 返回内容错误 %s", path, rec.Body.String())
+		}
+		names = append(names, p.Name)
+		// 文件名精确到毫秒，修改时间用于排序
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 只保留最新的 2 个，最早的 CPU profile 被删除
+	var listed []profileInfo
+	if err := json.Unmarshal(do(t, d, http.MethodGet, "/debug/profiles", "", true).Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 || listed[0].Name != names[2] || listed[1].Name != names[1] {
+		t.Fatalf("保留策略错误: %+v", listed)
+	}
+	if _, err := os.Stat(dir + "/" + names[0]); !os.IsNotExist(err) {
+		t.Fatalf("超出数量的 profile 应被删除: %v", err)
+	}
+
+	rec := do(t, d, http.MethodGet, "/debug/profiles/"+names[2], "", true)
+	if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+		t.Fatalf("下载失败: %d", rec.Code)
+	}
+	for _, name := range []string{"..%2Fsecret.pprof", "other.pprof", names[0]} {
+		if rec := do(t, d, http.MethodGet, "/debug/profiles/"+name, "", true); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: 应返回 404，实际 %d", name, rec.Code)
+		}
+	}
+}
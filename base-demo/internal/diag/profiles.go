@@ -0,0 +1,190 @@
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+)
+
+const (
+	profileExt = ".pprof"
+	// defaultCPUDuration 未指定 seconds 时的 CPU 采集时长
+	defaultCPUDuration = 10 * time.Second
+)
+
+// profileInfo 已保存的 profile 文件
+type profileInfo struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"time"`
+}
+
+// captureCPU 采集 CPU profile 并保存到磁盘，seconds 参数指定时长（默认 10s），请求在采集结束后返回
+func (d *Diagnostics) captureCPU(w http.ResponseWriter, r *http.Request) {
+	duration := defaultCPUDuration
+	if v := r.URL.Query().Get("seconds"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid seconds %q", v))
+			return
+		}
+		duration = time.Duration(n) * time.Second
+	}
+	if duration > d.opts.MaxCPUDuration {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("seconds exceeds limit %s", d.opts.MaxCPUDuration))
+		return
+	}
+
+	d.capture(w, "cpu", func(out io.Writer) error {
+		// /debug/pprof/profile 正在采集时 StartCPUProfile 返回错误
+		if err := pprof.StartCPUProfile(out); err != nil {
+			return errBusy
+		}
+		select {
+		case <-time.After(duration):
+		case <-r.Context().Done():
+		}
+		pprof.StopCPUProfile()
+		return r.Context().Err()
+	})
+}
+
+// captureHeap 采集堆 profile 并保存到磁盘，gc=1 时先执行一次 GC，使 inuse 数据反映最新状态
+func (d *Diagnostics) captureHeap(w http.ResponseWriter, r *http.Request) {
+	gc, _ := strconv.ParseBool(r.URL.Query().Get("gc"))
+	d.capture(w, "heap", func(out io.Writer) error {
+		if gc {
+			runtime.GC()
+		}
+		return pprof.Lookup("heap").WriteTo(out, 0)
+	})
+}
+
+var errBusy = errors.New("another profile capture is in progress")
+
+// capture 写入临时文件，成功后重命名为 <kind>-<时间>.pprof，再按保留策略清理旧文件
+func (d *Diagnostics) capture(w http.ResponseWriter, kind string, write func(io.Writer) error) {
+	if !d.capturing.CompareAndSwap(false, true) {
+		writeError(w, http.StatusConflict, errBusy)
+		return
+	}
+	defer d.capturing.Store(false)
+
+	tmp, err := os.CreateTemp(d.opts.ProfileDir, "."+kind+"-*.tmp")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	err = write(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errBusy) {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	name := kind + "-" + time.Now().UTC().Format("20060102T150405.000Z") + profileExt
+	if err := os.Rename(tmp.Name(), filepath.Join(d.opts.ProfileDir, name)); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := d.prune(); err != nil {
+		slog.Warn("Failed to prune profiles", logger.Err(err))
+	}
+
+	info, err := os.Stat(filepath.Join(d.opts.ProfileDir, name))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	slog.Info("Captured profile", slog.String("name", name), slog.Int64("size", info.Size()))
+	writeJSON(w, http.StatusCreated, profileInfo{Name: name, Size: info.Size(), ModTime: info.ModTime()})
+}
+
+func (d *Diagnostics) listProfiles(w http.ResponseWriter, _ *http.Request) {
+	profiles, err := d.profiles()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, profiles)
+}
+
+// downloadProfile 下载已保存的 profile，可直接交给 go tool pprof
+func (d *Diagnostics) downloadProfile(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !validProfileName(name) {
+		writeError(w, http.StatusNotFound, errors.New("profile not found"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	http.ServeFile(w, r, filepath.Join(d.opts.ProfileDir, name))
+}
+
+// profiles 按时间从新到旧列出已保存的 profile
+func (d *Diagnostics) profiles() ([]profileInfo, error) {
+	entries, err := os.ReadDir(d.opts.ProfileDir)
+	if err != nil {
+		return nil, err
+	}
+	out := []profileInfo{}
+	for _, e := range entries {
+		if e.IsDir() || !validProfileName(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, profileInfo{Name: e.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	slices.SortFunc(out, func(a, b profileInfo) int { return b.ModTime.Compare(a.ModTime) })
+	return out, nil
+}
+
+// prune 删除超过 MaxProfileAge 的文件，并只保留最新的 MaxProfiles 个
+func (d *Diagnostics) prune() error {
+	profiles, err := d.profiles()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	cutoff := time.Now().Add(-d.opts.MaxProfileAge)
+	for i, p := range profiles {
+		if i < d.opts.MaxProfiles && p.ModTime.After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(d.opts.ProfileDir, p.Name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validProfileName 只接受本包生成的文件名，防止路径穿越
+func validProfileName(name string) bool {
+	if name != filepath.Base(name) || !strings.HasSuffix(name, profileExt) {
+		return false
+	}
+	return strings.HasPrefix(name, "cpu-") || strings.HasPrefix(name, "heap-")
+}
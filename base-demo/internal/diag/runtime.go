@@ -0,0 +1,130 @@
+package diag
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"runtime/pprof"
+	"time"
+)
+
+// startTime 进程启动时间，用于计算运行时长
+var startTime = time.Now()
+
+// goroutines 输出全部 goroutine 的调用栈，格式与 panic 时一致
+func goroutines(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// memoryStats 堆与运行时内存统计（字节）
+type memoryStats struct {
+	Alloc        uint64 `json:"alloc"`
+	TotalAlloc   uint64 `json:"total_alloc"`
+	Sys          uint64 `json:"sys"`
+	HeapAlloc    uint64 `json:"heap_alloc"`
+	HeapSys      uint64 `json:"heap_sys"`
+	HeapIdle     uint64 `json:"heap_idle"`
+	HeapInuse    uint64 `json:"heap_inuse"`
+	HeapReleased uint64 `json:"heap_released"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	StackInuse   uint64 `json:"stack_inuse"`
+	Mallocs      uint64 `json:"mallocs"`
+	Frees        uint64 `json:"frees"`
+}
+
+// gcStats GC 统计，暂停时间分位数依次为最小值、25%、50%、75%、最大值
+type gcStats struct {
+	NumGC          int64           `json:"num_gc"`
+	LastGC         time.Time       `json:"last_gc"`
+	PauseTotal     time.Duration   `json:"pause_total_ns"`
+	PauseQuantiles []time.Duration `json:"pause_quantiles_ns"`
+	NextGC         uint64          `json:"next_gc"`
+	CPUFraction    float64         `json:"cpu_fraction"`
+	// GOGC 当前的 GOGC 百分比，关闭时为 0
+	GOGC int `json:"gogc"`
+	// MemoryLimit 当前的软内存上限（GOMEMLIMIT）
+	MemoryLimit int64 `json:"memory_limit"`
+}
+
+// runtimeStats 输出 goroutine 数、内存与 GC 统计；ReadMemStats 会短暂 STW
+func runtimeStats(w http.ResponseWriter, _ *http.Request) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	gc := debug.GCStats{PauseQuantiles: make([]time.Duration, 5)}
+	debug.ReadGCStats(&gc)
+
+	// GOGC 与 GOMEMLIMIT 的当前值（含运行时通过 debug.SetGCPercent 等调整后的值）
+	settings := []metrics.Sample{{Name: "/gc/gogc:percent"}, {Name: "/gc/gomemlimit:bytes"}}
+	metrics.Read(settings)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"uptime":     time.Since(startTime).Round(time.Second).String(),
+		"goroutines": runtime.NumGoroutine(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"num_cpu":    runtime.NumCPU(),
+		"memory": memoryStats{
+			Alloc:        ms.Alloc,
+			TotalAlloc:   ms.TotalAlloc,
+			Sys:          ms.Sys,
+			HeapAlloc:    ms.HeapAlloc,
+			HeapSys:      ms.HeapSys,
+			HeapIdle:     ms.HeapIdle,
+			HeapInuse:    ms.HeapInuse,
+			HeapReleased: ms.HeapReleased,
+			HeapObjects:  ms.HeapObjects,
+			StackInuse:   ms.StackInuse,
+			Mallocs:      ms.Mallocs,
+			Frees:        ms.Frees,
+		},
+		"gc": gcStats{
+			NumGC:          gc.NumGC,
+			LastGC:         gc.LastGC,
+			PauseTotal:     gc.PauseTotal,
+			PauseQuantiles: gc.PauseQuantiles,
+			NextGC:         ms.NextGC,
+			CPUFraction:    ms.GCCPUFraction,
+			GOGC:           int(settings[0].Value.Uint64()),
+			MemoryLimit:    int64(settings[1].Value.Uint64()),
+		},
+	})
+}
+
+// module 依赖模块
+type module struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"`
+}
+
+// buildInfo 输出主模块版本、VCS 信息、编译参数与依赖列表
+func buildInfo(w http.ResponseWriter, _ *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]string{"go_version": runtime.Version()})
+		return
+	}
+	settings := make(map[string]string, len(info.Settings))
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	deps := make([]module, 0, len(info.Deps))
+	for _, m := range info.Deps {
+		dep := module{Path: m.Path, Version: m.Version}
+		if m.Replace != nil {
+			dep.Replace = m.Replace.Path
+		}
+		deps = append(deps, dep)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"path":       info.Main.Path,
+		"version":    info.Main.Version,
+		"revision":   settings["vcs.revision"],
+		"vcs_time":   settings["vcs.time"],
+		"modified":   settings["vcs.modified"] == "true",
+		"go_version": info.GoVersion,
+		"settings":   settings,
+		"deps":       deps,
+	})
+}
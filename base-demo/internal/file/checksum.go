@@ -0,0 +1,187 @@
+package file
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"os"
+	"slices"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Algorithm 分片校验和算法
+type Algorithm string
+
+const (
+	CRC32C Algorithm = "crc32c"
+	XXHash Algorithm = "xxhash64"
+	SHA256 Algorithm = "sha256"
+)
+
+// ManifestSuffix manifest 的文件名后缀，与数据文件位于同一目录
+const ManifestSuffix = ".manifest.json"
+
+// ErrChecksumMismatch 分片或文件内容与校验和不一致
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// New 返回算法对应的 hash.Hash
+func (a Algorithm) New() (hash.Hash, error) {
+	switch a {
+	case CRC32C:
+		return crc32.New(crc32cTable), nil
+	case XXHash:
+		return xxhash.New(), nil
+	case SHA256:
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown checksum algorithm %q", a)
+	}
+}
+
+// Sum 计算 data 的校验和，生产者可用它填充 Chunk.Checksum
+func (a Algorithm) Sum(data []byte) ([]byte, error) {
+	h, err := a.New()
+	if err != nil {
+		return nil, err
+	}
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// ChunkSum 分片区间及其校验和（十六进制）；CRC32C 为分片内容的 CRC32C，用于合成整个文件的摘要
+type ChunkSum struct {
+	Range
+	Sum    string `json:"sum,omitempty"`
+	CRC32C uint32 `json:"crc32c"`
+}
+
+// Manifest 文件的分片布局与校验和，Chunks 按偏移排序且恰好覆盖 [0, Size)。
+// Digest 是整个文件内容的 CRC32C（十六进制），由各分片的 CRC32C 按偏移顺序合并得到，写入时不需要再读一遍文件
+type Manifest struct {
+	Algorithm Algorithm  `json:"algorithm"`
+	Size      int64      `json:"size"`
+	Chunks    []ChunkSum `json:"chunks"`
+	Digest    string     `json:"digest"`
+}
+
+// ManifestPath 返回 filePath 对应的 manifest 路径
+func ManifestPath(filePath string) string {
+	return filePath + ManifestSuffix
+}
+
+// LoadManifest 读取 manifest 文件
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// newManifest 由写入过程中记录的分片校验和生成 manifest，分片必须恰好覆盖 [0, size)；
+// 同一区间重复写入时以最后一次为准
+func newManifest(alg Algorithm, size int64, sums []ChunkSum) (*Manifest, error) {
+	sorted := slices.Clone(sums)
+	slices.SortStableFunc(sorted, func(a, b ChunkSum) int { return cmp.Compare(a.Offset, b.Offset) })
+	chunks := make([]ChunkSum, 0, len(sorted))
+	var pos int64
+	for _, c := range sorted {
+		if n := len(chunks); n > 0 && c.Range == chunks[n-1].Range {
+			chunks[n-1] = c
+			continue
+		}
+		if c.Offset != pos {
+			return nil, fmt.Errorf("chunks overlap or leave a gap at offset %d", pos)
+		}
+		chunks = append(chunks, c)
+		pos = c.End()
+	}
+	if pos != size {
+		return nil, fmt.Errorf("chunks cover %d bytes, want %d", pos, size)
+	}
+	var crc uint32
+	for _, c := range chunks {
+		crc = crc32cCombine(crc, c.CRC32C, c.Length)
+	}
+	return &Manifest{Algorithm: alg, Size: size, Chunks: chunks, Digest: formatCRC32C(crc)}, nil
+}
+
+// formatCRC32C 以大端十六进制表示 CRC32C，与 Algorithm.Sum 的输出一致
+func formatCRC32C(crc uint32) string {
+	return fmt.Sprintf("%08x", crc)
+}
+
+// crc32cCombine 由 A 的 CRC32C crc1、B 的 CRC32C crc2 与 B 的长度 len2 计算 A+B 的 CRC32C（zlib crc32_combine）：
+// 在 GF(2) 上把 crc1 推进 len2 个零字节后与 crc2 异或
+func crc32cCombine(crc1, crc2 uint32, len2 int64) uint32 {
+	if len2 <= 0 {
+		return crc1
+	}
+	var even, odd [32]uint32
+	// odd 为推进一个零比特的算子
+	odd[0] = crc32.Castagnoli
+	row := uint32(1)
+	for n := 1; n < 32; n++ {
+		odd[n] = row
+		row <<= 1
+	}
+	gf2MatrixSquare(&even, &odd) // 两个零比特
+	gf2MatrixSquare(&odd, &even) // 四个零比特
+
+	// 每轮平方一次算子，按 len2 的二进制位推进 crc1
+	for {
+		gf2MatrixSquare(&even, &odd)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&even, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+		gf2MatrixSquare(&odd, &even)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&odd, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+	}
+	return crc1 ^ crc2
+}
+
+func gf2MatrixTimes(mat *[32]uint32, vec uint32) uint32 {
+	var sum uint32
+	for i := 0; vec != 0; i, vec = i+1, vec>>1 {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+	}
+	return sum
+}
+
+func gf2MatrixSquare(square, mat *[32]uint32) {
+	for n := range 32 {
+		square[n] = gf2MatrixTimes(mat, mat[n])
+	}
+}
+
+// save 先写临时文件并 fsync，再 rename 到 path
+func (m *Manifest) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"os"
+	"slices"
 	"testing"
 	"time"
 )
@@ -43,11 +47,13 @@ func TestWithFile(t *testing.T) {
 
 			// copy 数据
 			copy(buf[:size], src[offset:offset+int64(size)])
+			sum, _ := SHA256.Sum(buf[:size])
 
 			// 发送 chunk
 			chunkChan <- Chunk{
-				Offset: offset,
-				Data:   buf[:size],
+				Offset:   offset,
+				Data:     buf[:size],
+				Checksum: sum,
 			}
 
 			offset += int64(size)
@@ -59,19 +65,24 @@ func TestWithFile(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*3)
 	defer cancel()
 
-	err = WriteFileConcurrently(ctx, dstFile, chunkChan, fileSize)
+	err = WriteFileWithOptions(ctx, dstFile, chunkChan, fileSize, WriteOptions{Checksum: SHA256})
 	if err != nil {
 		t.Fatalf("并发写入失败: %v", err)
 	}
+	t.Cleanup(func() { os.Remove(ManifestPath(dstFile)) })
 
-	// 5. 验证写出的文件是否一致
-	dst, err := os.ReadFile(dstFile)
+	// 5. 按 manifest 逐个分片校验，并与原始数据的摘要比对
+	m, err := LoadManifest(ManifestPath(dstFile))
 	if err != nil {
-		t.Fatalf("读取目标文件失败: %v", err)
+		t.Fatalf("读取 manifest 失败: %v", err)
 	}
-
-	if !bytes.Equal(src, dst) {
-		t.Fatalf("文件校验失败：内容不一致")
+	if err := ReadFileWithMmap(dstFile, ChunkSize, nil, m); err != nil {
+		t.Fatalf("文件校验失败: %v", err)
+	}
+	for _, c := range m.Chunks {
+		if sum, _ := SHA256.Sum(src[c.Offset:c.End()]); hex.EncodeToString(sum) != c.Sum {
+			t.Fatalf("文件校验失败：偏移 %d 处内容不一致", c.Offset)
+		}
 	}
 
 	fmt.Println("🚀 测试通过：文件内容完全一致！")
@@ -83,5 +94,396 @@ func TestReadFileChunks(t *testing.T) {
 	ReadFileWithMmap("test_output.bin", block, func(chunk []byte) error {
 		fmt.Println("read chunk:", len(chunk))
 		return nil
-	})
+	}, nil)
+}
+
+// sendRanges 按 1MB 切分 ranges 并发送 src 中对应的数据
+func sendRanges(src []byte, ranges []Range) <-chan Chunk {
+	chunkChan := make(chan Chunk, 16)
+	go func() {
+		defer close(chunkChan)
+		for _, r := range ranges {
+			for off := r.Offset; off < r.End(); off += 1 << 20 {
+				end := min(off+1<<20, r.End())
+				chunkChan <- Chunk{Offset: off, Data: src[off:end]}
+			}
+		}
+	}()
+	return chunkChan
+}
+
+func TestResumableWrite(t *testing.T) {
+	const mb = 1 << 20
+	fileSize := int64(8*mb + 123)
+	src := make([]byte, fileSize)
+	if _, err := rand.Read(src); err != nil {
+		t.Fatalf("无法生成随机数据: %v", err)
+	}
+	dstFile := t.TempDir() + "/resume.bin"
+	opts := WriteOptions{Resume: true, CheckpointInterval: 10 * time.Millisecond, Checksum: CRC32C}
+
+	missing, err := MissingRanges(dstFile, fileSize, opts)
+	if err != nil || len(missing) != 1 || missing[0] != (Range{0, fileSize}) {
+		t.Fatalf("首次写入应缺失整个文件: %v %v", missing, err)
+	}
+
+	// 1. 只发送部分分片，模拟传输中断
+	err = WriteFileWithOptions(context.Background(), dstFile, sendRanges(src, []Range{{0, 3 * mb}, {5 * mb, mb}}), fileSize, opts)
+	if !errors.Is(err, ErrIncomplete) {
+		t.Fatalf("未写完时应返回 ErrIncomplete: %v", err)
+	}
+	missing, err = MissingRanges(dstFile, fileSize, opts)
+	if err != nil {
+		t.Fatalf("读取 journal 失败: %v", err)
+	}
+	want := []Range{{3 * mb, 2 * mb}, {6 * mb, fileSize - 6*mb}}
+	if !slices.Equal(missing, want) {
+		t.Fatalf("缺失区间错误: got %v, want %v", missing, want)
+	}
+
+	// 大小或写入选项与 journal 不一致时不可续传，与 WriteFileWithOptions 一样从头写入
+	if other, _ := MissingRanges(dstFile, fileSize+1, opts); len(other) != 1 || other[0] != (Range{0, fileSize + 1}) {
+		t.Fatalf("文件大小变化后应从头写入: %v", other)
+	}
+	for _, o := range []WriteOptions{{Resume: true}, {Resume: true, Checksum: CRC32C, Atomic: true}} {
+		if other, _ := MissingRanges(dstFile, fileSize, o); len(other) != 1 || other[0] != (Range{0, fileSize}) {
+			t.Fatalf("写入选项变化后应从头写入: %+v %v", o, other)
+		}
+	}
+
+	// 2. 中断期间已完成的分片被修改，重发的区间与已完成的分片部分重叠
+	f, err := os.OpenFile(dstFile, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("打开目标文件失败: %v", err)
+	}
+	if _, err := f.WriteAt([]byte{^src[5*mb+10]}, 5*mb+10); err != nil {
+		t.Fatalf("修改目标文件失败: %v", err)
+	}
+	f.Close()
+	err = WriteFileWithOptions(context.Background(), dstFile, sendRanges(src, []Range{{mb / 2, mb}}), fileSize, opts)
+	if !errors.Is(err, ErrIncomplete) {
+		t.Fatalf("未写完时应返回 ErrIncomplete: %v", err)
+	}
+	// 被部分覆盖的 [0, 1MB)、[1MB, 2MB) 失去校验和，需要重新发送未被覆盖的部分
+	missing, _ = MissingRanges(dstFile, fileSize, opts)
+	want = []Range{{0, mb / 2}, {3 * mb / 2, mb / 2}, {3 * mb, 2 * mb}, {6 * mb, fileSize - 6*mb}}
+	if !slices.Equal(missing, want) {
+		t.Fatalf("重叠写入后缺失区间错误: got %v, want %v", missing, want)
+	}
+
+	// 3. 补发缺失区间后提交前重新校验全部分片，被修改的分片移出 journal
+	err = WriteFileWithOptions(context.Background(), dstFile, sendRanges(src, missing), fileSize, opts)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("已完成的分片被修改时应返回 ErrChecksumMismatch: %v", err)
+	}
+	missing, _ = MissingRanges(dstFile, fileSize, opts)
+	if !slices.Equal(missing, []Range{{5 * mb, mb}}) {
+		t.Fatalf("校验失败的分片应重新发送: %v", missing)
+	}
+
+	// 4. 只补发缺失的区间
+	if err := WriteFileWithOptions(context.Background(), dstFile, sendRanges(src, missing), fileSize, opts); err != nil {
+		t.Fatalf("续传失败: %v", err)
+	}
+	if _, err := os.Stat(JournalPath(dstFile)); !os.IsNotExist(err) {
+		t.Fatalf("写完后应删除 journal: %v", err)
+	}
+	dst, err := os.ReadFile(dstFile)
+	if err != nil {
+		t.Fatalf("读取目标文件失败: %v", err)
+	}
+	if !bytes.Equal(src, dst) {
+		t.Fatalf("文件校验失败：内容不一致")
+	}
+
+	// 各次写入的分片校验和都记录在 manifest 中
+	m, err := LoadManifest(ManifestPath(dstFile))
+	if err != nil {
+		t.Fatalf("读取 manifest 失败: %v", err)
+	}
+	if err := ReadFileWithMmap(dstFile, ChunkSize, nil, m); err != nil {
+		t.Fatalf("manifest 校验失败: %v", err)
+	}
+	if want := fmt.Sprintf("%08x", crc32.Checksum(src, crc32cTable)); m.Digest != want {
+		t.Fatalf("整个文件的摘要错误: got %s, want %s", m.Digest, want)
+	}
+
+	// 只改一个字节而 manifest 不变，读取时校验失败
+	f, err = os.OpenFile(dstFile, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("打开目标文件失败: %v", err)
+	}
+	if _, err := f.WriteAt([]byte{^src[fileSize-1]}, fileSize-1); err != nil {
+		t.Fatalf("修改目标文件失败: %v", err)
+	}
+	f.Close()
+	if err := ReadFileWithMmap(dstFile, ChunkSize, nil, m); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("修改一个字节后应校验失败: %v", err)
+	}
+}
+
+func TestCRC32CCombine(t *testing.T) {
+	data := make([]byte, 1<<16+13)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("无法生成随机数据: %v", err)
+	}
+	want := crc32.Checksum(data, crc32cTable)
+	for _, split := range []int{0, 1, 7, 4096, len(data) - 1, len(data)} {
+		a, b := data[:split], data[split:]
+		got := crc32cCombine(crc32.Checksum(a, crc32cTable), crc32.Checksum(b, crc32cTable), int64(len(b)))
+		if got != want {
+			t.Fatalf("在 %d 处切分后合并错误: got %08x, want %08x", split, got, want)
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	src := make([]byte, 3<<20+7)
+	if _, err := rand.Read(src); err != nil {
+		t.Fatalf("无法生成随机数据: %v", err)
+	}
+	ranges := []Range{{0, 1 << 20}, {1 << 20, 1 << 20}, {2 << 20, 1<<20 + 7}}
+
+	for _, alg := range []Algorithm{CRC32C, XXHash, SHA256} {
+		t.Run(string(alg), func(t *testing.T) {
+			dstFile := t.TempDir() + "/checksum.bin"
+			send := func(corrupt int) <-chan Chunk {
+				chunkChan := make(chan Chunk, len(ranges))
+				for i, r := range ranges {
+					data := slices.Clone(src[r.Offset:r.End()])
+					sum, _ := alg.Sum(data)
+					if i == corrupt {
+						data[0] ^= 0xff
+					}
+					chunkChan <- Chunk{Offset: r.Offset, Data: data, Checksum: sum}
+				}
+				close(chunkChan)
+				return chunkChan
+			}
+			opts := WriteOptions{Checksum: alg}
+
+			// 分片内容与校验和不一致时拒绝写入，不生成 manifest
+			err := WriteFileWithOptions(context.Background(), dstFile, send(1), int64(len(src)), opts)
+			if !errors.Is(err, ErrChecksumMismatch) {
+				t.Fatalf("应返回 ErrChecksumMismatch: %v", err)
+			}
+			if _, err := os.Stat(ManifestPath(dstFile)); !os.IsNotExist(err) {
+				t.Fatalf("校验失败时不应生成 manifest: %v", err)
+			}
+
+			if err := WriteFileWithOptions(context.Background(), dstFile, send(-1), int64(len(src)), opts); err != nil {
+				t.Fatalf("写入失败: %v", err)
+			}
+			m, err := LoadManifest(ManifestPath(dstFile))
+			if err != nil {
+				t.Fatalf("读取 manifest 失败: %v", err)
+			}
+			if m.Algorithm != alg || len(m.Chunks) != len(ranges) {
+				t.Fatalf("manifest 错误: %+v", m)
+			}
+			if want := fmt.Sprintf("%08x", crc32.Checksum(src, crc32cTable)); m.Digest != want {
+				t.Fatalf("整个文件的摘要错误: got %s, want %s", m.Digest, want)
+			}
+			// 读取块与 manifest 分片边界不对齐
+			var read int
+			count := func(chunk []byte) error { read += len(chunk); return nil }
+			if err := ReadFileWithMmap(dstFile, 3<<19, count, m); err != nil || read != len(src) {
+				t.Fatalf("校验失败: %v, 读取 %d 字节", err, read)
+			}
+
+			// 篡改文件中的一个字节
+			f, err := os.OpenFile(dstFile, os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteAt([]byte{src[2<<20] ^ 1}, 2<<20)
+			f.Close()
+			// 被篡改的分片不会交给 handler
+			read = 0
+			if err := ReadFileWithMmap(dstFile, 1<<19, count, m); !errors.Is(err, ErrChecksumMismatch) || read != 2<<20 {
+				t.Fatalf("篡改后应校验失败: %v, 读取 %d 字节", err, read)
+			}
+
+			// 分片校验和随篡改一起更新，整个文件的摘要仍能发现
+			tampered, err := os.ReadFile(dstFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := &m.Chunks[len(m.Chunks)-1]
+			sum, _ := alg.Sum(tampered[last.Offset:last.End()])
+			last.Sum = hex.EncodeToString(sum)
+			read = 0
+			if err := ReadFileWithMmap(dstFile, 1<<19, count, m); !errors.Is(err, ErrChecksumMismatch) || read != len(src) {
+				t.Fatalf("摘要不一致时应校验失败: %v, 读取 %d 字节", err, read)
+			}
+		})
+	}
+}
+
+// dirEntries 返回目录中的文件名，用于检查临时文件是否清理
+func dirEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestAtomicWrite(t *testing.T) {
+	const mb = 1 << 20
+	fileSize := int64(4*mb + 9)
+	src := make([]byte, fileSize)
+	if _, err := rand.Read(src); err != nil {
+		t.Fatalf("无法生成随机数据: %v", err)
+	}
+	dir := t.TempDir()
+	dstFile := dir + "/atomic.bin"
+	old := []byte("old content")
+	if err := os.WriteFile(dstFile, old, 0644); err != nil {
+		t.Fatal(err)
+	}
+	unchanged := func() {
+		t.Helper()
+		if got, err := os.ReadFile(dstFile); err != nil || !bytes.Equal(got, old) {
+			t.Fatalf("失败时目标文件应保持原内容: %v", err)
+		}
+	}
+	all := []Range{{0, fileSize}}
+
+	// 1. 取消：临时文件被删除，目标文件不变
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := WriteFileWithOptions(ctx, dstFile, make(chan Chunk), fileSize, WriteOptions{Atomic: true}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("应返回 context.Canceled: %v", err)
+	}
+	unchanged()
+	if names := dirEntries(t, dir); len(names) != 1 {
+		t.Fatalf("临时文件未清理: %v", names)
+	}
+
+	// 2. 校验失败同样不替换目标文件
+	bad := make(chan Chunk, 1)
+	bad <- Chunk{Offset: 0, Data: src[:mb], Checksum: []byte{0, 0, 0, 0}}
+	close(bad)
+	if err := WriteFileWithOptions(context.Background(), dstFile, bad, fileSize, WriteOptions{Atomic: true, Checksum: CRC32C}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("应返回 ErrChecksumMismatch: %v", err)
+	}
+	unchanged()
+	if names := dirEntries(t, dir); len(names) != 1 {
+		t.Fatalf("临时文件未清理: %v", names)
+	}
+
+	// 3. 成功后 rename 到目标文件，权限与非原子模式一致
+	if err := WriteFileWithOptions(context.Background(), dstFile, sendRanges(src, all), fileSize, WriteOptions{Atomic: true}); err != nil {
+		t.Fatalf("原子写入失败: %v", err)
+	}
+	if got, err := os.ReadFile(dstFile); err != nil || !bytes.Equal(got, src) {
+		t.Fatalf("文件校验失败：内容不一致 %v", err)
+	}
+	if info, _ := os.Stat(dstFile); info.Mode().Perm() != 0644 {
+		t.Fatalf("权限错误: %v", info.Mode())
+	}
+	if names := dirEntries(t, dir); len(names) != 1 {
+		t.Fatalf("临时文件未清理: %v", names)
+	}
+}
+
+func TestAtomicResumableWrite(t *testing.T) {
+	const mb = 1 << 20
+	fileSize := int64(4*mb + 9)
+	src := make([]byte, fileSize)
+	if _, err := rand.Read(src); err != nil {
+		t.Fatalf("无法生成随机数据: %v", err)
+	}
+	dstFile := t.TempDir() + "/atomic.bin"
+	opts := WriteOptions{Resume: true, Atomic: true}
+
+	// 1. 中断后数据保留在 .part 中，目标文件尚不存在
+	err := WriteFileWithOptions(context.Background(), dstFile, sendRanges(src, []Range{{0, 2 * mb}}), fileSize, opts)
+	if !errors.Is(err, ErrIncomplete) {
+		t.Fatalf("未写完时应返回 ErrIncomplete: %v", err)
+	}
+	if _, err := os.Stat(dstFile); !os.IsNotExist(err) {
+		t.Fatalf("未写完时不应生成目标文件: %v", err)
+	}
+	if _, err := os.Stat(PartPath(dstFile)); err != nil {
+		t.Fatalf("中断后应保留 .part: %v", err)
+	}
+	missing, err := MissingRanges(dstFile, fileSize, opts)
+	if err != nil || !slices.Equal(missing, []Range{{2 * mb, fileSize - 2*mb}}) {
+		t.Fatalf("缺失区间错误: %v %v", missing, err)
+	}
+
+	// 2. 续传完成后 rename 为目标文件，.part 与 journal 均被删除
+	if err := WriteFileWithOptions(context.Background(), dstFile, sendRanges(src, missing), fileSize, opts); err != nil {
+		t.Fatalf("续传失败: %v", err)
+	}
+	if got, err := os.ReadFile(dstFile); err != nil || !bytes.Equal(got, src) {
+		t.Fatalf("文件校验失败：内容不一致 %v", err)
+	}
+	for _, p := range []string{PartPath(dstFile), JournalPath(dstFile)} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Fatalf("%s 未删除: %v", p, err)
+		}
+	}
+}
+
+func TestStaleManifest(t *testing.T) {
+	const mb = 1 << 20
+	src := make([]byte, 2*mb+3)
+	if _, err := rand.Read(src); err != nil {
+		t.Fatalf("无法生成随机数据: %v", err)
+	}
+	fileSize := int64(len(src))
+	all := []Range{{0, fileSize}}
+	dir := t.TempDir()
+	dstFile := dir + "/manifest.bin"
+	write := func(ctx context.Context, opts WriteOptions) error {
+		return WriteFileWithOptions(ctx, dstFile, sendRanges(src, all), fileSize, opts)
+	}
+	hasManifest := func() bool {
+		_, err := os.Stat(ManifestPath(dstFile))
+		return err == nil
+	}
+
+	// 1. 原子写入且开启校验：manifest 与新内容一致，没有残留的临时文件
+	if err := write(context.Background(), WriteOptions{Atomic: true, Checksum: XXHash}); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	m, err := LoadManifest(ManifestPath(dstFile))
+	if err != nil {
+		t.Fatalf("读取 manifest 失败: %v", err)
+	}
+	if err := ReadFileWithMmap(dstFile, mb, nil, m); err != nil {
+		t.Fatalf("manifest 校验失败: %v", err)
+	}
+	if names := dirEntries(t, dir); len(names) != 2 {
+		t.Fatalf("临时文件未清理: %v", names)
+	}
+
+	// 2. 非原子改写失败时不保留描述旧内容的 manifest
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := WriteFileWithOptions(ctx, dstFile, make(chan Chunk), fileSize, WriteOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("应返回 context.Canceled: %v", err)
+	}
+	if hasManifest() {
+		t.Fatalf("改写失败后应删除旧 manifest")
+	}
+
+	// 3. 原子写入且未开启校验：rename 后旧 manifest 不再有效，一并删除
+	if err := write(context.Background(), WriteOptions{Checksum: CRC32C}); err != nil || !hasManifest() {
+		t.Fatalf("写入失败: %v", err)
+	}
+	if err := write(context.Background(), WriteOptions{Atomic: true}); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	if hasManifest() {
+		t.Fatalf("未开启校验的原子写入应删除旧 manifest")
+	}
 }
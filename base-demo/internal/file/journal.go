@@ -0,0 +1,387 @@
+package file
+
+import (
+	"cmp"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+)
+
+const (
+	// JournalSuffix 断点续传 journal 的文件名后缀，与数据文件位于同一目录
+	JournalSuffix = ".journal"
+	// PartSuffix 原子写入且断点续传时临时文件的后缀，写完后 rename 为目标文件
+	PartSuffix = ".part"
+)
+
+// ErrIncomplete chunks 已关闭但文件仍有未写入的区间，journal 保留，可通过 MissingRanges 续传
+var ErrIncomplete = errors.New("file incomplete")
+
+// errStaleJournal journal 存在但不能用于本次续传，需要从头写入
+var errStaleJournal = errors.New("stale journal")
+
+// Range 字节区间 [Offset, Offset+Length)
+type Range struct {
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+}
+
+// End 区间的结束偏移（不含）
+func (r Range) End() int64 {
+	return r.Offset + r.Length
+}
+
+// journal 记录已 fsync 到数据文件的区间，Completed 按偏移排序且互不相邻；
+// Algorithm 与 Atomic 为创建时的 WriteOptions，不一致时不能续传。
+// 开启校验时 Sums 记录每个已完成分片的校验和且互不重叠，Completed 恰为 Sums 的并集，用于最终校验并生成 manifest；
+// Atomic 时数据写在 <filePath>.part 中
+type journal struct {
+	Size      int64      `json:"size"`
+	Algorithm Algorithm  `json:"algorithm,omitempty"`
+	Atomic    bool       `json:"atomic,omitempty"`
+	Completed []Range    `json:"completed"`
+	Sums      []ChunkSum `json:"sums,omitempty"`
+}
+
+// JournalPath 返回 filePath 对应的 journal 路径
+func JournalPath(filePath string) string {
+	return filePath + JournalSuffix
+}
+
+// PartPath 返回原子写入且断点续传时 filePath 对应的临时文件路径
+func PartPath(filePath string) string {
+	return filePath + PartSuffix
+}
+
+// MissingRanges 返回以 opts 写入 filePath 时仍需发送的区间，opts 应与随后调用 WriteFileWithOptions 的相同；
+// 与写入时的判断一致，journal 不存在或不能续传（大小、Checksum、Atomic 与 journal 不符，数据文件缺失或长度不符）时返回整个文件
+func MissingRanges(filePath string, fileSize int64, opts WriteOptions) ([]Range, error) {
+	j, err := loadJournal(filePath, fileSize, opts)
+	if err != nil && !errors.Is(err, errStaleJournal) {
+		return nil, err
+	}
+	return j.missingFrom(fileSize), nil
+}
+
+// loadJournal 读取可用于以 opts 续传的 journal；journal 不存在时返回 nil，
+// 存在但不能续传时返回 errStaleJournal，两种情况都需要从头写入
+func loadJournal(filePath string, fileSize int64, opts WriteOptions) (*journal, error) {
+	data, err := os.ReadFile(JournalPath(filePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		// 原子替换保证不会读到半个 journal，解析失败说明文件被篡改
+		return nil, fmt.Errorf("%w: %w", errStaleJournal, err)
+	}
+	if j.Size != fileSize {
+		return nil, fmt.Errorf("%w: size %d, want %d", errStaleJournal, j.Size, fileSize)
+	}
+	if j.Algorithm != opts.Checksum || j.Atomic != opts.Atomic {
+		// 已完成的分片缺少对应算法的校验和，或数据不在本次要写入的文件中
+		return nil, fmt.Errorf("%w: written with checksum %q atomic %t, want checksum %q atomic %t",
+			errStaleJournal, j.Algorithm, j.Atomic, opts.Checksum, opts.Atomic)
+	}
+	dataPath := filePath
+	if j.Atomic {
+		dataPath = PartPath(filePath)
+	}
+	info, err := os.Stat(dataPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s missing", errStaleJournal, dataPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() != fileSize {
+		return nil, fmt.Errorf("%w: %s size %d, want %d", errStaleJournal, dataPath, info.Size(), fileSize)
+	}
+	j.Completed = mergeRanges(j.Completed)
+	return &j, nil
+}
+
+// save 原子替换 journal，保证读到的要么是旧版本要么是新版本
+func (j *journal) save(path string) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// add 记录一个已落盘的分片。开启校验时先丢弃与其重叠的旧分片，重发或重新切分的区间以最新写入为准
+func (j *journal) add(c ChunkSum) {
+	if j.Algorithm != "" {
+		j.drop(c.Range)
+		j.Sums = append(j.Sums, c)
+	}
+	j.Completed = mergeRanges(append(j.Completed, c.Range))
+}
+
+// drop 移除与 r 重叠的分片的校验和，并把这些分片的整个区间移出 Completed：
+// 部分被覆盖的旧分片剩余的数据没有对应的校验和，需要重新写入
+func (j *journal) drop(r Range) {
+	var stale []Range
+	j.Sums = slices.DeleteFunc(j.Sums, func(s ChunkSum) bool {
+		if s.Offset < r.End() && r.Offset < s.End() {
+			stale = append(stale, s.Range)
+			return true
+		}
+		return false
+	})
+	for _, s := range stale {
+		j.Completed = subtractRange(j.Completed, s)
+	}
+}
+
+// verify 重新读取 Sums 记录的每个分片并与校验和比较，返回内容不一致的分片
+func (j *journal) verify(file *os.File) ([]ChunkSum, error) {
+	h, err := j.Algorithm.New()
+	if err != nil {
+		return nil, err
+	}
+	var bad []ChunkSum
+	for _, c := range j.Sums {
+		h.Reset()
+		if _, err := io.Copy(h, io.NewSectionReader(file, c.Offset, c.Length)); err != nil {
+			return nil, err
+		}
+		if hex.EncodeToString(h.Sum(nil)) != c.Sum {
+			bad = append(bad, c)
+		}
+	}
+	return bad, nil
+}
+
+// missingFrom 返回 [0, size) 中未被 Completed 覆盖的区间，j 为 nil 时返回整个区间
+func (j *journal) missingFrom(size int64) []Range {
+	var completed []Range
+	if j != nil {
+		completed = j.Completed
+	}
+	var (
+		missing []Range
+		pos     int64
+	)
+	for _, r := range completed {
+		if r.Offset > pos {
+			missing = append(missing, Range{Offset: pos, Length: min(r.Offset, size) - pos})
+		}
+		pos = max(pos, r.End())
+		if pos >= size {
+			break
+		}
+	}
+	if pos < size {
+		missing = append(missing, Range{Offset: pos, Length: size - pos})
+	}
+	return missing
+}
+
+// subtractRange 从有序且互不重叠的 ranges 中去掉 r 覆盖的部分
+func subtractRange(ranges []Range, r Range) []Range {
+	var out []Range
+	for _, c := range ranges {
+		if c.End() <= r.Offset || c.Offset >= r.End() {
+			out = append(out, c)
+			continue
+		}
+		if c.Offset < r.Offset {
+			out = append(out, Range{Offset: c.Offset, Length: r.Offset - c.Offset})
+		}
+		if c.End() > r.End() {
+			out = append(out, Range{Offset: r.End(), Length: c.End() - r.End()})
+		}
+	}
+	return out
+}
+
+// mergeRanges 排序并合并重叠或相邻的区间，丢弃空区间
+func mergeRanges(ranges []Range) []Range {
+	ranges = slices.DeleteFunc(ranges, func(r Range) bool { return r.Length <= 0 })
+	slices.SortFunc(ranges, func(a, b Range) int { return cmp.Compare(a.Offset, b.Offset) })
+	var merged []Range
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.Offset <= merged[n-1].End() {
+			merged[n-1].Length = max(merged[n-1].End(), r.End()) - merged[n-1].Offset
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// writeResumable 保留 journal 中已完成的区间，定期 fsync 数据文件后把新写入的区间记入 journal；
+// 全部写完后检查文件长度，开启校验时重新读取全部分片与 journal 中的校验和比较，
+// 通过后（开启校验时先写入 manifest，原子写入时再 rename .part）删除 journal，
+// 否则返回 ErrIncomplete、ErrChecksumMismatch 或写入错误并保留 journal 与数据文件
+func writeResumable(
+	ctx context.Context,
+	filePath string,
+	chunks <-chan Chunk,
+	fileSize int64,
+	opts WriteOptions,
+) error {
+	jpath := JournalPath(filePath)
+	j, err := loadJournal(filePath, fileSize, opts)
+	if errors.Is(err, errStaleJournal) {
+		// 与 MissingRanges 的判断一致，调用方此时应发送了整个文件
+		slog.Warn("Discarding journal, rewriting file from scratch", slog.String("path", jpath), logger.Err(err))
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dataPath := filePath
+	if opts.Atomic {
+		dataPath = PartPath(filePath)
+	} else if err := removeManifest(filePath); err != nil {
+		// 数据直接写入 filePath，旧 manifest 不再描述它
+		return err
+	}
+	// 不截断，保留上次写入的数据
+	file, err := os.OpenFile(dataPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if j == nil {
+		// 没有可信的进度：先落盘空 journal，再清空并预分配数据文件，中途崩溃也不会把旧数据当成已完成
+		j = &journal{Size: fileSize, Algorithm: opts.Checksum, Atomic: opts.Atomic}
+		if err := j.save(jpath); err != nil {
+			return err
+		}
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if err := file.Truncate(fileSize); err != nil {
+			return err
+		}
+	} else {
+		var done int64
+		for _, r := range j.Completed {
+			done += r.Length
+		}
+		slog.Info("Resuming file write", slog.String("path", filePath), slog.Int64("completed_bytes", done), slog.Int64("size", fileSize))
+	}
+
+	var (
+		mu      sync.Mutex
+		pending []ChunkSum
+	)
+	// checkpoint 只在 ticker goroutine 与写入结束后调用，不会并发执行
+	checkpoint := func() error {
+		mu.Lock()
+		batch := pending
+		pending = nil
+		mu.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := file.Sync(); err != nil {
+			// 数据未确认落盘，留到下次 checkpoint 重试
+			mu.Lock()
+			pending = append(pending, batch...)
+			mu.Unlock()
+			return err
+		}
+		for _, c := range batch {
+			j.add(c)
+		}
+		return j.save(jpath)
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(opts.CheckpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := checkpoint(); err != nil {
+					slog.Warn("Failed to checkpoint journal", slog.String("path", jpath), logger.Err(err))
+				}
+			}
+		}
+	}()
+
+	werr := runWorkers(ctx, file, chunks, fileSize, opts.Checksum, func(c ChunkSum) {
+		mu.Lock()
+		pending = append(pending, c)
+		mu.Unlock()
+	})
+	close(stop)
+	<-stopped
+
+	// 出错或取消时同样记录已完成的区间，供下次续传
+	if err := checkpoint(); err != nil {
+		return errors.Join(werr, fmt.Errorf("checkpoint journal: %w", err))
+	}
+	if werr != nil {
+		return werr
+	}
+
+	if missing := j.missingFrom(fileSize); len(missing) > 0 {
+		var n int64
+		for _, r := range missing {
+			n += r.Length
+		}
+		return fmt.Errorf("%w: %d bytes in %d ranges missing", ErrIncomplete, n, len(missing))
+	}
+	// 数据文件长度与 journal 一致；未开启校验时不检查内容
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != fileSize {
+		return fmt.Errorf("%w: size %d, want %d", ErrIncomplete, info.Size(), fileSize)
+	}
+	if j.Algorithm != "" {
+		// 之前会话写入的数据在中断期间可能被修改，提交前重新读取全部分片校验；
+		// 不一致的分片移出 journal，调用方通过 MissingRanges 重新发送
+		bad, err := j.verify(file)
+		if err != nil {
+			return err
+		}
+		if len(bad) > 0 {
+			checksumErrors.Add(float64(len(bad)))
+			for _, c := range bad {
+				j.drop(c.Range)
+			}
+			if err := j.save(jpath); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %d chunks on disk, first at offset %d", ErrChecksumMismatch, len(bad), bad[0].Offset)
+		}
+	}
+	var m *Manifest
+	if j.Algorithm != "" {
+		if m, err = newManifest(j.Algorithm, fileSize, j.Sums); err != nil {
+			return err
+		}
+	}
+	if err := finishWrite(file, filePath, m, opts.Atomic); err != nil {
+		return err
+	}
+	return os.Remove(jpath)
+}
@@ -0,0 +1,15 @@
+package file
+
+import "github.com/lyonmu/demo/base-demo/pkg/metrics"
+
+var (
+	fileMetrics = metrics.NewSubsystem("file")
+
+	writeBytes    = fileMetrics.Counter("write_bytes_total", "Total bytes written by WriteFileConcurrently.")
+	writeChunks   = fileMetrics.Counter("write_chunks_total", "Total chunks written by WriteFileConcurrently.")
+	writeErrors   = fileMetrics.Counter("write_errors_total", "Total chunk write errors.")
+	writeDuration = fileMetrics.Histogram("chunk_write_duration_seconds", "Time spent writing a single chunk.",
+		[]float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5})
+	readBytes      = fileMetrics.Counter("read_bytes_total", "Total bytes handed to handlers by ReadFileWithMmap.")
+	checksumErrors = fileMetrics.Counter("checksum_errors_total", "Total chunks rejected by checksum verification.")
+)
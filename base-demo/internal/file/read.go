@@ -1,28 +1,129 @@
 package file
 
 import (
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
 	"os"
 	"syscall"
 )
 
-// ReadFileWithMmap mmap 分片读取实现（无额外内存 copy）
-func ReadFileWithMmap(path string, blockSize int, handler func(chunk []byte) error) error {
+// ReadFileWithMmap mmap 分片读取实现（无额外内存 copy），handler 可为 nil。
+// m 不为 nil 时按 manifest 校验文件：长度必须一致，每个块交给 handler 前，与之重叠的 manifest 分片都已校验通过，读完后再校验整个文件的 Digest；
+// 任一校验失败返回 ErrChecksumMismatch
+func ReadFileWithMmap(path string, blockSize int, handler func(chunk []byte) error, m *Manifest) error {
+	data, unmap, err := mmapFile(path)
+	if err != nil {
+		return err
+	}
+	defer unmap()
+
+	var v *verifier
+	if m != nil {
+		if int64(len(data)) != m.Size {
+			return fmt.Errorf("%w: size %d, want %d", ErrChecksumMismatch, len(data), m.Size)
+		}
+		if v, err = newVerifier(m); err != nil {
+			return err
+		}
+	}
+
+	for offset := 0; offset < len(data); offset += blockSize {
+		end := min(offset+blockSize, len(data))
+
+		if v != nil {
+			if err := v.verifyTo(data, int64(end)); err != nil {
+				return err
+			}
+		}
+
+		chunk := data[offset:end] // 🔥 直接引用 mmap 区域，不复制
+
+		if handler != nil {
+			if err := handler(chunk); err != nil {
+				return err
+			}
+		}
+		readBytes.Add(float64(len(chunk)))
+	}
+	if v != nil {
+		return v.done()
+	}
+	return nil
+}
+
+// verifier 按偏移顺序校验 manifest 的分片，[0, pos) 已校验通过，crc 为 [0, pos) 的 CRC32C
+type verifier struct {
+	m    *Manifest
+	h    hash.Hash
+	next int
+	pos  int64
+	crc  uint32
+}
+
+func newVerifier(m *Manifest) (*verifier, error) {
+	h, err := m.Algorithm.New()
+	if err != nil {
+		return nil, err
+	}
+	return &verifier{m: m, h: h}, nil
+}
+
+// verifyTo 校验覆盖 [pos, end) 的分片，data 为整个文件
+func (v *verifier) verifyTo(data []byte, end int64) error {
+	for v.pos < end {
+		if v.next >= len(v.m.Chunks) {
+			return fmt.Errorf("invalid manifest: chunks cover %d bytes, want %d", v.pos, v.m.Size)
+		}
+		c := v.m.Chunks[v.next]
+		if c.Offset != v.pos || c.Length <= 0 || c.End() > v.m.Size {
+			return fmt.Errorf("invalid manifest: chunk [%d, %d) at offset %d", c.Offset, c.End(), v.pos)
+		}
+		chunk := data[c.Offset:c.End()]
+		v.h.Reset()
+		v.h.Write(chunk)
+		if sum := hex.EncodeToString(v.h.Sum(nil)); sum != c.Sum {
+			checksumErrors.Inc()
+			return fmt.Errorf("%w: chunk at offset %d: got %s, want %s", ErrChecksumMismatch, c.Offset, sum, c.Sum)
+		}
+		v.crc = crc32.Update(v.crc, crc32cTable, chunk)
+		v.next++
+		v.pos = c.End()
+	}
+	return nil
+}
+
+// done 检查 manifest 的分片恰好覆盖整个文件，且整个文件的 CRC32C 与 Digest 一致
+func (v *verifier) done() error {
+	if v.pos != v.m.Size || v.next != len(v.m.Chunks) {
+		return fmt.Errorf("invalid manifest: %d chunks cover %d bytes, want %d chunks covering %d", v.next, v.pos, len(v.m.Chunks), v.m.Size)
+	}
+	if digest := formatCRC32C(v.crc); digest != v.m.Digest {
+		checksumErrors.Inc()
+		return fmt.Errorf("%w: file digest: got %s, want %s", ErrChecksumMismatch, digest, v.m.Digest)
+	}
+	return nil
+}
+
+// mmapFile 只读映射整个文件，空文件返回 nil
+func mmapFile(path string) ([]byte, func(), error) {
 	// 打开文件
 	file, err := os.Open(path)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	defer file.Close()
 
 	// 获取文件大小
 	fi, err := file.Stat()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	fileSize := fi.Size()
 
 	if fileSize == 0 {
-		return nil
+		return nil, func() {}, nil
 	}
 
 	data, err := syscall.Mmap(
@@ -33,18 +134,7 @@ func ReadFileWithMmap(path string, blockSize int, handler func(chunk []byte) err
 		syscall.MAP_SHARED,
 	)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	defer syscall.Munmap(data)
-
-	for offset := 0; offset < len(data); offset += blockSize {
-		end := min(offset+blockSize, len(data))
-
-		chunk := data[offset:end] // 🔥 直接引用 mmap 区域，不复制
-
-		if err := handler(chunk); err != nil {
-			return err
-		}
-	}
-	return nil
+	return data, func() { syscall.Munmap(data) }, nil
 }
@@ -1,10 +1,17 @@
 package file
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"hash/crc32"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 )
 
 const (
@@ -15,6 +22,8 @@ const (
 type Chunk struct {
 	Offset int64
 	Data   []byte
+	// Checksum 可选，按 WriteOptions.Checksum 指定的算法计算的 Data 校验和，写入前校验
+	Checksum []byte
 }
 
 var chunkPool = sync.Pool{
@@ -24,25 +33,212 @@ var chunkPool = sync.Pool{
 	},
 }
 
+// WriteOptions WriteFileWithOptions 的可选行为，零值等价于 WriteFileConcurrently
+type WriteOptions struct {
+	// Resume 断点续传：在 <filePath>.journal 中记录已落盘的分片，中断后再次写入时保留已完成的数据，
+	// 调用方以相同的 opts 调用 MissingRanges 获取仍需发送的区间；全部写完并通过校验后删除 journal
+	Resume bool
+	// CheckpointInterval journal 的落盘间隔，默认 1s；每次落盘前先 fsync 数据文件，保证 journal 记录的分片已持久化
+	CheckpointInterval time.Duration
+	// Checksum 分片校验和算法，为空时不校验。设置后 Chunk.Checksum 不为空的分片在写入前校验，
+	// 写入成功后在 <filePath>.manifest.json 中记录每个分片的校验和，供 ReadFileWithMmap 校验
+	Checksum Algorithm
+	// Atomic 原子写入：先写入同目录下的临时文件，成功后 fsync 文件、rename 到 filePath 并 fsync 目录，
+	// filePath 要么不存在（或保持旧内容），要么是完整的新文件。出错或取消时删除临时文件；
+	// 与 Resume 同时开启时临时文件固定为 <filePath>.part 并保留，供下次续传
+	Atomic bool
+}
+
+// WriteFileConcurrently 截断并预分配 filePath，由 Workers 个 goroutine 并发写入 chunks，直到 chunks 关闭
 func WriteFileConcurrently(
 	ctx context.Context,
 	filePath string,
 	chunks <-chan Chunk,
 	fileSize int64,
 ) error {
+	return WriteFileWithOptions(ctx, filePath, chunks, fileSize, WriteOptions{})
+}
+
+// WriteFileWithOptions 与 WriteFileConcurrently 相同，按 opts 开启断点续传、校验与原子写入
+func WriteFileWithOptions(
+	ctx context.Context,
+	filePath string,
+	chunks <-chan Chunk,
+	fileSize int64,
+	opts WriteOptions,
+) error {
+	if opts.Checksum != "" {
+		if _, err := opts.Checksum.New(); err != nil {
+			return err
+		}
+	}
+	if opts.Resume {
+		if opts.CheckpointInterval <= 0 {
+			opts.CheckpointInterval = time.Second
+		}
+		return writeResumable(ctx, filePath, chunks, fileSize, opts)
+	}
 
 	// 打开文件
-	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	var (
+		file *os.File
+		err  error
+	)
+	if opts.Atomic {
+		file, err = createTemp(filePath)
+	} else if err = removeManifest(filePath); err == nil {
+		file, err = os.OpenFile(filePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	}
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	committed := false
+	defer func() {
+		file.Close()
+		if opts.Atomic && !committed {
+			os.Remove(file.Name())
+		}
+	}()
 
 	// 扩容文件
 	if err := file.Truncate(fileSize); err != nil {
 		return err
 	}
 
+	var (
+		mu   sync.Mutex
+		sums []ChunkSum
+	)
+	err = runWorkers(ctx, file, chunks, fileSize, opts.Checksum, func(c ChunkSum) {
+		mu.Lock()
+		sums = append(sums, c)
+		mu.Unlock()
+	})
+	if err != nil {
+		return err
+	}
+
+	var m *Manifest
+	if opts.Checksum != "" {
+		if m, err = newManifest(opts.Checksum, fileSize, sums); err != nil {
+			return err
+		}
+	}
+	if err := finishWrite(file, filePath, m, opts.Atomic); err != nil {
+		return err
+	}
+	committed = opts.Atomic
+	return nil
+}
+
+// removeManifest 删除 filePath 的 manifest 并 fsync 目录，在改写 filePath 的数据之前调用，
+// 写入失败时不会留下描述旧内容的 manifest
+func removeManifest(filePath string) error {
+	err := os.Remove(ManifestPath(filePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(filePath))
+}
+
+// finishWrite fsync 数据后写入 m（不为 nil 时），原子写入时最后 rename 到 filePath。
+// 原子写入时旧 manifest 在 rename 前被替换或删除：rename 失败时旧文件与新 manifest 不一致，
+// 校验会失败，而不会出现新文件搭配旧 manifest 的情况
+func finishWrite(file *os.File, filePath string, m *Manifest, atomic bool) error {
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if m != nil {
+		if err := m.save(ManifestPath(filePath)); err != nil {
+			return err
+		}
+	} else if atomic {
+		if err := removeManifest(filePath); err != nil {
+			return err
+		}
+	}
+	if atomic {
+		return commitFile(file, filePath)
+	}
+	return nil
+}
+
+// createTemp 在 filePath 所在目录创建临时文件，保证 rename 不跨文件系统
+func createTemp(filePath string) (*os.File, error) {
+	file, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	// CreateTemp 的权限为 0600，与非原子模式保持一致
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+// commitFile fsync 并关闭 file，rename 到 filePath 后 fsync 所在目录，使 rename 本身持久化
+func commitFile(file *os.File, filePath string) error {
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), filePath); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(filePath))
+}
+
+// writeFileAtomic 原子替换 path，读者只会看到旧内容或完整的新内容
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := createTemp(path)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		tmp.Close()
+		if !committed {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := commitFile(tmp, path); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// syncDir fsync 目录，持久化目录项的创建、删除与 rename
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// runWorkers 启动 Workers 个 goroutine 写入 chunks，alg 不为空时计算并校验分片校验和，
+// 每个分片写入成功后调用 written（可为 nil）
+func runWorkers(
+	ctx context.Context,
+	file *os.File,
+	chunks <-chan Chunk,
+	fileSize int64,
+	alg Algorithm,
+	written func(ChunkSum),
+) error {
 	var (
 		wg   sync.WaitGroup
 		mu   sync.Mutex
@@ -68,11 +264,17 @@ func WriteFileConcurrently(
 						return
 					}
 
-					// 并发写文件（WriteAt 是线程安全的）
-					if _, err := file.WriteAt(chunk.Data, chunk.Offset); err != nil {
+					c, err := writeChunk(file, chunk, fileSize, alg)
+					if err != nil {
+						writeErrors.Inc()
 						mu.Lock()
 						errs = append(errs, err)
 						mu.Unlock()
+					} else {
+						writeChunks.Inc()
+						if written != nil {
+							written(c)
+						}
 					}
 
 					// 将 chunk.Data 放回内存池复用
@@ -90,3 +292,35 @@ func WriteFileConcurrently(
 	wg.Wait()
 	return errors.Join(errs...)
 }
+
+// writeChunk 校验区间与校验和后写入单个分片，返回分片区间及其校验和
+func writeChunk(file *os.File, chunk Chunk, fileSize int64, alg Algorithm) (ChunkSum, error) {
+	c := ChunkSum{Range: Range{Offset: chunk.Offset, Length: int64(len(chunk.Data))}}
+	if c.Offset < 0 || c.End() > fileSize {
+		return c, fmt.Errorf("chunk [%d, %d) out of file size %d", c.Offset, c.End(), fileSize)
+	}
+
+	if alg != "" {
+		sum, err := alg.Sum(chunk.Data)
+		if err != nil {
+			return c, err
+		}
+		if chunk.Checksum != nil && !bytes.Equal(sum, chunk.Checksum) {
+			checksumErrors.Inc()
+			return c, fmt.Errorf("%w: chunk at offset %d: got %x, want %x", ErrChecksumMismatch, c.Offset, sum, chunk.Checksum)
+		}
+		c.Sum = hex.EncodeToString(sum)
+		if alg == CRC32C {
+			c.CRC32C = binary.BigEndian.Uint32(sum)
+		} else {
+			c.CRC32C = crc32.Checksum(chunk.Data, crc32cTable)
+		}
+	}
+
+	// 并发写文件（WriteAt 是线程安全的）
+	start := time.Now()
+	n, err := file.WriteAt(chunk.Data, chunk.Offset)
+	writeDuration.Observe(time.Since(start).Seconds())
+	writeBytes.Add(float64(n))
+	return c, err
+}
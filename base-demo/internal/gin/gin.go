@@ -1,37 +1,75 @@
 package gin
 
 import (
-	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
-	"os"
+	"strings"
 
 	"github.com/gin-contrib/cors"
-	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
-	"github.com/lyonmu/demo/base-demo/internal/metrics"
+	"github.com/lyonmu/demo/base-demo/internal/diag"
+	"github.com/lyonmu/demo/base-demo/internal/lifecycle"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/base-demo/pkg/metrics"
+	"github.com/lyonmu/demo/base-demo/pkg/tracing"
 	"github.com/soheilhy/cmux"
 )
 
-func NewGin(m cmux.CMux) error {
+// ServiceName 链路追踪中的服务名
+const ServiceName = "base-demo"
+
+// NewGin 在每个 cmux 上匹配 HTTP/1.x 与 HTTP/2 请求，交给同一个 http.Server：
+// grpcHandler 不为空时 content-type 为 application/grpc 的 HTTP/2 请求交给它，其余请求交给 gin 引擎；
+// d 不为空时在 /debug 下挂载诊断接口；返回的服务由调用方启动与关闭
+func NewGin(d *diag.Diagnostics, grpcHandler http.Handler, ms ...cmux.CMux) lifecycle.Server {
 
 	gin.SetMode(gin.ReleaseMode)
 
 	r := gin.New()
 
+	// 链路追踪中间件在最外层，指标 exemplar 与访问日志都能从 context 取到 span；
+	// 指标中间件紧随其后，CORS 预检与 Recovery 恢复的 panic 同样计入
+	r.Use(tracing.GinMiddleware(ServiceName))
+	r.Use(metrics.GinMiddleware(metrics.MiddlewareOptions{}))
 	r.Use(cors.Default())
 	r.Use(gin.Recovery())
-	r.Use(gin.Logger())
+	r.Use(logger.GinMiddleware(slog.Default()))
+
+	metrics.RegisterMetrics(r)
 
-	if err := metrics.RegisterMetrics(r); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to register metrics: %v", err)
-		return err
+	if d != nil {
+		d.Mount(r)
 	}
 
-	if gin.Mode() != gin.ReleaseMode {
-		pprof.Register(r)
+	// 明文 HTTP/2（h2c）与 tlsmux 解密后的 HTTP/2 连接由同一个 http.Server 处理，
+	// 按请求分流 gRPC，普通 h2c 客户端与 gRPC 客户端看到的是同一个 HTTP/2 服务端
+	var handler http.Handler = r
+	if grpcHandler != nil {
+		handler = routeGRPC(grpcHandler, r)
+	}
+	srv := &http.Server{Handler: handler, Protocols: new(http.Protocols)}
+	srv.Protocols.SetHTTP1(true)
+	srv.Protocols.SetUnencryptedHTTP2(true)
+	var ls []net.Listener
+	for _, m := range ms {
+		ls = append(ls, m.Match(cmux.HTTP1Fast(), cmux.HTTP2()))
 	}
 
-	go (&http.Server{Handler: r}).Serve(m.Match(cmux.HTTP1Fast()))
+	return lifecycle.HTTP(srv, ls...)
+}
 
-	return nil
+// routeGRPC 将 content-type 为 application/grpc 的 HTTP/2 请求交给 grpcHandler，其余交给 h。
+//
+// 没有使用 cmux.HTTP2HeaderField("content-type", "application/grpc") 在连接层分流：该 matcher 不回复 SETTINGS，
+// grpc-go 等客户端要等到服务端 SETTINGS 才发送 HEADERS，连接会卡住；HTTP2MatchHeaderFieldSendSettings 虽然能避免卡住，
+// 但只看连接上第一个请求，同一个 h2c 连接上的后续请求无法再分流。按请求分流时 gRPC 与 h2c 共用同一个 HTTP/2 服务端
+func routeGRPC(grpcHandler, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcHandler.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
 }
@@ -0,0 +1,81 @@
+package gin
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"testing"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/internal/lifecycle"
+	"github.com/soheilhy/cmux"
+)
+
+func TestH2C(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := cmux.New(l)
+	grpcHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "grpc")
+	})
+	app := lifecycle.New(lifecycle.Options{})
+	app.AddMux("main", m)
+	app.AddServer("gin", NewGin(nil, grpcHandler, m))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("退出失败: %v", err)
+		}
+	})
+	url := "http://" + l.Addr().String() + "/metrics"
+
+	tr := &http.Transport{Protocols: new(http.Protocols)}
+	tr.Protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Timeout: 5 * time.Second, Transport: tr}
+
+	// 普通 h2c 请求交给 gin，同一连接可连续发送多个请求
+	var reused bool
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused }}
+	for range 2 {
+		req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, url, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("h2c 请求失败: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 || resp.Header.Get("X-Handler") != "" {
+			t.Fatalf("h2c 响应错误: %d %s %q", resp.StatusCode, resp.Proto, resp.Header.Get("X-Handler"))
+		}
+	}
+	if !reused {
+		t.Fatalf("h2c 连接应被复用")
+	}
+
+	// content-type 为 application/grpc 的请求交给 grpcHandler
+	resp, err := client.Post(url, "application/grpc+proto", nil)
+	if err != nil {
+		t.Fatalf("gRPC 请求失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Handler") != "grpc" {
+		t.Fatalf("gRPC 请求未交给 grpcHandler")
+	}
+
+	// HTTP/1.1 请求即使 content-type 为 application/grpc 也交给 gin
+	resp, err = (&http.Client{Timeout: 5 * time.Second}).Post(url, "application/grpc", nil)
+	if err != nil {
+		t.Fatalf("HTTP/1.1 请求失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Handler") != "" {
+		t.Fatalf("HTTP/1.1 请求不应交给 grpcHandler")
+	}
+}
@@ -0,0 +1,56 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	metricsv1 "github.com/lyonmu/demo/base-demo/api/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewGRPC 创建注册了健康检查、服务反射与 MetricsService 的 gRPC 服务；
+// 返回的 Server 作为 http.Handler 交给 gin.NewGin，由其按 content-type: application/grpc 分流，
+// 同时作为 lifecycle.Server 注册在 gin 之后，退出时先将健康状态置为 NOT_SERVING
+func NewGRPC() *Server {
+	s := grpc.NewServer()
+
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(s, hs)
+	metricsv1.RegisterMetricsServiceServer(s, &metricsServer{})
+	reflection.Register(s)
+
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus(metricsv1.MetricsService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	return &Server{s: s, health: hs, done: make(chan struct{})}
+}
+
+// Server 通过 gin 的 http.Server 提供 gRPC，连接与进行中的 RPC 由该 http.Server 管理
+type Server struct {
+	s      *grpc.Server
+	health *health.Server
+	done   chan struct{}
+	once   sync.Once
+}
+
+// ServeHTTP 处理 HTTP/2 上的 gRPC 请求
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.s.ServeHTTP(w, r)
+}
+
+// Serve 阻塞直到 Shutdown
+func (s *Server) Serve() error {
+	<-s.done
+	return nil
+}
+
+// Shutdown 将健康状态置为 NOT_SERVING；进行中的 RPC 由随后关闭的 gin http.Server 等待完成
+func (s *Server) Shutdown(context.Context) error {
+	s.health.Shutdown()
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
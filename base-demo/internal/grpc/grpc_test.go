@@ -0,0 +1,137 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+
+	metricsv1 "github.com/lyonmu/demo/base-demo/api/metrics/v1"
+	"github.com/lyonmu/demo/base-demo/internal/gin"
+	"github.com/lyonmu/demo/base-demo/internal/lifecycle"
+	"github.com/soheilhy/cmux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+// serve 在随机端口上启动与 main 相同的 cmux 组合，返回监听地址
+func serve(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := cmux.New(l)
+	app := lifecycle.New(lifecycle.Options{})
+	app.AddMux("main", m)
+	grpcServer := NewGRPC()
+	app.AddServer("gin", gin.NewGin(nil, grpcServer, m))
+	app.AddServer("grpc", grpcServer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("退出失败: %v", err)
+		}
+	})
+	return l.Addr().String()
+}
+
+func TestGRPC(t *testing.T) {
+	addr := serve(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	hc, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: metricsv1.MetricsService_ServiceDesc.ServiceName})
+	if err != nil || hc.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("健康检查失败: %v %v", hc, err)
+	}
+
+	resp, err := metricsv1.NewMetricsServiceClient(conn).Gather(ctx, &metricsv1.GatherRequest{Names: []string{"go_goroutines"}})
+	if err != nil {
+		t.Fatalf("采集指标失败: %v", err)
+	}
+	if len(resp.GetFamilies()) != 1 || resp.GetFamilies()[0].GetType() != "gauge" || resp.GetFamilies()[0].GetSamples()[0].GetValue() <= 0 {
+		t.Fatalf("指标内容错误: %v", resp.GetFamilies())
+	}
+	if !strings.Contains(resp.GetText(), "# TYPE go_goroutines gauge") {
+		t.Fatalf("文本格式错误: %s", resp.GetText())
+	}
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var services []string
+	for _, s := range ref.GetListServicesResponse().GetService() {
+		services = append(services, s.GetName())
+	}
+	if !strings.Contains(strings.Join(services, ","), metricsv1.MetricsService_ServiceDesc.ServiceName) {
+		t.Fatalf("反射未列出 MetricsService: %v", services)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	addr := serve(t)
+
+	h2c := &http.Transport{Protocols: new(http.Protocols)}
+	h2c.Protocols.SetUnencryptedHTTP2(true)
+
+	for name, client := range map[string]*http.Client{
+		"HTTP/1.1": {Timeout: 5 * time.Second},
+		"h2c":      {Timeout: 5 * time.Second, Transport: h2c},
+	} {
+		resp, err := client.Get("http://" + addr + "/metrics")
+		if err != nil {
+			t.Fatalf("%s 请求失败: %v", name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s 状态码错误: %d", name, resp.StatusCode)
+		}
+		if name == "h2c" && resp.ProtoMajor != 2 {
+			t.Fatalf("h2c 请求应使用 HTTP/2: %s", resp.Proto)
+		}
+	}
+
+	// gRPC 与 h2c 共用同一个 HTTP/2 服务端，h2c 连接在第一个请求后仍可复用
+	var reused bool
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused }}
+	client := &http.Client{Timeout: 5 * time.Second, Transport: h2c}
+	for range 2 {
+		req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, "http://"+addr+"/metrics", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("h2c 请求失败: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if !reused {
+		t.Fatalf("h2c 连接应被复用")
+	}
+}
@@ -0,0 +1,77 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+
+	metricsv1 "github.com/lyonmu/demo/base-demo/api/metrics/v1"
+	"github.com/lyonmu/demo/base-demo/pkg/metrics"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// metricsServer 实现 MetricsService，数据来源与 /metrics 相同
+type metricsServer struct {
+	metricsv1.UnimplementedMetricsServiceServer
+}
+
+func (s *metricsServer) Gather(_ context.Context, req *metricsv1.GatherRequest) (*metricsv1.GatherResponse, error) {
+	families, err := metrics.Registry.Gather()
+	// 与 /metrics 的 ContinueOnError 一致，部分采集失败时仍返回已采集到的指标
+	if err != nil && len(families) == 0 {
+		return nil, status.Errorf(codes.Internal, "gather metrics: %v", err)
+	}
+	if names := req.GetNames(); len(names) > 0 {
+		families = slices.DeleteFunc(families, func(f *dto.MetricFamily) bool {
+			return !slices.Contains(names, f.GetName())
+		})
+	}
+
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.NewFormat(expfmt.TypeTextPlain))
+	resp := &metricsv1.GatherResponse{Families: make([]*metricsv1.MetricFamily, 0, len(families))}
+	for _, f := range families {
+		if err := enc.Encode(f); err != nil {
+			return nil, status.Errorf(codes.Internal, "encode metrics: %v", err)
+		}
+		resp.Families = append(resp.Families, convertFamily(f))
+	}
+	resp.Text = buf.String()
+	return resp, nil
+}
+
+func convertFamily(f *dto.MetricFamily) *metricsv1.MetricFamily {
+	out := &metricsv1.MetricFamily{
+		Name:    f.GetName(),
+		Help:    f.GetHelp(),
+		Type:    strings.ToLower(f.GetType().String()),
+		Samples: make([]*metricsv1.Sample, 0, len(f.GetMetric())),
+	}
+	for _, m := range f.GetMetric() {
+		sample := &metricsv1.Sample{}
+		if len(m.GetLabel()) > 0 {
+			sample.Labels = make(map[string]string, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				sample.Labels[l.GetName()] = l.GetValue()
+			}
+		}
+		switch {
+		case m.Counter != nil:
+			sample.Value = m.GetCounter().GetValue()
+		case m.Gauge != nil:
+			sample.Value = m.GetGauge().GetValue()
+		case m.Untyped != nil:
+			sample.Value = m.GetUntyped().GetValue()
+		case m.Summary != nil:
+			sample.Value, sample.Count = m.GetSummary().GetSampleSum(), m.GetSummary().GetSampleCount()
+		case m.Histogram != nil:
+			sample.Value, sample.Count = m.GetHistogram().GetSampleSum(), m.GetHistogram().GetSampleCount()
+		}
+		out.Samples = append(out.Samples, sample)
+	}
+	return out
+}
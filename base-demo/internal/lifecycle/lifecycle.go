@@ -0,0 +1,216 @@
+// Package lifecycle 管理进程内服务与后台任务的启动和退出：
+// 任一组件异常退出或收到 SIGINT/SIGTERM 时，先关闭 cmux 监听器停止接收新连接，
+// 再按注册的逆序在超时时间内优雅关闭各个服务，最后等待后台任务退出
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/soheilhy/cmux"
+)
+
+// Server 可由 App 管理的服务，Serve 阻塞直到服务停止，Shutdown 在 ctx 到期前优雅关闭
+type Server interface {
+	Serve() error
+	Shutdown(ctx context.Context) error
+}
+
+// Options 生命周期配置
+type Options struct {
+	// ShutdownTimeout 关闭所有服务与等待后台任务的总超时，默认 10s
+	ShutdownTimeout time.Duration
+	// Signals 触发优雅退出的信号，默认 SIGINT、SIGTERM
+	Signals []os.Signal
+}
+
+// App 按注册顺序启动组件，退出时逆序关闭
+type App struct {
+	opts    Options
+	muxes   []namedMux
+	servers []namedServer
+	workers []namedWorker
+}
+
+type namedMux struct {
+	name string
+	m    cmux.CMux
+}
+
+type namedServer struct {
+	name string
+	s    Server
+}
+
+type namedWorker struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// New 创建 App
+func New(opts Options) *App {
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = 10 * time.Second
+	}
+	if len(opts.Signals) == 0 {
+		opts.Signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	return &App{opts: opts}
+}
+
+// AddMux 注册 cmux，Run 时调用 Serve，退出时先于所有服务关闭
+func (a *App) AddMux(name string, m cmux.CMux) {
+	a.muxes = append(a.muxes, namedMux{name: name, m: m})
+}
+
+// AddServer 注册服务，退出时按注册的逆序调用 Shutdown
+func (a *App) AddServer(name string, s Server) {
+	a.servers = append(a.servers, namedServer{name: name, s: s})
+}
+
+// Go 注册后台任务，ctx 在退出开始时取消；任务在 ctx 取消前返回错误视为致命错误
+func (a *App) Go(name string, fn func(ctx context.Context) error) {
+	a.workers = append(a.workers, namedWorker{name: name, fn: fn})
+}
+
+// Run 启动所有组件并阻塞，直到 ctx 取消、收到退出信号或某个组件出错；
+// 返回第一个致命错误与关闭过程中的错误，正常退出时返回 nil
+func (a *App) Run(ctx context.Context) error {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, a.opts.Signals...)
+	defer signal.Stop(sigc)
+
+	workerCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 每个组件最多发送一次，缓冲保证退出后组件返回时不会阻塞
+	errc := make(chan error, len(a.muxes)+len(a.servers)+len(a.workers))
+	var serving, working sync.WaitGroup
+
+	// 服务先于 cmux 启动，cmux 开始分发连接时各匹配器的 Accept 已就绪
+	for _, s := range a.servers {
+		serving.Go(func() {
+			if err := s.s.Serve(); err != nil && !IsClosed(err) {
+				errc <- fmt.Errorf("server %s: %w", s.name, err)
+			}
+		})
+	}
+	for _, m := range a.muxes {
+		serving.Go(func() {
+			if err := m.m.Serve(); err != nil && !IsClosed(err) {
+				errc <- fmt.Errorf("mux %s: %w", m.name, err)
+			}
+		})
+	}
+	for _, w := range a.workers {
+		working.Go(func() {
+			if err := w.fn(workerCtx); err != nil && workerCtx.Err() == nil {
+				errc <- fmt.Errorf("worker %s: %w", w.name, err)
+			}
+		})
+	}
+
+	var cause error
+	select {
+	case sig := <-sigc:
+		slog.Info("Received signal", slog.String("signal", sig.String()))
+	case <-ctx.Done():
+	case cause = <-errc:
+		slog.Error("Component failed", logger.Err(cause))
+	}
+
+	slog.Info("Shutting down")
+	cancel()
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), a.opts.ShutdownTimeout)
+	defer shutdownCancel()
+
+	errs := []error{cause}
+	// 先关闭监听器停止接收新连接，已建立的连接由各服务的 Shutdown 处理
+	for i := len(a.muxes) - 1; i >= 0; i-- {
+		a.muxes[i].m.Close()
+	}
+	for i := len(a.servers) - 1; i >= 0; i-- {
+		s := a.servers[i]
+		if err := s.s.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Failed to shutdown server", slog.String("server", s.name), logger.Err(err))
+			errs = append(errs, fmt.Errorf("shutdown %s: %w", s.name, err))
+		}
+	}
+
+	if err := wait(shutdownCtx, &working); err != nil {
+		slog.Error("Background workers did not exit in time", logger.Err(err))
+		errs = append(errs, fmt.Errorf("wait workers: %w", err))
+	}
+	if err := wait(shutdownCtx, &serving); err != nil {
+		errs = append(errs, fmt.Errorf("wait servers: %w", err))
+	}
+	slog.Info("Shutdown complete")
+	return errors.Join(errs...)
+}
+
+// wait 等待 wg 结束，ctx 先到期时返回 ctx 的错误
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// IsClosed 判断 Serve 返回的错误是否由关闭监听器或服务引起
+func IsClosed(err error) bool {
+	return errors.Is(err, http.ErrServerClosed) || errors.Is(err, cmux.ErrListenerClosed) ||
+		errors.Is(err, cmux.ErrServerClosed) || errors.Is(err, net.ErrClosed)
+}
+
+// ServeAll 在每个监听器上调用 serve，返回第一个非关闭引起的错误，全部正常结束时返回 nil
+func ServeAll(ls []net.Listener, serve func(net.Listener) error) error {
+	errc := make(chan error, len(ls))
+	for _, l := range ls {
+		go func() { errc <- serve(l) }()
+	}
+	for range ls {
+		if err := <-errc; err != nil && !IsClosed(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// HTTP 将 http.Server 包装为在多个监听器上提供服务的 Server
+func HTTP(srv *http.Server, ls ...net.Listener) Server {
+	return &httpServer{srv: srv, ls: ls}
+}
+
+type httpServer struct {
+	srv *http.Server
+	ls  []net.Listener
+}
+
+func (s *httpServer) Serve() error {
+	return ServeAll(s.ls, s.srv.Serve)
+}
+
+func (s *httpServer) Shutdown(ctx context.Context) error {
+	// cmux 子监听器的 Close 会关闭共享的底层监听器，App 已先关闭 cmux，忽略重复关闭的错误
+	if err := s.srv.Shutdown(ctx); err != nil && !IsClosed(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/soheilhy/cmux"
+)
+
+// fakeServer 的 Serve 阻塞到 Shutdown 被调用，并记录关闭顺序
+type fakeServer struct {
+	name  string
+	order *[]string
+	mu    *sync.Mutex
+	stop  chan struct{}
+	block bool
+}
+
+func newFake(name string, order *[]string, mu *sync.Mutex) *fakeServer {
+	return &fakeServer{name: name, order: order, mu: mu, stop: make(chan struct{})}
+}
+
+func (s *fakeServer) Serve() error {
+	<-s.stop
+	return http.ErrServerClosed
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	*s.order = append(*s.order, s.name)
+	s.mu.Unlock()
+	if s.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	close(s.stop)
+	return nil
+}
+
+func TestRunWorkerError(t *testing.T) {
+	var (
+		order []string
+		mu    sync.Mutex
+	)
+	app := New(Options{})
+	app.AddServer("a", newFake("a", &order, &mu))
+	app.AddServer("b", newFake("b", &order, &mu))
+
+	boom := errors.New("boom")
+	app.Go("failing", func(ctx context.Context) error { return boom })
+	stopped := make(chan struct{})
+	app.Go("waiting", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return ctx.Err()
+	})
+
+	err := app.Run(context.Background())
+	if !errors.Is(err, boom) {
+		t.Fatalf("应返回后台任务的错误: %v", err)
+	}
+	if !slices.Equal(order, []string{"b", "a"}) {
+		t.Fatalf("服务应按注册的逆序关闭: %v", order)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Fatalf("退出时应取消其他后台任务")
+	}
+}
+
+func TestRunShutdownTimeout(t *testing.T) {
+	var (
+		order []string
+		mu    sync.Mutex
+	)
+	stuck := newFake("stuck", &order, &mu)
+	stuck.block = true
+	app := New(Options{ShutdownTimeout: 50 * time.Millisecond})
+	app.AddServer("stuck", stuck)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	err := app.Run(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("关闭超时应返回 DeadlineExceeded: %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("关闭应在超时后返回，实际耗时 %s", d)
+	}
+}
+
+func TestRunSignal(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := cmux.New(l)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "ok")
+	})}
+
+	app := New(Options{Signals: []os.Signal{syscall.SIGUSR1}})
+	app.AddMux("main", m)
+	app.AddServer("http", HTTP(srv, m.Match(cmux.Any())))
+	done := make(chan error, 1)
+	go func() { done <- app.Run(context.Background()) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String())
+		if err != nil {
+			resc <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		resc <- result{body: string(b), err: err}
+	}()
+	<-started
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	// 收到信号后监听器关闭，新连接被拒绝
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			break
+		}
+		c.Close()
+		if time.Now().After(deadline) {
+			t.Fatalf("退出后监听器应关闭")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 进行中的请求在关闭期间完成
+	close(release)
+	if res := <-resc; res.err != nil || res.body != "ok" {
+		t.Fatalf("进行中的请求应正常完成: %q %v", res.body, res.err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("正常退出不应返回错误: %v", err)
+	}
+}
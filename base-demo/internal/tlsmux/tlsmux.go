@@ -0,0 +1,187 @@
+// Package tlsmux 在 cmux 上识别 TLS ClientHello，进程内按 SNI 选择证书终止 TLS，
+// 再将解密后的连接交给新的 cmux 按 HTTP/1.1、HTTP/2 分流（gRPC 在 HTTP/2 服务端内按 content-type 处理）；明文 HTTP 可选重定向到 HTTPS
+package tlsmux
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/internal/lifecycle"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/soheilhy/cmux"
+)
+
+// Options TLS 配置
+type Options struct {
+	// Certs 证书与私钥文件，为空时不启用 TLS
+	Certs []CertFile
+	// RedirectHTTP 为 true 时明文 HTTP/1.x 请求以 308 重定向到同一端口的 HTTPS
+	RedirectHTTP bool
+	// HandshakeTimeout TLS 握手与协议识别的超时，默认 10s
+	HandshakeTimeout time.Duration
+}
+
+// CertFile 一对证书与私钥文件
+type CertFile struct {
+	CertFile string
+	KeyFile  string
+}
+
+// OptionsFromEnv 从环境变量读取 TLS 配置：
+// TLS_CERTS 为逗号分隔的 cert:key 文件对，TLS_REDIRECT_HTTP 为 true 时重定向明文 HTTP
+func OptionsFromEnv() (Options, error) {
+	var opts Options
+	for _, pair := range strings.Split(os.Getenv("TLS_CERTS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		cert, key, ok := strings.Cut(pair, ":")
+		if !ok || cert == "" || key == "" {
+			return Options{}, fmt.Errorf("TLS_CERTS: invalid pair %q, want cert:key", pair)
+		}
+		opts.Certs = append(opts.Certs, CertFile{CertFile: cert, KeyFile: key})
+	}
+	if v := os.Getenv("TLS_REDIRECT_HTTP"); v != "" {
+		redirect, err := strconv.ParseBool(v)
+		if err != nil {
+			return Options{}, fmt.Errorf("TLS_REDIRECT_HTTP: %w", err)
+		}
+		opts.RedirectHTTP = redirect
+	}
+	return opts, nil
+}
+
+// CertStore 按 SNI 选择证书：精确匹配优先，其次通配符，都不匹配或客户端未发送 SNI 时使用第一张证书
+type CertStore struct {
+	byName map[string]*tls.Certificate
+	def    *tls.Certificate
+}
+
+// LoadCerts 加载证书，名称取自证书的 DNS SAN，没有 SAN 时取 CN
+func LoadCerts(files []CertFile) (*CertStore, error) {
+	if len(files) == 0 {
+		return nil, errors.New("no certificates")
+	}
+	s := &CertStore{byName: make(map[string]*tls.Certificate)}
+	for _, f := range files {
+		cert, err := tls.LoadX509KeyPair(f.CertFile, f.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", f.CertFile, err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", f.CertFile, err)
+		}
+		cert.Leaf = leaf
+
+		names := leaf.DNSNames
+		if len(names) == 0 && leaf.Subject.CommonName != "" {
+			names = []string{leaf.Subject.CommonName}
+		}
+		for _, name := range names {
+			name = strings.ToLower(name)
+			if _, ok := s.byName[name]; ok {
+				slog.Warn("Duplicate certificate name, keeping the first one", slog.String("name", name), slog.String("file", f.CertFile))
+				continue
+			}
+			s.byName[name] = &cert
+		}
+		if s.def == nil {
+			s.def = &cert
+		}
+		slog.Info("Loaded TLS certificate", slog.String("file", f.CertFile), slog.Any("names", names), slog.Time("not_after", leaf.NotAfter))
+	}
+	return s, nil
+}
+
+// GetCertificate 实现 tls.Config.GetCertificate
+func (s *CertStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if name == "" {
+		return s.def, nil
+	}
+	if cert := s.lookup(name); cert != nil {
+		return cert, nil
+	}
+	slog.Debug("No certificate for SNI, using default", slog.String("server_name", name))
+	return s.def, nil
+}
+
+// HasName 报告 host（不含端口，大小写不敏感）是否在某张证书的名称中，通配符规则与 GetCertificate 相同
+func (s *CertStore) HasName(host string) bool {
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+	return name != "" && s.lookup(name) != nil
+}
+
+// lookup 按名称精确匹配，其次匹配通配符，name 须已转为小写
+func (s *CertStore) lookup(name string) *tls.Certificate {
+	if cert, ok := s.byName[name]; ok {
+		return cert
+	}
+	// 通配符只匹配一级子域名
+	if _, parent, ok := strings.Cut(name, "."); ok {
+		if cert, ok := s.byName["*."+parent]; ok {
+			return cert
+		}
+	}
+	return nil
+}
+
+// TLSConfig 返回按 SNI 选择证书的 TLS 配置，ALPN 同时声明 h2 与 http/1.1
+func (s *CertStore) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: s.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+		MinVersion:     tls.VersionTLS12,
+	}
+}
+
+// Split 匹配 m 上的 TLS 连接并终止 TLS，返回承载解密后连接的 cmux，
+// 调用方在其上注册 HTTP/1.1 与 HTTP/2 匹配器（gRPC 由 HTTP/2 服务端按 content-type 分流）后负责 Serve 与 Close；
+// 需要在 m 的其他匹配器之前调用
+func Split(m cmux.CMux, store *CertStore, opts Options) cmux.CMux {
+	timeout := opts.HandshakeTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	inner := cmux.New(tls.NewListener(m.Match(cmux.TLS()), store.TLSConfig()))
+	// 协议识别时首次读取会触发握手，超时同时限制握手时长
+	inner.SetReadTimeout(timeout)
+	inner.HandleError(func(err error) bool {
+		slog.Debug("TLS connection rejected", logger.Err(err))
+		return true
+	})
+	return inner
+}
+
+// Redirect 将 m 上的明文 HTTP/1.x 请求以 308 重定向到同一地址的 HTTPS。Host 由客户端控制，
+// 只有主机名属于 store 中某张证书时才重定向，其余请求返回 400，避免被用作开放重定向；
+// 需要在 gin 的匹配器之前调用，明文 gRPC 与 h2c 请求不受影响，返回的服务由调用方启动与关闭
+func Redirect(m cmux.CMux, store *CertStore) lifecycle.Server {
+	srv := &http.Server{Handler: redirect(store), ReadHeaderTimeout: 5 * time.Second}
+	return lifecycle.HTTP(srv, m.Match(cmux.HTTP1Fast()))
+}
+
+func redirect(store *CertStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !store.HasName(host) {
+			http.Error(w, "unknown host", http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, "https://"+r.Host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	}
+}
@@ -0,0 +1,220 @@
+package tlsmux
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/internal/gin"
+	"github.com/lyonmu/demo/base-demo/internal/grpc"
+	"github.com/lyonmu/demo/base-demo/internal/lifecycle"
+	"github.com/soheilhy/cmux"
+	grpcgo "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// writeCert 生成自签名证书，cn 写入 CN，names 写入 DNS SAN
+func writeCert(t *testing.T, cn string, names ...string) CertFile {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	f := CertFile{CertFile: filepath.Join(dir, cn+".crt"), KeyFile: filepath.Join(dir, cn+".key")}
+	if err := os.WriteFile(f.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(f.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestGetCertificate(t *testing.T) {
+	store, err := LoadCerts([]CertFile{
+		writeCert(t, "example.com", "example.com", "www.example.com"),
+		writeCert(t, "wildcard", "*.api.example.com"),
+		writeCert(t, "cn-only.example.org"),
+	})
+	if err != nil {
+		t.Fatalf("加载证书失败: %v", err)
+	}
+	for sni, want := range map[string]string{
+		"":                     "example.com",
+		"WWW.example.com.":     "example.com",
+		"v1.api.example.com":   "wildcard",
+		"a.v1.api.example.com": "example.com",
+		"cn-only.example.org":  "cn-only.example.org",
+		"unknown.test":         "example.com",
+	} {
+		cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: sni})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cert.Leaf.Subject.CommonName != want {
+			t.Errorf("SNI %q: 期望证书 %s，实际 %s", sni, want, cert.Leaf.Subject.CommonName)
+		}
+	}
+
+	// HasName 不回退到默认证书
+	for host, want := range map[string]bool{
+		"WWW.example.com":      true,
+		"v1.api.example.com":   true,
+		"a.v1.api.example.com": false,
+		"unknown.test":         false,
+		"":                     false,
+	} {
+		if got := store.HasName(host); got != want {
+			t.Errorf("HasName(%q) = %t，期望 %t", host, got, want)
+		}
+	}
+
+	if _, err := LoadCerts([]CertFile{{CertFile: "missing.crt", KeyFile: "missing.key"}}); err == nil {
+		t.Fatalf("证书文件不存在时应返回错误")
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv("TLS_CERTS", "a.crt:a.key, b.crt:b.key")
+	t.Setenv("TLS_REDIRECT_HTTP", "true")
+	opts, err := OptionsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.Certs) != 2 || opts.Certs[1] != (CertFile{CertFile: "b.crt", KeyFile: "b.key"}) || !opts.RedirectHTTP {
+		t.Fatalf("环境变量解析错误: %+v", opts)
+	}
+
+	t.Setenv("TLS_CERTS", "a.crt")
+	if _, err := OptionsFromEnv(); err == nil {
+		t.Fatalf("缺少私钥时应返回错误")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	cert := writeCert(t, "example.com", "example.com")
+	store, err := LoadCerts([]CertFile{cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := cmux.New(l)
+	inner := Split(m, store, Options{})
+	muxes := []cmux.CMux{m, inner}
+	app := lifecycle.New(lifecycle.Options{})
+	app.AddMux("main", m)
+	app.AddMux("tls", inner)
+	app.AddServer("redirect", Redirect(m, store))
+	grpcServer := grpc.NewGRPC()
+	app.AddServer("gin", gin.NewGin(nil, grpcServer, muxes...))
+	app.AddServer("grpc", grpcServer)
+
+	appCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go app.Run(appCtx)
+	addr := l.Addr().String()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(store.def.Leaf)
+	tlsConfig := &tls.Config{RootCAs: pool, ServerName: "example.com"}
+
+	// HTTPS：ALPN 协商 HTTP/1.1 与 HTTP/2
+	for _, proto := range []string{"http/1.1", "h2"} {
+		tr := &http.Transport{TLSClientConfig: tlsConfig.Clone(), ForceAttemptHTTP2: proto == "h2"}
+		tr.TLSClientConfig.NextProtos = []string{proto}
+		resp, err := (&http.Client{Transport: tr, Timeout: 5 * time.Second}).Get("https://" + addr + "/metrics")
+		if err != nil {
+			t.Fatalf("%s 请求失败: %v", proto, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.TLS == nil || resp.TLS.NegotiatedProtocol != proto {
+			t.Fatalf("%s 响应错误: %d %s", proto, resp.StatusCode, resp.Proto)
+		}
+	}
+
+	// 明文 HTTP/1.1 重定向到 HTTPS
+	client := &http.Client{Timeout: 5 * time.Second, CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	_, port, _ := net.SplitHostPort(addr)
+	for host, want := range map[string]string{
+		"example.com:" + port: "https://example.com:" + port + "/metrics?x=1",
+		"EXAMPLE.com":         "https://EXAMPLE.com/metrics?x=1",
+		// 不属于证书的 Host（包括 IP 与任意域名）不重定向
+		addr:               "",
+		"evil.example.net": "",
+		"evil.com:" + port: "",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, "http://"+addr+"/metrics?x=1", nil)
+		req.Host = host
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if want == "" {
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("Host %s 应返回 400: %d %s", host, resp.StatusCode, resp.Header.Get("Location"))
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusPermanentRedirect || resp.Header.Get("Location") != want {
+			t.Fatalf("Host %s 重定向错误: %d %s", host, resp.StatusCode, resp.Header.Get("Location"))
+		}
+	}
+
+	// gRPC over TLS 与明文 gRPC
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for name, creds := range map[string]credentials.TransportCredentials{
+		"tls":       credentials.NewTLS(tlsConfig),
+		"plaintext": insecure.NewCredentials(),
+	} {
+		conn, err := grpcgo.NewClient(addr, grpcgo.WithTransportCredentials(creds))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hc, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		conn.Close()
+		if err != nil || hc.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("%s gRPC 健康检查失败: %v %v", name, hc, err)
+		}
+	}
+}
@@ -1,31 +1,124 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"log/slog"
 	"net"
 	"os"
+	"time"
 
+	"github.com/lyonmu/demo/base-demo/internal/diag"
 	"github.com/lyonmu/demo/base-demo/internal/gin"
+	"github.com/lyonmu/demo/base-demo/internal/grpc"
+	"github.com/lyonmu/demo/base-demo/internal/lifecycle"
+	"github.com/lyonmu/demo/base-demo/internal/tlsmux"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/base-demo/pkg/metrics"
+	"github.com/lyonmu/demo/base-demo/pkg/tracing"
 	"github.com/soheilhy/cmux"
 )
 
 func main() {
 
+	logger.Init(logger.OptionsFromEnv())
+
+	traceOpts, err := tracing.OptionsFromEnv()
+	if err != nil {
+		slog.Error("Invalid tracing config", logger.Err(err))
+		os.Exit(1)
+	}
+	shutdownTracing, err := tracing.Init(context.Background(), gin.ServiceName, traceOpts)
+	if err != nil {
+		slog.Error("Failed to init tracing", logger.Err(err))
+		os.Exit(1)
+	}
+
 	l, err := net.Listen("tcp", ":9024")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to listen: %v", err)
+		slog.Error("Failed to listen", logger.Err(err))
 		os.Exit(1)
 	}
 
 	m := cmux.New(l)
+	muxes := []cmux.CMux{m}
+	app := lifecycle.New(lifecycle.Options{})
+	app.AddMux("main", m)
+
+	// 配置了证书时同一端口同时提供 TLS：TLS 匹配器最先注册，解密后的连接在 tlsMux 上再次分流
+	tlsOpts, err := tlsmux.OptionsFromEnv()
+	if err != nil {
+		slog.Error("Invalid TLS config", logger.Err(err))
+		os.Exit(1)
+	}
+	if len(tlsOpts.Certs) > 0 {
+		store, err := tlsmux.LoadCerts(tlsOpts.Certs)
+		if err != nil {
+			slog.Error("Failed to load TLS certificates", logger.Err(err))
+			os.Exit(1)
+		}
+		tlsMux := tlsmux.Split(m, store, tlsOpts)
+		app.AddMux("tls", tlsMux)
+		muxes = append(muxes, tlsMux)
+
+		if tlsOpts.RedirectHTTP {
+			app.AddServer("https-redirect", tlsmux.Redirect(m, store))
+		}
+	}
 
-	if err := gin.NewGin(m); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create gin engine: %v", err)
+	// 诊断接口：配置了 DIAG_ADDR 时独立监听，否则挂载到主端口（需要 DIAG_TOKEN）；未配置两者时不提供
+	diagOpts, err := diag.OptionsFromEnv()
+	if err != nil {
+		slog.Error("Invalid diagnostics config", logger.Err(err))
 		os.Exit(1)
 	}
+	var mounted *diag.Diagnostics
+	if diagOpts.Addr != "" || diagOpts.Token != "" {
+		d, err := diag.New(diagOpts)
+		if err != nil {
+			slog.Error("Failed to create diagnostics", logger.Err(err))
+			os.Exit(1)
+		}
+		if diagOpts.Addr == "" {
+			mounted = d
+		} else {
+			diagServer, err := d.Listen()
+			if err != nil {
+				slog.Error("Failed to listen diagnostics", logger.Err(err))
+				os.Exit(1)
+			}
+			app.AddServer("diag", diagServer)
+		}
+	}
+
+	// gRPC 请求由 gin 的 http.Server 按 content-type 分流；gRPC 注册在 gin 之后，退出时先置为 NOT_SERVING
+	grpcServer := grpc.NewGRPC()
+	app.AddServer("gin", gin.NewGin(mounted, grpcServer, muxes...))
+	app.AddServer("grpc", grpcServer)
 
-	if err := m.Serve(); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to serve: %v", err)
+	// 进程存活时间短、来不及被抓取时主动推送指标，退出时会再导出一次
+	exportOpts, err := metrics.ExportOptionsFromEnv()
+	if err != nil {
+		slog.Error("Invalid metrics export config", logger.Err(err))
+		os.Exit(1)
+	}
+	if exportOpts.Push.URL != "" {
+		app.Go("metrics-push", metrics.NewPusher(exportOpts.Push).Run)
+	}
+	if exportOpts.RemoteWrite.URL != "" {
+		app.Go("metrics-remote-write", metrics.NewRemoteWriter(exportOpts.RemoteWrite).Run)
+	}
+
+	slog.Info("Listening", slog.String("addr", l.Addr().String()))
+	err = app.Run(context.Background())
+
+	// 所有服务退出后再刷新剩余的 span，关闭过程中产生的 span 也能导出
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if terr := shutdownTracing(ctx); terr != nil {
+		slog.Error("Failed to flush traces", logger.Err(terr))
+	}
+	cancel()
+	if err != nil {
+		slog.Error("Exited with error", logger.Err(err))
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,59 @@
+// Package consul 提供各个 demo 共用的 Consul 客户端辅助：阻塞查询循环与连接配置
+package consul
+
+import (
+	"context"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+// WatchOptions BlockingQuery 的参数
+type WatchOptions struct {
+	// Index 首次查询的 WaitIndex，0 表示立即返回当前数据
+	Index uint64
+	// WaitTime 阻塞查询的最长等待时间，默认 5m
+	WaitTime time.Duration
+	// RetryInterval 查询失败后的重试间隔，默认 5s
+	RetryInterval time.Duration
+	// OnError 查询失败时调用（ctx 结束导致的错误除外），用于记录日志与指标，可为 nil
+	OnError func(error)
+}
+
+// BlockingQuery 循环执行阻塞查询直到 ctx 结束。query 使用传入的 QueryOptions（已带 WaitIndex、WaitTime 与 ctx）
+// 查询并处理结果，返回响应的 QueryMeta；下一次查询以 LastIndex 作为 WaitIndex。
+// LastIndex 回退（如快照恢复、Consul 集群重建）时重置为 0，避免阻塞查询永远不返回
+func BlockingQuery(ctx context.Context, opts WatchOptions, query func(q *capi.QueryOptions) (*capi.QueryMeta, error)) {
+	if opts.WaitTime <= 0 {
+		opts.WaitTime = 5 * time.Minute
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+
+	index := opts.Index
+	for ctx.Err() == nil {
+		q := (&capi.QueryOptions{WaitIndex: index, WaitTime: opts.WaitTime}).WithContext(ctx)
+		meta, err := query(q)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if opts.OnError != nil {
+				opts.OnError(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(opts.RetryInterval):
+			}
+			continue
+		}
+
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+	}
+}
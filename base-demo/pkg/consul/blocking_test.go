@@ -0,0 +1,82 @@
+package consul
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+func TestBlockingQuery(t *testing.T) {
+	// 依次返回的 X-Consul-Index，第 3 次回退，第 4 次失败
+	indexes := []string{"10", "12", "5", "", "7"}
+	var (
+		mu    sync.Mutex
+		waits []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n := len(waits)
+		waits = append(waits, r.URL.Query().Get("index"))
+		mu.Unlock()
+		if n >= len(indexes) || indexes[n] == "" {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Consul-Index", indexes[n])
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	client, err := capi.NewClient(&capi.Config{Address: srv.Listener.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var errs int
+	BlockingQuery(ctx, WatchOptions{
+		Index:         3,
+		RetryInterval: time.Millisecond,
+		OnError:       func(error) { errs++ },
+	}, func(q *capi.QueryOptions) (*capi.QueryMeta, error) {
+		_, meta, err := client.KV().List("demo/", q)
+		mu.Lock()
+		if len(waits) == len(indexes) {
+			cancel()
+		}
+		mu.Unlock()
+		return meta, err
+	})
+
+	// 首次使用 Index，之后沿用 LastIndex；回退时重置为 0（不携带 index 参数），失败后以原 index 重试
+	want := []string{"3", "10", "12", "", ""}
+	if !slices.Equal(waits, want) {
+		t.Fatalf("WaitIndex 错误: got %v, want %v", waits, want)
+	}
+	if errs != 1 {
+		t.Fatalf("OnError 应调用 1 次，实际 %d 次", errs)
+	}
+}
+
+func TestBlockingQueryCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		BlockingQuery(ctx, WatchOptions{RetryInterval: time.Hour, OnError: func(error) { cancel() }},
+			func(*capi.QueryOptions) (*capi.QueryMeta, error) { return nil, errors.New("boom") })
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ctx 结束后应立即返回")
+	}
+}
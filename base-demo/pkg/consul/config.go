@@ -0,0 +1,34 @@
+package consul
+
+import (
+	"os"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+// Config Agent 连接配置，CONSUL_HTTP_ADDR、CONSUL_HTTP_TOKEN 等环境变量优先于配置文件
+type Config struct {
+	Address    string `yaml:"address"`
+	Scheme     string `yaml:"scheme"`
+	Datacenter string `yaml:"datacenter"`
+	Token      string `yaml:"token"`
+}
+
+// APIConfig 生成 Consul 客户端配置，优先级：环境变量 > 配置文件 > 默认值
+func (c Config) APIConfig() *capi.Config {
+	// DefaultConfig 已读取 CONSUL_HTTP_ADDR、CONSUL_HTTP_TOKEN、CONSUL_HTTP_SSL 等环境变量
+	cfg := capi.DefaultConfig()
+	if os.Getenv(capi.HTTPAddrEnvName) == "" && c.Address != "" {
+		cfg.Address = c.Address
+	}
+	if os.Getenv(capi.HTTPTokenEnvName) == "" && os.Getenv(capi.HTTPTokenFileEnvName) == "" && c.Token != "" {
+		cfg.Token = c.Token
+	}
+	if os.Getenv(capi.HTTPSSLEnvName) == "" && c.Scheme != "" {
+		cfg.Scheme = c.Scheme
+	}
+	if c.Datacenter != "" {
+		cfg.Datacenter = c.Datacenter
+	}
+	return cfg
+}
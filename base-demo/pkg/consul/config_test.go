@@ -0,0 +1,27 @@
+package consul
+
+import (
+	"testing"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+func TestAPIConfig(t *testing.T) {
+	c := Config{Address: "10.0.0.1:8500", Scheme: "https", Datacenter: "dc2", Token: "file-token"}
+
+	t.Setenv(capi.HTTPAddrEnvName, "")
+	t.Setenv(capi.HTTPTokenEnvName, "")
+	t.Setenv(capi.HTTPSSLEnvName, "")
+	cfg := c.APIConfig()
+	if cfg.Address != c.Address || cfg.Scheme != "https" || cfg.Datacenter != "dc2" || cfg.Token != "file-token" {
+		t.Fatalf("未使用配置文件中的值: %+v", cfg)
+	}
+
+	// 环境变量优先
+	t.Setenv(capi.HTTPAddrEnvName, "127.0.0.1:18500")
+	t.Setenv(capi.HTTPTokenEnvName, "env-token")
+	cfg = c.APIConfig()
+	if cfg.Address != "127.0.0.1:18500" || cfg.Token != "env-token" {
+		t.Fatalf("环境变量未优先: %+v", cfg)
+	}
+}
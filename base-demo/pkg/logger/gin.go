@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GinMiddleware 替代 gin.Logger() 的结构化访问日志中间件
+//
+// 从 X-Request-ID 读取请求 ID（缺失或不合法时生成，见 ValidRequestID），写入 request context 并回写到响应头，
+// 便于 Envoy 与后端日志关联；query 中的敏感参数会被脱敏。
+func GinMiddleware(l *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(HeaderRequestID)
+		if !ValidRequestID(id) {
+			id = NewRequestID()
+		}
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Header(HeaderRequestID, id)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		lvl := slog.LevelInfo
+		switch {
+		case status >= 500:
+			lvl = slog.LevelError
+		case status >= 400:
+			lvl = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("size", c.Writer.Size()),
+		}
+		if q := c.Request.URL.RawQuery; q != "" {
+			attrs = append(attrs, slog.String("query", RedactQuery(q)))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		l.LogAttrs(c.Request.Context(), lvl, "http request", attrs...)
+	}
+}
+
+// RedactQuery 对 query 字符串中的敏感参数脱敏
+func RedactQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return RedactedValue
+	}
+	for k := range values {
+		if IsSecret(k) {
+			values[k] = []string{RedactedValue}
+		}
+	}
+	return values.Encode()
+}
@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// RedactedValue 敏感字段脱敏后的占位值
+	RedactedValue = "REDACTED"
+)
+
+// DefaultSecretKeys 默认需要脱敏的字段名（不区分大小写）
+var DefaultSecretKeys = []string{
+	"token",
+	"access_token",
+	"refresh_token",
+	"authorization",
+	"auth_header",
+	"password",
+	"secret",
+	"cookie",
+	"set-cookie",
+	"x-consul-token",
+}
+
+// Options 日志配置
+type Options struct {
+	// Level 日志级别：debug、info、warn、error
+	Level string
+	// Format 输出格式：json（默认）或 text
+	Format string
+	// AddSource 是否输出调用位置
+	AddSource bool
+	// SecretKeys 额外需要脱敏的字段名
+	SecretKeys []string
+	// Output 输出目标，默认 os.Stdout
+	Output io.Writer
+}
+
+var (
+	// level 全局日志级别，支持运行时调整
+	level = new(slog.LevelVar)
+	// secrets 当前生效的脱敏字段集合
+	secrets atomic.Pointer[map[string]struct{}]
+)
+
+func init() {
+	setSecretKeys(nil)
+}
+
+// OptionsFromEnv 从环境变量读取日志配置（LOG_LEVEL、LOG_FORMAT、LOG_ADD_SOURCE）
+func OptionsFromEnv() Options {
+	addSource, _ := strconv.ParseBool(os.Getenv("LOG_ADD_SOURCE"))
+	return Options{
+		Level:     os.Getenv("LOG_LEVEL"),
+		Format:    os.Getenv("LOG_FORMAT"),
+		AddSource: addSource,
+	}
+}
+
+// New 根据配置创建 slog.Logger，敏感字段默认脱敏，并自动附带 context 中的请求 ID
+func New(opts Options) *slog.Logger {
+	if err := SetLevel(opts.Level); err != nil {
+		level.Set(slog.LevelInfo)
+	}
+
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	setSecretKeys(opts.SecretKeys)
+
+	handlerOpts := &slog.HandlerOptions{
+		AddSource: opts.AddSource,
+		Level:     level,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if IsSecret(a.Key) && a.Value.String() != "" {
+				return slog.String(a.Key, RedactedValue)
+			}
+			return a
+		},
+	}
+
+	var h slog.Handler
+	if strings.EqualFold(opts.Format, "text") {
+		h = slog.NewTextHandler(out, handlerOpts)
+	} else {
+		h = slog.NewJSONHandler(out, handlerOpts)
+	}
+	return slog.New(&contextHandler{Handler: h})
+}
+
+// Init 创建 Logger 并设置为 slog 默认 Logger（同时接管标准库 log 的输出）
+func Init(opts Options) *slog.Logger {
+	l := New(opts)
+	slog.SetDefault(l)
+	return l
+}
+
+// SetLevel 运行时调整日志级别，空字符串视为 info
+func SetLevel(s string) error {
+	if s == "" {
+		level.Set(slog.LevelInfo)
+		return nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
+// Level 返回当前日志级别
+func Level() slog.Level {
+	return level.Level()
+}
+
+// IsSecret 判断字段名是否需要脱敏
+func IsSecret(key string) bool {
+	_, ok := (*secrets.Load())[strings.ToLower(key)]
+	return ok
+}
+
+func setSecretKeys(extra []string) {
+	m := make(map[string]struct{}, len(DefaultSecretKeys)+len(extra))
+	for _, k := range DefaultSecretKeys {
+		m[strings.ToLower(k)] = struct{}{}
+	}
+	for _, k := range extra {
+		m[strings.ToLower(k)] = struct{}{}
+	}
+	secrets.Store(&m)
+}
+
+// Err 统一的错误字段
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedact(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(Options{Output: &buf, SecretKeys: []string{"api_key"}})
+
+	l.Info("login", slog.String("token", "abc"), slog.String("api_key", "k"), slog.String("user_id", "u1"))
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("日志不是合法 JSON: %v", err)
+	}
+	if rec["token"] != RedactedValue || rec["api_key"] != RedactedValue {
+		t.Fatalf("敏感字段未脱敏: %v", rec)
+	}
+	if rec["user_id"] != "u1" {
+		t.Fatalf("普通字段被修改: %v", rec)
+	}
+
+	if q := RedactQuery("token=abc&user_id=u1"); strings.Contains(q, "abc") || !strings.Contains(q, "user_id=u1") {
+		t.Fatalf("query 脱敏结果错误: %s", q)
+	}
+}
+
+func TestGinMiddlewareRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	l := New(Options{Output: &buf})
+
+	r := gin.New()
+	r.Use(GinMiddleware(l))
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, RequestIDFrom(c.Request.Context()))
+	})
+
+	// 透传上游的 X-Request-ID
+	req := httptest.NewRequest(http.MethodGet, "/ping?token=abc", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get(HeaderRequestID); got != "req-1" || w.Body.String() != "req-1" {
+		t.Fatalf("请求 ID 未透传: header=%q body=%q", got, w.Body.String())
+	}
+	if !strings.Contains(buf.String(), `"request_id":"req-1"`) || strings.Contains(buf.String(), "abc") {
+		t.Fatalf("访问日志不符合预期: %s", buf.String())
+	}
+
+	// 缺失时自动生成
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if got := w.Header().Get(HeaderRequestID); got == "" || got != w.Body.String() {
+		t.Fatalf("未生成请求 ID: header=%q body=%q", got, w.Body.String())
+	}
+
+	// 过长或包含非法字符时丢弃并重新生成
+	for _, bad := range []string{strings.Repeat("a", MaxRequestIDLength+1), "id\x00evil", "a b", `"}{`} {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header.Set(HeaderRequestID, bad)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get(HeaderRequestID); got == bad || !ValidRequestID(got) || strings.Contains(buf.String(), bad) {
+			t.Fatalf("非法请求 ID 未替换: %q -> %q", bad, got)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const (
+	defaultExportInterval = 15 * time.Second
+	// finalExportTimeout 退出时最后一次导出的超时
+	finalExportTimeout = 5 * time.Second
+)
+
+// ExportOptions 主动导出配置，用于进程存活时间短、来不及被 Prometheus 抓取的场景
+type ExportOptions struct {
+	Push        PushOptions
+	RemoteWrite RemoteWriteOptions
+}
+
+// ExportOptionsFromEnv 从环境变量读取导出配置：
+// METRICS_PUSH_URL、METRICS_PUSH_JOB、METRICS_PUSH_INTERVAL 配置 Pushgateway，
+// METRICS_REMOTE_WRITE_URL、METRICS_REMOTE_WRITE_INTERVAL 配置 remote write，URL 为空时不启用
+func ExportOptionsFromEnv() (ExportOptions, error) {
+	opts := ExportOptions{
+		Push:        PushOptions{URL: os.Getenv("METRICS_PUSH_URL"), Job: os.Getenv("METRICS_PUSH_JOB")},
+		RemoteWrite: RemoteWriteOptions{URL: os.Getenv("METRICS_REMOTE_WRITE_URL")},
+	}
+	for env, d := range map[string]*time.Duration{
+		"METRICS_PUSH_INTERVAL":         &opts.Push.Interval,
+		"METRICS_REMOTE_WRITE_INTERVAL": &opts.RemoteWrite.Interval,
+	} {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return ExportOptions{}, fmt.Errorf("%s: %w", env, err)
+		}
+		*d = parsed
+	}
+	return opts, nil
+}
+
+// Export 向已配置的 Pushgateway 与 remote write 接收端各导出一次，URL 为空的导出方式跳过；
+// 用于批处理任务结束前推送最终结果，两者都失败时返回合并后的错误
+func Export(ctx context.Context, opts ExportOptions) error {
+	var errs []error
+	if opts.Push.URL != "" {
+		if err := NewPusher(opts.Push).Push(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("pushgateway: %w", err))
+		}
+	}
+	if opts.RemoteWrite.URL != "" {
+		if err := NewRemoteWriter(opts.RemoteWrite).Write(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PushOptions Pushgateway 推送配置
+type PushOptions struct {
+	// URL Pushgateway 地址，例如 http://127.0.0.1:9091
+	URL string
+	// Job 分组中的 job 标签，默认取可执行文件名
+	Job string
+	// Grouping 额外的分组标签，默认 instance=<hostname>，避免多个实例互相覆盖
+	Grouping map[string]string
+	// Interval 推送间隔，默认 15s
+	Interval time.Duration
+	// Client 默认使用 10s 超时的 http.Client
+	Client *http.Client
+}
+
+// Pusher 定期将 Registry 推送到 Pushgateway，退出时再推送一次
+type Pusher struct {
+	opts PushOptions
+	p    *push.Pusher
+}
+
+// NewPusher 创建 Pusher，需调用 Run 开始推送
+func NewPusher(opts PushOptions) *Pusher {
+	if opts.Job == "" {
+		opts.Job = filepath.Base(os.Args[0])
+	}
+	if opts.Grouping == nil {
+		host, _ := os.Hostname()
+		opts.Grouping = map[string]string{"instance": host}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultExportInterval
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	p := push.New(opts.URL, opts.Job).Gatherer(Registry).Client(opts.Client)
+	for k, v := range opts.Grouping {
+		p = p.Grouping(k, v)
+	}
+	return &Pusher{opts: opts, p: p}
+}
+
+// Push 以 PUT 方式推送一次，替换同一分组下的全部指标
+func (p *Pusher) Push(ctx context.Context) error {
+	return p.p.PushContext(ctx)
+}
+
+// Run 按间隔推送直到 ctx 结束；推送失败只记录日志，返回值为退出时最后一次推送的结果
+func (p *Pusher) Run(ctx context.Context) error {
+	return runPeriodically(ctx, "pushgateway", p.opts.Interval, p.Push)
+}
+
+// runPeriodically 每隔 interval 调用一次 export，ctx 结束后在 finalExportTimeout 内再调用一次，
+// 保证退出前的最新数据被导出
+func runPeriodically(ctx context.Context, name string, interval time.Duration, export func(context.Context) error) error {
+	log := slog.With(slog.String("exporter", name))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			finalCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finalExportTimeout)
+			defer cancel()
+			if err := export(finalCtx); err != nil {
+				log.Error("Failed to export metrics on exit", logger.Err(err))
+				return err
+			}
+			log.Debug("Exported metrics on exit")
+			return nil
+		case <-ticker.C:
+			if err := export(ctx); err != nil && ctx.Err() == nil {
+				log.Warn("Failed to export metrics", logger.Err(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestPusher(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if len(body) == 0 {
+			t.Errorf("推送内容为空")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	p := NewPusher(PushOptions{URL: srv.URL, Job: "batch", Grouping: map[string]string{"instance": "test"}, Interval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(paths)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("未按间隔推送")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	before := len(paths)
+	mu.Unlock()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("退出时推送失败: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) <= before {
+		t.Fatalf("退出时应再推送一次")
+	}
+	if paths[len(paths)-1] != "PUT /metrics/job/batch/instance/test" {
+		t.Fatalf("推送请求错误: %s", paths[len(paths)-1])
+	}
+}
+
+func TestExport(t *testing.T) {
+	var pushes, writes int
+	push := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushes++
+	}))
+	defer push.Close()
+	write := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writes++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer write.Close()
+
+	opts := ExportOptions{Push: PushOptions{URL: push.URL, Job: "batch"}, RemoteWrite: RemoteWriteOptions{URL: write.URL}}
+	if err := Export(context.Background(), opts); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	if pushes != 1 || writes != 1 {
+		t.Fatalf("应各导出一次: push %d, remote write %d", pushes, writes)
+	}
+
+	// 未配置 URL 时跳过
+	if err := Export(context.Background(), ExportOptions{}); err != nil {
+		t.Fatalf("未配置时不应返回错误: %v", err)
+	}
+}
+
+// series 测试中解码后的序列，labels 按 name=value 逗号拼接
+type series struct {
+	labels    string
+	value     float64
+	timestamp int64
+}
+
+func TestRemoteWriter(t *testing.T) {
+	s := NewSubsystem("test")
+	counter := s.CounterVec("rw_total", "Remote write test counter.", "kind").WithLabelValues("a")
+	counter.Add(3)
+	s.Histogram("rw_seconds", "Remote write test histogram.", []float64{1}).Observe(0.5)
+	// 指标注册在全局 Registry 上，-count 多次运行时会累加
+	observed := testutil.ToFloat64(counter) / 3
+
+	var got []series
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+			t.Errorf("请求头错误: %v", r.Header)
+		}
+		compressed, _ := io.ReadAll(r.Body)
+		raw, err := snappy.Decode(nil, compressed)
+		if err != nil {
+			t.Errorf("snappy 解压失败: %v", err)
+		}
+		got = decodeWriteRequest(t, raw)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w := NewRemoteWriter(RemoteWriteOptions{URL: srv.URL, ExternalLabels: map[string]string{"cluster": "c1", "kind": "ignored"}})
+	start := time.Now().UnixMilli()
+	if err := w.Write(context.Background()); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	// 外部标签不覆盖指标自身的同名标签
+	want := map[string]float64{
+		"__name__=demo_test_rw_total,cluster=c1,kind=a":                        3 * observed,
+		"__name__=demo_test_rw_seconds_bucket,cluster=c1,kind=ignored,le=1":    observed,
+		"__name__=demo_test_rw_seconds_bucket,cluster=c1,kind=ignored,le=+Inf": observed,
+		"__name__=demo_test_rw_seconds_sum,cluster=c1,kind=ignored":            0.5 * observed,
+		"__name__=demo_test_rw_seconds_count,cluster=c1,kind=ignored":          observed,
+	}
+	found := make(map[string]bool)
+	for _, s := range got {
+		if s.timestamp < start {
+			t.Errorf("样本时间戳错误: %s %d", s.labels, s.timestamp)
+		}
+		if v, ok := want[s.labels]; ok && s.value == v {
+			found[s.labels] = true
+		}
+	}
+	for labels := range want {
+		if !found[labels] {
+			t.Errorf("缺少序列 %s", labels)
+		}
+	}
+
+	// 接收端返回非 2xx 时返回错误
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer bad.Close()
+	err := NewRemoteWriter(RemoteWriteOptions{URL: bad.URL}).Write(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "out of order sample") {
+		t.Fatalf("非 2xx 响应应返回错误: %v", err)
+	}
+}
+
+// decodeWriteRequest 按 remote write 1.0 的 proto 定义解码 WriteRequest，每个序列只取第一个样本
+func decodeWriteRequest(t *testing.T, b []byte) []series {
+	t.Helper()
+	var out []series
+	for _, raw := range decodeFields(t, b)[fieldTimeseries] {
+		var (
+			labels  []string
+			samples []series
+		)
+		fields := decodeFields(t, raw)
+		for _, l := range fields[fieldLabels] {
+			lf := decodeFields(t, l)
+			labels = append(labels, string(first(lf[fieldLabelName]))+"="+string(first(lf[fieldLabelValue])))
+		}
+		for _, smp := range fields[fieldSamples] {
+			sf := decodeFields(t, smp)
+			v, _ := protowire.ConsumeFixed64(first(sf[fieldSampleValue]))
+			ts, _ := protowire.ConsumeVarint(first(sf[fieldSampleTimestamp]))
+			samples = append(samples, series{value: math.Float64frombits(v), timestamp: int64(ts)})
+		}
+		if len(samples) != 1 {
+			t.Fatalf("每个序列应只有一个样本: %v %v", labels, samples)
+		}
+		out = append(out, series{labels: strings.Join(labels, ","), value: samples[0].value, timestamp: samples[0].timestamp})
+	}
+	return out
+}
+
+// decodeFields 按字段编号收集 b 中的字段：length-delimited 字段为内容，其余为原始编码
+func decodeFields(t *testing.T, b []byte) map[protowire.Number][][]byte {
+	t.Helper()
+	fields := make(map[protowire.Number][][]byte)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("解码 WriteRequest 失败: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		m := protowire.ConsumeFieldValue(num, typ, b)
+		if m < 0 {
+			t.Fatalf("解码 WriteRequest 失败: %v", protowire.ParseError(m))
+		}
+		v := b[:m]
+		if typ == protowire.BytesType {
+			v, _ = protowire.ConsumeBytes(v)
+		}
+		fields[num] = append(fields[num], v)
+		b = b[m:]
+	}
+	return fields
+}
+
+func first(values [][]byte) []byte {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path 指标端点路径
+const Path = "/metrics"
+
+// Registry 进程级指标注册表，HTTP /metrics 与 gRPC MetricsService 共用
+var Registry = newRegistry()
+
+func newRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		newBuildInfo(),
+		newStartTime(),
+	)
+	return reg
+}
+
+// newBuildInfo 构建信息，值恒为 1，版本信息在标签中
+func newBuildInfo() prometheus.Collector {
+	labels := prometheus.Labels{"version": "unknown", "revision": "unknown", "go_version": "unknown"}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		labels["version"] = info.Main.Version
+		labels["go_version"] = info.GoVersion
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				labels["revision"] = s.Value
+			}
+		}
+	}
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   Namespace,
+		Name:        "build_info",
+		Help:        "Build information of the running binary.",
+		ConstLabels: labels,
+	})
+	g.Set(1)
+	return g
+}
+
+// newStartTime 进程启动时间，与 time() 相减即为运行时长
+func newStartTime() prometheus.Collector {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "start_time_seconds",
+		Help:      "Start time of the process since unix epoch in seconds.",
+	})
+	g.Set(float64(time.Now().UnixNano()) / 1e9)
+	return g
+}
+
+// Handler 以 Prometheus 格式输出 Registry 中的指标
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.ContinueOnError,
+		EnableOpenMetrics: true,
+		Registry:          Registry,
+	})
+}
+
+// RegisterMetrics 在 gin 引擎或路由组上挂载 GET /metrics
+func RegisterMetrics(r gin.IRoutes) {
+	r.GET(Path, gin.WrapH(Handler()))
+}
+
+// RegisterMux 在 http.ServeMux 上挂载 GET /metrics
+func RegisterMux(mux *http.ServeMux) {
+	mux.Handle("GET "+Path, Handler())
+}
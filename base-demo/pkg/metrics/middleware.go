@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// HeaderTraceParent W3C Trace Context 请求头，Envoy 开启 tracing 时会生成并透传
+	HeaderTraceParent = "traceparent"
+
+	// unmatchedRoute 未匹配到路由的请求统一使用的 route 标签，避免原始路径带来的高基数
+	unmatchedRoute = "unmatched"
+)
+
+// MiddlewareOptions HTTP 指标中间件配置
+type MiddlewareOptions struct {
+	// TraceID 返回请求的 trace ID，非空时作为 exemplar 附加到请求计数与耗时直方图上，
+	// 默认取请求 context 中已采样的 span，未启用链路追踪时从 traceparent 请求头解析
+	TraceID func(c *gin.Context) string
+}
+
+// httpMetrics 按 RED（Rate、Errors、Duration）方法记录的 HTTP 服务端指标
+type httpMetrics struct {
+	requests     *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	inFlight     *prometheus.GaugeVec
+	requestSize  *prometheus.SummaryVec
+	responseSize *prometheus.SummaryVec
+}
+
+var (
+	httpSubsystem = NewSubsystem("http")
+
+	// serverMetrics 同一进程内的多个 gin 引擎共用一组 demo_http_* 指标
+	serverMetrics = sync.OnceValue(func() *httpMetrics {
+		labels := []string{"method", "route", "status_class"}
+		return &httpMetrics{
+			requests:     httpSubsystem.CounterVec("server_requests_total", "Total number of HTTP requests.", labels...),
+			errors:       httpSubsystem.CounterVec("server_errors_total", "Total number of HTTP requests that resulted in a 5xx response.", labels...),
+			duration:     httpSubsystem.HistogramVec("server_request_duration_seconds", "HTTP request latency in seconds.", nil, labels...),
+			inFlight:     httpSubsystem.GaugeVec("server_requests_in_flight", "Number of HTTP requests currently being served.", "method", "route"),
+			requestSize:  httpSubsystem.SummaryVec("server_request_size_bytes", "HTTP request body size in bytes.", labels...),
+			responseSize: httpSubsystem.SummaryVec("server_response_size_bytes", "HTTP response body size in bytes.", labels...),
+		}
+	})
+)
+
+// GinMiddleware 记录 HTTP 请求数、5xx 错误数、耗时直方图、进行中的请求数以及请求 / 响应大小，
+// 标签使用 c.FullPath() 路由模板而不是原始路径；需要注册在 gin.Recovery 之前，panic 才能记为 500
+func GinMiddleware(opts MiddlewareOptions) gin.HandlerFunc {
+	if opts.TraceID == nil {
+		opts.TraceID = traceID
+	}
+	m := serverMetrics()
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		method := c.Request.Method
+		// 路由在中间件执行前已经匹配，FullPath 此时即可用
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		inFlight := m.inFlight.WithLabelValues(method, route)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		labels := prometheus.Labels{"method": method, "route": route, "status_class": statusClass(status)}
+		elapsed := time.Since(start).Seconds()
+
+		var exemplar prometheus.Labels
+		if id := opts.TraceID(c); id != "" {
+			exemplar = prometheus.Labels{"trace_id": id}
+		}
+		addCounter(m.requests.With(labels), exemplar)
+		if status >= 500 {
+			addCounter(m.errors.With(labels), exemplar)
+		}
+		if obs := m.duration.With(labels); exemplar != nil {
+			obs.(prometheus.ExemplarObserver).ObserveWithExemplar(elapsed, exemplar)
+		} else {
+			obs.Observe(elapsed)
+		}
+
+		// ContentLength 为 -1（分块传输）时大小未知，记为 0
+		m.requestSize.With(labels).Observe(float64(max(c.Request.ContentLength, 0)))
+		m.responseSize.With(labels).Observe(float64(max(c.Writer.Size(), 0)))
+	}
+}
+
+func addCounter(c prometheus.Counter, exemplar prometheus.Labels) {
+	if a, ok := c.(prometheus.ExemplarAdder); ok && exemplar != nil {
+		a.AddWithExemplar(1, exemplar)
+		return
+	}
+	c.Inc()
+}
+
+// statusClass 将状态码归类为 1xx-5xx
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// traceID 优先使用链路追踪中间件写入 context 的 span：未采样的 span 不会被导出，不作为 exemplar
+func traceID(c *gin.Context) string {
+	if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+		if !sc.IsSampled() {
+			return ""
+		}
+		return sc.TraceID().String()
+	}
+	return traceIDFromHeader(c)
+}
+
+// traceIDFromHeader 从 traceparent（version-traceid-parentid-flags）中解析 trace ID，格式不合法时返回空
+func traceIDFromHeader(c *gin.Context) string {
+	parts := strings.Split(c.GetHeader(HeaderTraceParent), "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || parts[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+	for _, r := range parts[1] {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return ""
+		}
+	}
+	return parts[1]
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware(MiddlewareOptions{}))
+	r.Use(gin.Recovery())
+	r.GET("/users/:id", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.POST("/panic", func(c *gin.Context) { panic("boom") })
+	RegisterMetrics(r)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	m := serverMetrics()
+	// 指标注册在全局 Registry 上，按增量比较
+	counters := []prometheus.Collector{
+		m.requests.WithLabelValues("GET", "/users/:id", "2xx"),
+		m.requests.WithLabelValues("GET", unmatchedRoute, "4xx"),
+		m.errors.WithLabelValues("POST", "/panic", "5xx"),
+		m.inFlight.WithLabelValues("GET", "/users/:id"),
+	}
+	before := make([]float64, len(counters))
+	for i, c := range counters {
+		before[i] = testutil.ToFloat64(c)
+	}
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/users/1", nil),
+		httptest.NewRequest(http.MethodGet, "/users/2", nil),
+		httptest.NewRequest(http.MethodPost, "/panic", strings.NewReader("body")),
+		httptest.NewRequest(http.MethodGet, "/missing", nil),
+	} {
+		if req.URL.Path == "/panic" {
+			req.Header.Set(HeaderTraceParent, "00-"+traceID+"-00f067aa0ba902b7-01")
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	for i, tc := range []struct {
+		want float64
+		name string
+	}{
+		{2, "路由模板请求数"},
+		{1, "未匹配路由请求数"},
+		{1, "5xx 错误数"},
+		{0, "进行中请求数"},
+	} {
+		if got := testutil.ToFloat64(counters[i]) - before[i]; got != tc.want {
+			t.Errorf("%s: 期望 %v，实际 %v", tc.name, tc.want, got)
+		}
+	}
+
+	// OpenMetrics 格式输出 exemplar
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	body := rec.Body.String()
+	if !strings.Contains(body, `# {trace_id="`+traceID+`"}`) {
+		t.Fatalf("缺少带 trace_id 的 exemplar:\n%s", body)
+	}
+	if !strings.Contains(body, `demo_http_server_request_size_bytes_sum{method="POST",route="/panic",status_class="5xx"} `) {
+		t.Fatalf("请求大小统计错误:\n%s", body)
+	}
+}
+
+func TestTraceIDFromHeader(t *testing.T) {
+	for header, want := range map[string]string{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01": "",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01": "",
+		"garbage": "",
+		"":        "",
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.Header.Set(HeaderTraceParent, header)
+		if got := traceIDFromHeader(c); got != want {
+			t.Errorf("%q: 期望 %q，实际 %q", header, want, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace 所有自定义指标的命名空间，指标全名为 demo_<subsystem>_<name>
+const Namespace = "demo"
+
+// Register 向 Registry 注册收集器；相同描述的收集器已经注册时返回已注册的实例，
+// 便于同一个包被多次初始化（例如测试中创建多个实例）时共用指标，其余注册错误直接 panic
+func Register[T prometheus.Collector](c T) T {
+	if err := Registry.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return c
+}
+
+// Subsystem 按 demo_<subsystem>_<name> 约定创建并注册指标，例如 NewSubsystem("file").Counter("write_bytes_total", ...)
+// 得到 demo_file_write_bytes_total
+type Subsystem struct {
+	name string
+}
+
+// NewSubsystem 创建子系统，name 使用小写加下划线
+func NewSubsystem(name string) Subsystem {
+	return Subsystem{name: name}
+}
+
+// Counter 创建并注册计数器
+func (s Subsystem) Counter(name, help string) prometheus.Counter {
+	return Register(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace, Subsystem: s.name, Name: name, Help: help,
+	}))
+}
+
+// CounterVec 创建并注册带标签的计数器
+func (s Subsystem) CounterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	return Register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace, Subsystem: s.name, Name: name, Help: help,
+	}, labels))
+}
+
+// Gauge 创建并注册仪表盘
+func (s Subsystem) Gauge(name, help string) prometheus.Gauge {
+	return Register(prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace, Subsystem: s.name, Name: name, Help: help,
+	}))
+}
+
+// GaugeVec 创建并注册带标签的仪表盘
+func (s Subsystem) GaugeVec(name, help string, labels ...string) *prometheus.GaugeVec {
+	return Register(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace, Subsystem: s.name, Name: name, Help: help,
+	}, labels))
+}
+
+// Histogram 创建并注册直方图，buckets 为空时使用 prometheus.DefBuckets
+func (s Subsystem) Histogram(name, help string, buckets []float64) prometheus.Histogram {
+	return Register(prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: Namespace, Subsystem: s.name, Name: name, Help: help, Buckets: buckets,
+	}))
+}
+
+// HistogramVec 创建并注册带标签的直方图，buckets 为空时使用 prometheus.DefBuckets
+func (s Subsystem) HistogramVec(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	return Register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace, Subsystem: s.name, Name: name, Help: help, Buckets: buckets,
+	}, labels))
+}
+
+// SummaryVec 创建并注册带标签的摘要，只记录 _sum 与 _count
+func (s Subsystem) SummaryVec(name, help string, labels ...string) *prometheus.SummaryVec {
+	return Register(prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace: Namespace, Subsystem: s.name, Name: name, Help: help,
+	}, labels))
+}
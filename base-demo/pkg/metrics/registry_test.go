@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSubsystem(t *testing.T) {
+	s := NewSubsystem("test")
+	c := s.CounterVec("events_total", "Test events.", "kind")
+	before := testutil.ToFloat64(c.WithLabelValues("a"))
+	c.WithLabelValues("a").Inc()
+
+	// 重复注册返回已注册的收集器，计数不会分裂到两个实例
+	again := s.CounterVec("events_total", "Test events.", "kind")
+	again.WithLabelValues("a").Inc()
+	if got := testutil.ToFloat64(c.WithLabelValues("a")) - before; got != 2 {
+		t.Fatalf("重复注册应复用同一收集器: %v", got)
+	}
+
+	// 同名但标签不同属于注册冲突
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("标签不一致的重复注册应 panic")
+		}
+	}()
+	s.CounterVec("events_total", "Test events.", "other")
+}
+
+func TestRegisterMux(t *testing.T) {
+	NewSubsystem("test").Gauge("mux_value", "Test gauge.").Set(42)
+
+	mux := http.NewServeMux()
+	RegisterMux(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(b)
+	for _, want := range []string{"demo_test_mux_value 42", "demo_build_info{", "demo_start_time_seconds "} {
+		if !strings.Contains(body, want) {
+			t.Errorf("缺少指标 %q", want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// RemoteWriteOptions Prometheus remote write 配置
+type RemoteWriteOptions struct {
+	// URL 接收端地址，例如 http://127.0.0.1:9090/api/v1/write
+	URL string
+	// Interval 发送间隔，默认 15s
+	Interval time.Duration
+	// ExternalLabels 附加到每个序列上的标签，与指标自身标签同名时以指标标签为准
+	ExternalLabels map[string]string
+	// Client 默认使用 10s 超时的 http.Client
+	Client *http.Client
+}
+
+// RemoteWriter 定期将 Registry 以 remote write 1.0 协议（snappy 压缩的 protobuf）发送到接收端
+type RemoteWriter struct {
+	opts RemoteWriteOptions
+}
+
+// NewRemoteWriter 创建 RemoteWriter，需调用 Run 开始发送
+func NewRemoteWriter(opts RemoteWriteOptions) *RemoteWriter {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultExportInterval
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RemoteWriter{opts: opts}
+}
+
+// Run 按间隔发送直到 ctx 结束；发送失败只记录日志，返回值为退出时最后一次发送的结果
+func (w *RemoteWriter) Run(ctx context.Context) error {
+	return runPeriodically(ctx, "remote_write", w.opts.Interval, w.Write)
+}
+
+// Write 采集 Registry 并发送一次
+func (w *RemoteWriter) Write(ctx context.Context) error {
+	families, err := Registry.Gather()
+	// 与 /metrics 的 ContinueOnError 一致，部分采集失败时仍发送已采集到的指标
+	if err != nil && len(families) == 0 {
+		return fmt.Errorf("gather metrics: %w", err)
+	}
+	raw := marshalWriteRequest(toTimeSeries(families, time.Now().UnixMilli(), w.opts.ExternalLabels))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(snappy.Encode(nil, raw)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := w.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// label、sample、timeSeries 对应 remote write 1.0 prompb 中的 Label、Sample、TimeSeries，
+// 只需要这几个字段，由 marshalWriteRequest 用 protowire 手工编码，不引入 Prometheus 服务端模块
+type label struct {
+	Name, Value string
+}
+
+type sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+type timeSeries struct {
+	Labels  []label
+	Samples []sample
+}
+
+// remote write 1.0 proto 的字段编号：
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+const (
+	fieldTimeseries      protowire.Number = 1
+	fieldLabels          protowire.Number = 1
+	fieldSamples         protowire.Number = 2
+	fieldLabelName       protowire.Number = 1
+	fieldLabelValue      protowire.Number = 2
+	fieldSampleValue     protowire.Number = 1
+	fieldSampleTimestamp protowire.Number = 2
+)
+
+// marshalWriteRequest 按 protobuf 线格式编码 WriteRequest
+func marshalWriteRequest(series []timeSeries) []byte {
+	var out, ts, msg []byte
+	for _, s := range series {
+		ts = ts[:0]
+		for _, l := range s.Labels {
+			msg = protowire.AppendTag(msg[:0], fieldLabelName, protowire.BytesType)
+			msg = protowire.AppendString(msg, l.Name)
+			msg = protowire.AppendTag(msg, fieldLabelValue, protowire.BytesType)
+			msg = protowire.AppendString(msg, l.Value)
+			ts = protowire.AppendTag(ts, fieldLabels, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, msg)
+		}
+		for _, smp := range s.Samples {
+			msg = protowire.AppendTag(msg[:0], fieldSampleValue, protowire.Fixed64Type)
+			msg = protowire.AppendFixed64(msg, math.Float64bits(smp.Value))
+			msg = protowire.AppendTag(msg, fieldSampleTimestamp, protowire.VarintType)
+			msg = protowire.AppendVarint(msg, uint64(smp.Timestamp))
+			ts = protowire.AppendTag(ts, fieldSamples, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, msg)
+		}
+		out = protowire.AppendTag(out, fieldTimeseries, protowire.BytesType)
+		out = protowire.AppendBytes(out, ts)
+	}
+	return out
+}
+
+// toTimeSeries 将指标族展开为序列：summary 展开为 quantile、_sum、_count，
+// histogram 展开为累计的 _bucket、_sum、_count，与抓取时 Prometheus 的展开方式一致；每个序列只携带一个样本
+func toTimeSeries(families []*dto.MetricFamily, now int64, external map[string]string) []timeSeries {
+	var out []timeSeries
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			ts := now
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+			add := func(name string, value float64, extra ...label) {
+				out = append(out, timeSeries{
+					Labels:  seriesLabels(name, m.GetLabel(), external, extra),
+					Samples: []sample{{Value: value, Timestamp: ts}},
+				})
+			}
+
+			name := f.GetName()
+			switch f.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, q.GetValue(), label{Name: "quantile", Value: formatFloat(q.GetQuantile())})
+				}
+				add(name+"_sum", s.GetSampleSum())
+				add(name+"_count", float64(s.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := m.GetHistogram()
+				inf := false
+				for _, b := range h.GetBucket() {
+					inf = inf || math.IsInf(b.GetUpperBound(), 1)
+					add(name+"_bucket", float64(b.GetCumulativeCount()), label{Name: "le", Value: formatFloat(b.GetUpperBound())})
+				}
+				if !inf {
+					add(name+"_bucket", float64(h.GetSampleCount()), label{Name: "le", Value: "+Inf"})
+				}
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			}
+		}
+	}
+	return out
+}
+
+// seriesLabels 合并 __name__、指标标签、额外标签与外部标签，按名称排序
+func seriesLabels(name string, pairs []*dto.LabelPair, external map[string]string, extra []label) []label {
+	labels := make([]label, 0, 1+len(pairs)+len(extra)+len(external))
+	labels = append(labels, label{Name: "__name__", Value: name})
+	for _, p := range pairs {
+		labels = append(labels, label{Name: p.GetName(), Value: p.GetValue()})
+	}
+	labels = append(labels, extra...)
+	for k, v := range external {
+		if !slices.ContainsFunc(labels, func(l label) bool { return l.Name == k }) {
+			labels = append(labels, label{Name: k, Value: v})
+		}
+	}
+	slices.SortFunc(labels, func(a, b label) int { return strings.Compare(a.Name, b.Name) })
+	return labels
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
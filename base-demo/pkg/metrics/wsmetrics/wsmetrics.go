@@ -0,0 +1,51 @@
+// Package wsmetrics 声明 WebSocket 服务共用的 demo_websocket_* 指标，websocket-demo 与 consul-demo 的广播 hub
+// 使用同一套指标名与标签，跨服务的面板与告警不需要区分来源
+package wsmetrics
+
+import (
+	"strconv"
+
+	"github.com/lyonmu/demo/base-demo/pkg/metrics"
+)
+
+// 客户端消息类型，对应 messages_received_total 的 type 标签
+const (
+	MessageAuth  = "auth"
+	MessageOther = "other"
+)
+
+var (
+	subsystem = metrics.NewSubsystem("websocket")
+
+	activeConnections = subsystem.Gauge("connections", "Number of open WebSocket connections.")
+	connectionsTotal  = subsystem.CounterVec("connections_total", "Total number of accepted WebSocket connections.", "authenticated")
+	messagesSent      = subsystem.Counter("messages_sent_total", "Total number of messages written to clients.")
+	messagesReceived  = subsystem.CounterVec("messages_received_total", "Total number of messages read from clients by type.", "type")
+	sendErrors        = subsystem.Counter("send_errors_total", "Total number of failed writes to clients.")
+)
+
+// Opened 记录一个新连接，authenticated 表示身份是否已由鉴权服务确认
+func Opened(authenticated bool) {
+	activeConnections.Inc()
+	connectionsTotal.WithLabelValues(strconv.FormatBool(authenticated)).Inc()
+}
+
+// Closed 记录一个连接关闭，每个 Opened 对应一次
+func Closed() {
+	activeConnections.Dec()
+}
+
+// Received 记录一条客户端消息，msgType 为 MessageAuth 或 MessageOther
+func Received(msgType string) {
+	messagesReceived.WithLabelValues(msgType).Inc()
+}
+
+// Sent 记录一条成功写入客户端的消息
+func Sent() {
+	messagesSent.Inc()
+}
+
+// SendFailed 记录一次写入客户端失败
+func SendFailed() {
+	sendErrors.Inc()
+}
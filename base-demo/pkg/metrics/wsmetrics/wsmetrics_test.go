@@ -0,0 +1,35 @@
+package wsmetrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyonmu/demo/base-demo/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLabels(t *testing.T) {
+	Opened(true)
+	Opened(false)
+	Closed()
+	Received(MessageAuth)
+	Sent()
+	SendFailed()
+
+	expected := `
+# HELP demo_websocket_connections Number of open WebSocket connections.
+# TYPE demo_websocket_connections gauge
+demo_websocket_connections 1
+# HELP demo_websocket_connections_total Total number of accepted WebSocket connections.
+# TYPE demo_websocket_connections_total counter
+demo_websocket_connections_total{authenticated="false"} 1
+demo_websocket_connections_total{authenticated="true"} 1
+# HELP demo_websocket_messages_received_total Total number of messages read from clients by type.
+# TYPE demo_websocket_messages_received_total counter
+demo_websocket_messages_received_total{type="auth"} 1
+`
+	if err := testutil.GatherAndCompare(metrics.Registry, strings.NewReader(expected),
+		"demo_websocket_connections", "demo_websocket_connections_total", "demo_websocket_messages_received_total"); err != nil {
+		t.Fatalf("websocket 指标错误: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName 数据库 span 的 instrumentation scope
+const instrumentationName = "github.com/lyonmu/demo/base-demo/pkg/tracing"
+
+// StartQuery 为一次数据库操作创建客户端 span，名称为 "<operation> <collection>"，属性遵循数据库语义约定；
+// system 如 duckdb，collection 为空时省略。SQL 只应包含占位符，参数不会记录到 span 上
+func StartQuery(ctx context.Context, system, operation, collection, query string) (context.Context, trace.Span) {
+	name := operation
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemNameKey.String(system),
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(query),
+	}
+	if collection != "" {
+		name += " " + collection
+		attrs = append(attrs, semconv.DBCollectionName(collection))
+	}
+	// 每次从全局 TracerProvider 获取，Init 之后（以及测试中替换 provider 后）创建的 span 都能被导出
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+}
+
+// EndQuery 记录返回 / 影响的行数与错误并结束 span
+func EndQuery(span trace.Span, rows int, err error) {
+	span.SetAttributes(semconv.DBResponseReturnedRows(rows))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,168 @@
+// Package tracing 初始化 OpenTelemetry 链路追踪：全局 TracerProvider、W3C traceparent 传播器
+// 以及 OTLP / 本地文件导出器，并提供 gin、HTTP 客户端与数据库查询的埋点封装
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyonmu/demo/base-demo/pkg/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+const (
+	// ExporterNone 不导出，仍然透传上游的 traceparent
+	ExporterNone = "none"
+	// ExporterOTLP 通过 OTLP 导出，地址等由标准的 OTEL_EXPORTER_OTLP_* 环境变量配置
+	ExporterOTLP = "otlp"
+	// ExporterFile 以 JSON 行写入本地文件，便于离线查看
+	ExporterFile = "file"
+
+	// ProtocolHTTP OTLP over HTTP（protobuf），默认端口 4318
+	ProtocolHTTP = "http/protobuf"
+	// ProtocolGRPC OTLP over gRPC，默认端口 4317
+	ProtocolGRPC = "grpc"
+)
+
+// Options 链路追踪配置
+type Options struct {
+	// Exporter none（默认）、otlp 或 file
+	Exporter string
+	// Protocol OTLP 协议：http/protobuf（默认）或 grpc
+	Protocol string
+	// File file 导出器的输出路径，默认 traces.jsonl（追加写入）
+	File string
+	// SampleRatio 根 span 的采样比例 [0, 1]，默认 1；带父 span 的请求沿用上游的采样决定
+	SampleRatio float64
+	// Attributes 附加到 resource 上的属性，例如 deployment.environment
+	Attributes map[string]string
+}
+
+// OptionsFromEnv 从环境变量读取链路追踪配置：TRACING_EXPORTER、TRACING_FILE、TRACING_SAMPLE_RATIO，
+// OTLP 协议沿用 OTEL_EXPORTER_OTLP_PROTOCOL，地址与请求头由导出器读取 OTEL_EXPORTER_OTLP_ENDPOINT 等变量
+func OptionsFromEnv() (Options, error) {
+	opts := Options{
+		Exporter:    os.Getenv("TRACING_EXPORTER"),
+		Protocol:    os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"),
+		File:        os.Getenv("TRACING_FILE"),
+		SampleRatio: 1,
+	}
+	if v := os.Getenv("TRACING_SAMPLE_RATIO"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return Options{}, fmt.Errorf("TRACING_SAMPLE_RATIO: invalid ratio %q", v)
+		}
+		opts.SampleRatio = ratio
+	}
+	return opts, nil
+}
+
+// Init 设置全局传播器与 TracerProvider，返回的 shutdown 会刷新尚未导出的 span 并关闭导出器。
+//
+// 无论是否导出，传播器都会被设置，服务收到的 traceparent 会透传到下游（Envoy、Consul 等）
+func Init(ctx context.Context, service string, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if opts.Exporter == "" {
+		opts.Exporter = ExporterNone
+	}
+	if opts.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := []attribute.KeyValue{semconv.ServiceName(service)}
+	for k, v := range opts.Attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	// resource.Default 包含 OTEL_RESOURCE_ATTRIBUTES 中的属性，这里的 service.name 优先
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, attrs...))
+	if err != nil {
+		return nil, errors.Join(err, exporter.Shutdown(ctx))
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, error) {
+	switch opts.Exporter {
+	case ExporterOTLP:
+		switch strings.ToLower(opts.Protocol) {
+		case "", ProtocolHTTP:
+			return otlptracehttp.New(ctx)
+		case ProtocolGRPC:
+			return otlptracegrpc.New(ctx)
+		default:
+			return nil, fmt.Errorf("unknown OTLP protocol %q", opts.Protocol)
+		}
+	case ExporterFile:
+		path := opts.File
+		if path == "" {
+			path = "traces.jsonl"
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			return nil, errors.Join(err, f.Close())
+		}
+		return &fileExporter{SpanExporter: exporter, f: f}, nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+}
+
+// fileExporter 关闭导出器时一并关闭文件
+type fileExporter struct {
+	sdktrace.SpanExporter
+	f io.Closer
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.SpanExporter.Shutdown(ctx), e.f.Close())
+}
+
+// GinMiddleware 为每个请求创建服务端 span，span 名称为路由模板，并从 traceparent 请求头继续上游的链路；
+// /metrics 抓取请求不记录
+func GinMiddleware(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return c.Request.URL.Path != metrics.Path
+	}))
+}
+
+// Transport 为 HTTP 客户端包装 otelhttp：每个请求创建客户端 span 并注入 traceparent，base 为空时使用 http.DefaultTransport
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
@@ -0,0 +1,181 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyonmu/demo/base-demo/pkg/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	// unsampled 上游未采样的 traceparent（flags=00）
+	unsampled = "00-" + traceID + "-00f067aa0ba902b7-00"
+	sampled   = "00-" + traceID + "-00f067aa0ba902b7-01"
+)
+
+// exportedSpan stdouttrace 输出中测试关心的字段
+type exportedSpan struct {
+	Name        string
+	SpanContext struct{ TraceID string }
+	Parent      struct{ SpanID string }
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	// 根 span 全部丢弃，带已采样父 span 的请求仍按上游决定采样
+	shutdown, err := Init(context.Background(), "test", Options{Exporter: ExporterFile, File: path, SampleRatio: 0})
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	// 下游服务（例如 Envoy 之后的另一个服务）收到的 traceparent
+	var downstream string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Get("traceparent")
+	}))
+	defer backend.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware("test"))
+	r.GET("/users/:id", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, backend.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("调用下游失败: %v", err)
+			return
+		}
+		resp.Body.Close()
+		c.Status(http.StatusNoContent)
+	})
+	metrics.RegisterMetrics(r)
+
+	for path, parent := range map[string]string{
+		"/users/1":   sampled,
+		"/users/2":   unsampled,
+		"/users/3":   "",
+		metrics.Path: sampled,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if parent != "" {
+			req.Header.Set("traceparent", parent)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if path == "/users/1" && !strings.HasPrefix(downstream, "00-"+traceID+"-") {
+			t.Fatalf("traceparent 未传播到下游: %q", downstream)
+		}
+	}
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("关闭失败: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spans []exportedSpan
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	for dec.More() {
+		var s exportedSpan
+		if err := dec.Decode(&s); err != nil {
+			t.Fatalf("解析导出内容失败: %v", err)
+		}
+		spans = append(spans, s)
+	}
+
+	// 只有 /users/1 被采样：服务端 span 与调用下游的客户端 span
+	if len(spans) != 2 {
+		t.Fatalf("期望导出 2 个 span，实际 %d:\n%s", len(spans), data)
+	}
+	var server bool
+	for _, s := range spans {
+		if s.SpanContext.TraceID != traceID {
+			t.Errorf("span %s 未延续上游 trace: %s", s.Name, s.SpanContext.TraceID)
+		}
+		if s.Name == "GET /users/:id" {
+			server = s.Parent.SpanID == "00f067aa0ba902b7"
+		}
+	}
+	if !server {
+		t.Fatalf("服务端 span 名称或父 span 错误:\n%s", data)
+	}
+}
+
+func TestPropagateWithoutExporter(t *testing.T) {
+	// 未导出时仍延续上游 trace，后续中间件（指标 exemplar、访问日志）可以从 context 取到 span
+	if _, err := Init(context.Background(), "test", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware("test"))
+	var got trace.SpanContext
+	r.GET("/", func(c *gin.Context) { got = trace.SpanContextFromContext(c.Request.Context()) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", sampled)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if got.TraceID().String() != traceID || !got.IsSampled() {
+		t.Fatalf("context 中的 span 错误: %v", got)
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv("TRACING_EXPORTER", ExporterOTLP)
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	opts, err := OptionsFromEnv()
+	if err != nil || opts.Exporter != ExporterOTLP || opts.SampleRatio != 0.25 {
+		t.Fatalf("读取配置错误: %+v %v", opts, err)
+	}
+
+	t.Setenv("TRACING_SAMPLE_RATIO", "2")
+	if _, err := OptionsFromEnv(); err == nil {
+		t.Fatalf("采样比例超出范围应返回错误")
+	}
+
+	if _, err := Init(context.Background(), "test", Options{Exporter: "zipkin"}); err == nil {
+		t.Fatalf("未知导出器应返回错误")
+	}
+}
+
+func TestQuerySpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	_, span := StartQuery(context.Background(), "duckdb", "SELECT", "people", "SELECT id FROM people WHERE id = ?")
+	EndQuery(span, 3, errors.New("boom"))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "SELECT people" || spans[0].SpanKind() != trace.SpanKindClient {
+		t.Fatalf("span 错误: %v", spans)
+	}
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range spans[0].Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["db.system.name"].AsString() != "duckdb" || attrs["db.collection.name"].AsString() != "people" ||
+		attrs["db.response.returned_rows"].AsInt64() != 3 {
+		t.Fatalf("span 属性错误: %v", spans[0].Attributes())
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Fatalf("出错时 span 状态应为 Error: %v", spans[0].Status())
+	}
+}
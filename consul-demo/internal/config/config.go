@@ -0,0 +1,199 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/lyonmu/demo/base-demo/pkg/consul"
+)
+
+const (
+	// EnvConfigFile 配置文件路径环境变量
+	EnvConfigFile = "DEMO_CONFIG"
+	// DefaultConfigFile 默认配置文件路径
+	DefaultConfigFile = "config.yaml"
+)
+
+// Config consul-demo 配置
+type Config struct {
+	HTTP      HTTP      `yaml:"http"`
+	Consul    Consul    `yaml:"consul"`
+	Service   Service   `yaml:"service"`
+	Health    Health    `yaml:"health"`
+	KV        KV        `yaml:"kv"`
+	Discovery Discovery `yaml:"discovery"`
+	Election  Election  `yaml:"election"`
+}
+
+// HTTP 监听配置
+type HTTP struct {
+	Port int `yaml:"port"`
+}
+
+// Consul Agent 连接配置，与其他 demo 共用
+type Consul = consul.Config
+
+// Service 服务注册配置
+type Service struct {
+	Name string `yaml:"name"`
+	// ID 为空时使用 <address>:<port>
+	ID string `yaml:"id"`
+	// Address 对外通告地址，为空时自动探测访问 Consul Agent 所用网卡的地址
+	Address string `yaml:"address"`
+	// Port 对外通告端口，为空时与 http.port 一致
+	Port   int               `yaml:"port"`
+	Tags   []string          `yaml:"tags"`
+	Meta   map[string]string `yaml:"meta"`
+	Checks []Check           `yaml:"checks"`
+}
+
+// Check 健康检查配置，HTTP 以 / 开头时视为相对通告地址的路径
+type Check struct {
+	ID                             string `yaml:"id"`
+	Name                           string `yaml:"name"`
+	HTTP                           string `yaml:"http"`
+	Method                         string `yaml:"method"`
+	TCP                            string `yaml:"tcp"`
+	GRPC                           string `yaml:"grpc"`
+	TTL                            string `yaml:"ttl"`
+	Interval                       string `yaml:"interval"`
+	Timeout                        string `yaml:"timeout"`
+	Notes                          string `yaml:"notes"`
+	DeregisterCriticalServiceAfter string `yaml:"deregister_critical_service_after"`
+}
+
+// Health 健康探针配置
+type Health struct {
+	// DiskPath 磁盘空间探针检查的路径
+	DiskPath string `yaml:"disk_path"`
+	// DiskWarnFreeMB 剩余空间低于该值时降级
+	DiskWarnFreeMB uint64 `yaml:"disk_warn_free_mb"`
+	// DiskFailFreeMB 剩余空间低于该值时失败
+	DiskFailFreeMB uint64 `yaml:"disk_fail_free_mb"`
+}
+
+// KV 基于 Consul KV 的动态配置
+type KV struct {
+	// Prefix KV 前缀
+	Prefix string `yaml:"prefix"`
+	// CacheFile last-known-good 缓存文件，Consul 不可用时用于启动
+	CacheFile string `yaml:"cache_file"`
+}
+
+// Discovery 客户端服务发现配置
+type Discovery struct {
+	// Service 要调用的服务名
+	Service string `yaml:"service"`
+	// Tags 实例必须包含的标签
+	Tags []string `yaml:"tags"`
+	// Strategy 负载均衡策略：round_robin、least_requests、random
+	Strategy string `yaml:"strategy"`
+}
+
+// Election 选主配置，多实例部署时只有 leader 运行定时推送等单例任务
+type Election struct {
+	// Key 锁对应的 KV key
+	Key string `yaml:"key"`
+	// SessionTTL Session TTL，leader 异常退出后最长经过 TTL（加 lock-delay）完成切换
+	SessionTTL string `yaml:"session_ttl"`
+}
+
+// Default 返回默认配置
+func Default() Config {
+	return Config{
+		HTTP: HTTP{Port: 8080},
+		Service: Service{
+			Name: "demo",
+			Tags: []string{"demo"},
+			Checks: []Check{{
+				Name:                           "http",
+				HTTP:                           "/demo/health",
+				Interval:                       "10s",
+				Timeout:                        "5s",
+				DeregisterCriticalServiceAfter: "1m",
+			}},
+		},
+		Health: Health{
+			DiskPath:       ".",
+			DiskWarnFreeMB: 1024,
+			DiskFailFreeMB: 256,
+		},
+		KV: KV{
+			Prefix:    "demo/config/",
+			CacheFile: ".kvconfig-cache.json",
+		},
+		Discovery: Discovery{
+			Service:  "demo",
+			Strategy: "round_robin",
+		},
+		Election: Election{
+			Key:        "service/demo/leader",
+			SessionTTL: "15s",
+		},
+	}
+}
+
+// Load 读取配置文件并应用环境变量覆盖，path 为空时依次使用 DEMO_CONFIG 与 config.yaml，文件不存在时使用默认配置
+func Load(path string) (Config, error) {
+	if path == "" {
+		path = os.Getenv(EnvConfigFile)
+	}
+	if path == "" {
+		path = DefaultConfigFile
+	}
+
+	cfg := Default()
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return cfg, err
+	default:
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	if cfg.Service.Port == 0 {
+		cfg.Service.Port = cfg.HTTP.Port
+	}
+	return cfg, nil
+}
+
+// applyEnv 使用 DEMO_* 环境变量覆盖服务配置
+func (c *Config) applyEnv() error {
+	if v := os.Getenv("DEMO_HTTP_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("DEMO_HTTP_PORT: %w", err)
+		}
+		c.HTTP.Port = port
+	}
+	if v := os.Getenv("DEMO_SERVICE_NAME"); v != "" {
+		c.Service.Name = v
+	}
+	if v := os.Getenv("DEMO_SERVICE_ID"); v != "" {
+		c.Service.ID = v
+	}
+	if v := os.Getenv("DEMO_SERVICE_ADDRESS"); v != "" {
+		c.Service.Address = v
+	}
+	if v := os.Getenv("DEMO_SERVICE_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("DEMO_SERVICE_PORT: %w", err)
+		}
+		c.Service.Port = port
+	}
+	if v := os.Getenv("DEMO_SERVICE_TAGS"); v != "" {
+		c.Service.Tags = strings.Split(v, ",")
+	}
+	return nil
+}
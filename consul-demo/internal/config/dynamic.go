@@ -0,0 +1,31 @@
+package config
+
+import "time"
+
+// Dynamic 运行时可变配置，存放在 Consul KV 的 kv.prefix 下，例如：
+//
+//	demo/config/ws/tick_interval = 2s
+//	demo/config/log/level        = debug
+type Dynamic struct {
+	WS  DynamicWS  `kv:"ws"`
+	Log DynamicLog `kv:"log"`
+}
+
+// DynamicWS WebSocket 推送配置
+type DynamicWS struct {
+	// TickInterval 定时推送间隔
+	TickInterval time.Duration `kv:"tick_interval"`
+}
+
+// DynamicLog 日志配置
+type DynamicLog struct {
+	// Level 日志级别，为空时保持启动时的级别
+	Level string `kv:"level"`
+}
+
+// DefaultDynamic 返回运行时配置的默认值
+func DefaultDynamic() Dynamic {
+	return Dynamic{
+		WS: DynamicWS{TickInterval: time.Second},
+	}
+}
@@ -0,0 +1,235 @@
+package consultest
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+func (s *Server) routeAgent(mux *http.ServeMux) {
+	mux.HandleFunc("PUT /v1/agent/service/register", s.registerService)
+	mux.HandleFunc("PUT /v1/agent/service/deregister/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.deregister(r.PathValue("id"))
+		s.bump()
+		s.mu.Unlock()
+		s.writeJSON(w, nil)
+	})
+	mux.HandleFunc("GET /v1/agent/service/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		svc, ok := s.services[r.PathValue("id")]
+		var out capi.AgentService
+		if ok {
+			out = *svc
+		}
+		s.mu.Unlock()
+		if !ok {
+			s.notFound(w, "unknown service ID: "+r.PathValue("id"))
+			return
+		}
+		s.writeJSON(w, out)
+	})
+	mux.HandleFunc("GET /v1/agent/services", func(w http.ResponseWriter, r *http.Request) {
+		s.writeJSON(w, s.Services())
+	})
+	mux.HandleFunc("GET /v1/agent/checks", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		out := make(map[string]capi.AgentCheck, len(s.checks))
+		for id, c := range s.checks {
+			out[id] = capi.AgentCheck{
+				Node:        c.Node,
+				CheckID:     c.CheckID,
+				Name:        c.Name,
+				Status:      c.Status,
+				Notes:       c.Notes,
+				Output:      c.Output,
+				ServiceID:   c.ServiceID,
+				ServiceName: c.ServiceName,
+				Type:        c.Type,
+			}
+		}
+		s.mu.Unlock()
+		s.writeJSON(w, out)
+	})
+	mux.HandleFunc("PUT /v1/agent/check/update/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Status string
+			Output string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.updateTTL(w, r.PathValue("id"), body.Status, body.Output)
+	})
+	for path, status := range map[string]string{
+		"pass": capi.HealthPassing,
+		"warn": capi.HealthWarning,
+		"fail": capi.HealthCritical,
+	} {
+		mux.HandleFunc("PUT /v1/agent/check/"+path+"/{id}", func(w http.ResponseWriter, r *http.Request) {
+			s.updateTTL(w, r.PathValue("id"), status, r.URL.Query().Get("note"))
+		})
+	}
+}
+
+// registerService 注册服务及其检查，同 ID 重复注册时覆盖
+func (s *Server) registerService(w http.ResponseWriter, r *http.Request) {
+	var reg capi.AgentServiceRegistration
+	if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if reg.Name == "" {
+		http.Error(w, "missing service name", http.StatusBadRequest)
+		return
+	}
+	if reg.ID == "" {
+		reg.ID = reg.Name
+	}
+
+	checks := slices.Clone(reg.Checks)
+	if reg.Check != nil {
+		checks = append(capi.AgentServiceChecks{reg.Check}, checks...)
+	}
+	replace, _ := strconv.ParseBool(r.URL.Query().Get("replace-existing-checks"))
+
+	s.mu.Lock()
+	if replace {
+		s.deregister(reg.ID)
+	}
+	s.services[reg.ID] = &capi.AgentService{
+		Kind:    reg.Kind,
+		ID:      reg.ID,
+		Service: reg.Name,
+		Tags:    slices.Clone(reg.Tags),
+		Meta:    maps.Clone(reg.Meta),
+		Port:    reg.Port,
+		Address: reg.Address,
+	}
+	for i, c := range checks {
+		id := c.CheckID
+		if id == "" {
+			id = "service:" + reg.ID
+			if len(checks) > 1 {
+				id += ":" + strconv.Itoa(i+1)
+			}
+		}
+		name := c.Name
+		if name == "" {
+			name = "Service '" + reg.Name + "' check"
+		}
+		status := c.Status
+		if status == "" {
+			status = capi.HealthCritical
+		}
+		checkType := "ttl"
+		switch {
+		case c.HTTP != "":
+			checkType = "http"
+		case c.TCP != "":
+			checkType = "tcp"
+		case c.GRPC != "":
+			checkType = "grpc"
+		}
+		// 保留已有检查的状态，与真实 Agent 重新注册时的行为一致
+		if old, ok := s.checks[id]; ok && c.Status == "" {
+			status = old.Status
+		}
+		s.checks[id] = &capi.HealthCheck{
+			Node:        NodeName,
+			CheckID:     id,
+			Name:        name,
+			Status:      status,
+			Notes:       c.Notes,
+			ServiceID:   reg.ID,
+			ServiceName: reg.Name,
+			ServiceTags: slices.Clone(reg.Tags),
+			Type:        checkType,
+		}
+	}
+	s.bump()
+	s.mu.Unlock()
+
+	s.writeJSON(w, nil)
+}
+
+// deregister 删除服务及其检查，调用方需持有锁
+func (s *Server) deregister(id string) {
+	delete(s.services, id)
+	for checkID, c := range s.checks {
+		if c.ServiceID == id {
+			delete(s.checks, checkID)
+		}
+	}
+}
+
+func (s *Server) updateTTL(w http.ResponseWriter, id, status, output string) {
+	switch status {
+	case "pass":
+		status = capi.HealthPassing
+	case "warn":
+		status = capi.HealthWarning
+	case "fail":
+		status = capi.HealthCritical
+	}
+	if !s.SetCheckStatus(id, status, output) {
+		s.notFound(w, "unknown check ID: "+id)
+		return
+	}
+	s.writeJSON(w, nil)
+}
+
+// SetCheckStatus 设置检查状态，检查不存在时返回 false
+func (s *Server) SetCheckStatus(id, status, output string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.checks[id]
+	if !ok {
+		return false
+	}
+	if c.Status != status || c.Output != output {
+		c.Status, c.Output = status, output
+		s.bump()
+	}
+	return true
+}
+
+// Check 返回检查的快照
+func (s *Server) Check(id string) (capi.HealthCheck, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.checks[id]
+	if !ok {
+		return capi.HealthCheck{}, false
+	}
+	return *c, true
+}
+
+// Services 返回已注册服务的快照
+func (s *Server) Services() map[string]capi.AgentService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]capi.AgentService, len(s.services))
+	for id, svc := range s.services {
+		out[id] = *svc
+	}
+	return out
+}
+
+// RestartAgent 模拟 Agent 重启：清空本地注册的服务与检查
+func (s *Server) RestartAgent() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.services)
+	clear(s.checks)
+	s.bump()
+}
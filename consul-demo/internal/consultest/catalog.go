@@ -0,0 +1,112 @@
+package consultest
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+func (s *Server) routeCatalog(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/catalog/services", func(w http.ResponseWriter, r *http.Request) {
+		s.block(r)
+
+		s.mu.Lock()
+		out := make(map[string][]string)
+		for _, svc := range s.services {
+			tags := out[svc.Service]
+			for _, tag := range svc.Tags {
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+			if tags == nil {
+				tags = []string{}
+			}
+			out[svc.Service] = tags
+		}
+		s.mu.Unlock()
+		s.writeJSON(w, out)
+	})
+	mux.HandleFunc("GET /v1/catalog/service/{name}", func(w http.ResponseWriter, r *http.Request) {
+		s.block(r)
+
+		s.mu.Lock()
+		out := []capi.CatalogService{}
+		for _, svc := range s.matchServices(r.PathValue("name"), r.URL.Query()["tag"]) {
+			out = append(out, capi.CatalogService{
+				ID:             NodeName,
+				Node:           NodeName,
+				Address:        "127.0.0.1",
+				ServiceID:      svc.ID,
+				ServiceName:    svc.Service,
+				ServiceAddress: svc.Address,
+				ServiceTags:    slices.Clone(svc.Tags),
+				ServiceMeta:    svc.Meta,
+				ServicePort:    svc.Port,
+			})
+		}
+		s.mu.Unlock()
+		s.writeJSON(w, out)
+	})
+	mux.HandleFunc("GET /v1/health/service/{name}", func(w http.ResponseWriter, r *http.Request) {
+		s.block(r)
+
+		_, passing := r.URL.Query()["passing"]
+		s.mu.Lock()
+		out := []capi.ServiceEntry{}
+		for _, svc := range s.matchServices(r.PathValue("name"), r.URL.Query()["tag"]) {
+			var checks capi.HealthChecks
+			healthy := true
+			for _, c := range s.checks {
+				if c.ServiceID != svc.ID {
+					continue
+				}
+				cc := *c
+				checks = append(checks, &cc)
+				if c.Status != capi.HealthPassing {
+					healthy = false
+				}
+			}
+			if passing && !healthy {
+				continue
+			}
+			sort.Slice(checks, func(i, j int) bool { return checks[i].CheckID < checks[j].CheckID })
+
+			svcCopy := *svc
+			out = append(out, capi.ServiceEntry{
+				Node:    &capi.Node{ID: NodeName, Node: NodeName, Address: "127.0.0.1", Datacenter: "dc1"},
+				Service: &svcCopy,
+				Checks:  checks,
+			})
+		}
+		s.mu.Unlock()
+		s.writeJSON(w, out)
+	})
+}
+
+// matchServices 按服务名与标签筛选，结果按 ID 排序，调用方需持有锁
+func (s *Server) matchServices(name string, tags []string) []*capi.AgentService {
+	var out []*capi.AgentService
+	for _, svc := range s.services {
+		if svc.Service != name {
+			continue
+		}
+		if !containsAll(svc.Tags, tags) {
+			continue
+		}
+		out = append(out, svc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func containsAll(have, want []string) bool {
+	for _, tag := range want {
+		if !slices.Contains(have, tag) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package consultest
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+func (s *Server) routeKV(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/kv/{key...}", s.getKV)
+	mux.HandleFunc("PUT /v1/kv/{key...}", s.putKV)
+	mux.HandleFunc("DELETE /v1/kv/{key...}", s.deleteKV)
+}
+
+func (s *Server) getKV(w http.ResponseWriter, r *http.Request) {
+	s.block(r)
+
+	key := r.PathValue("key")
+	q := r.URL.Query()
+	_, recurse := q["recurse"]
+	_, keysOnly := q["keys"]
+
+	s.mu.Lock()
+	var pairs capi.KVPairs
+	for k, p := range s.kv {
+		if k == key || ((recurse || keysOnly) && strings.HasPrefix(k, key)) {
+			pc := *p
+			pairs = append(pairs, &pc)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+
+	if len(pairs) == 0 {
+		s.notFound(w, "")
+		return
+	}
+	if keysOnly {
+		keys := make([]string, 0, len(pairs))
+		sep := q.Get("separator")
+		for _, p := range pairs {
+			k := p.Key
+			if sep != "" {
+				if i := strings.Index(k[len(key):], sep); i >= 0 {
+					k = k[:len(key)+i+len(sep)]
+				}
+			}
+			if len(keys) == 0 || keys[len(keys)-1] != k {
+				keys = append(keys, k)
+			}
+		}
+		s.writeJSON(w, keys)
+		return
+	}
+	if !recurse {
+		pairs = pairs[:1]
+	}
+	s.writeJSON(w, pairs)
+}
+
+func (s *Server) putKV(w http.ResponseWriter, r *http.Request) {
+	value, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	key := r.PathValue("key")
+	q := r.URL.Query()
+	flags, _ := strconv.ParseUint(q.Get("flags"), 10, 64)
+
+	s.mu.Lock()
+	ok := s.put(key, value, flags, q)
+	s.mu.Unlock()
+	s.writeJSON(w, ok)
+}
+
+// put 按 cas / acquire / release 语义写入，调用方需持有锁
+func (s *Server) put(key string, value []byte, flags uint64, q map[string][]string) bool {
+	get := func(name string) (string, bool) {
+		v, ok := q[name]
+		if !ok || len(v) == 0 {
+			return "", ok
+		}
+		return v[0], true
+	}
+
+	existing := s.kv[key]
+	if v, ok := get("cas"); ok {
+		cas, _ := strconv.ParseUint(v, 10, 64)
+		switch {
+		case cas == 0 && existing != nil:
+			return false
+		case cas != 0 && (existing == nil || existing.ModifyIndex != cas):
+			return false
+		}
+	}
+
+	next := &capi.KVPair{Key: key, Flags: flags, Value: value}
+	if existing != nil {
+		next.CreateIndex = existing.CreateIndex
+		next.LockIndex = existing.LockIndex
+		next.Session = existing.Session
+	}
+
+	if id, ok := get("acquire"); ok {
+		if _, live := s.sessions[id]; !live {
+			return false
+		}
+		if existing != nil && existing.Session != "" && existing.Session != id {
+			return false
+		}
+		if next.Session != id {
+			next.LockIndex++
+		}
+		next.Session = id
+	}
+	if id, ok := get("release"); ok {
+		if existing == nil || existing.Session != id {
+			return false
+		}
+		next.Session = ""
+	}
+
+	index := s.bump()
+	if existing == nil {
+		next.CreateIndex = index
+	}
+	next.ModifyIndex = index
+	s.kv[key] = next
+	return true
+}
+
+func (s *Server) deleteKV(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	q := r.URL.Query()
+	_, recurse := q["recurse"]
+
+	s.mu.Lock()
+	ok := true
+	if v, has := q["cas"]; has && len(v) > 0 {
+		cas, _ := strconv.ParseUint(v[0], 10, 64)
+		if p := s.kv[key]; p == nil || p.ModifyIndex != cas {
+			ok = false
+		}
+	}
+	if ok {
+		for k := range s.kv {
+			if k == key || (recurse && strings.HasPrefix(k, key)) {
+				delete(s.kv, k)
+			}
+		}
+		s.bump()
+	}
+	s.mu.Unlock()
+	s.writeJSON(w, ok)
+}
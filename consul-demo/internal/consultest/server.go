@@ -0,0 +1,166 @@
+// Package consultest 提供一个内存版的 Consul Agent，用于在没有真实 Agent 的情况下测试注册、发现、KV 配置与选主。
+//
+// 只实现了 consul-demo 用到的 HTTP API 子集：
+//   - agent：服务注册 / 注销 / 查询、TTL 检查更新
+//   - catalog / health：服务查询（支持阻塞查询）
+//   - kv：get / list / put / cas / acquire / release / delete
+//   - session：create / destroy / renew / info
+//   - status：leader
+//
+// 简化之处：全局共享一个 raft index，任意写操作都会唤醒所有阻塞查询；
+// 不执行 HTTP / TCP 检查（通过 SetCheckStatus 设置状态），Session TTL 不会自动过期，也不实现 lock-delay。
+package consultest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+const (
+	// NodeName 假 Agent 的节点名
+	NodeName = "consultest"
+	// maxWait 阻塞查询的最长等待时间
+	maxWait = 10 * time.Minute
+)
+
+// Server 内存版 Consul Agent
+type Server struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	index    uint64
+	changed  chan struct{}
+	services map[string]*capi.AgentService
+	checks   map[string]*capi.HealthCheck
+	kv       map[string]*capi.KVPair
+	sessions map[string]*capi.SessionEntry
+	nextID   uint64
+}
+
+// NewServer 启动假 Agent，测试结束时自动关闭
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		index:    1,
+		changed:  make(chan struct{}),
+		services: make(map[string]*capi.AgentService),
+		checks:   make(map[string]*capi.HealthCheck),
+		kv:       make(map[string]*capi.KVPair),
+		sessions: make(map[string]*capi.SessionEntry),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status/leader", func(w http.ResponseWriter, r *http.Request) {
+		s.writeJSON(w, "127.0.0.1:8300")
+	})
+	s.routeAgent(mux)
+	s.routeCatalog(mux)
+	s.routeKV(mux)
+	s.routeSession(mux)
+
+	s.srv = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close 关闭假 Agent，之后的请求都会失败
+func (s *Server) Close() {
+	s.srv.CloseClientConnections()
+	s.srv.Close()
+}
+
+// Addr 返回 host:port
+func (s *Server) Addr() string {
+	return s.srv.Listener.Addr().String()
+}
+
+// Config 返回指向假 Agent 的客户端配置
+func (s *Server) Config() *capi.Config {
+	cfg := capi.DefaultConfig()
+	cfg.Address = s.Addr()
+	cfg.Scheme = "http"
+	cfg.Token = ""
+	return cfg
+}
+
+// Client 返回指向假 Agent 的客户端
+func (s *Server) Client(t testing.TB) *capi.Client {
+	t.Helper()
+	client, err := capi.NewClient(s.Config())
+	if err != nil {
+		t.Fatalf("创建 Consul 客户端失败: %v", err)
+	}
+	return client
+}
+
+// bump 写操作后递增 index 并唤醒阻塞查询，调用方需持有锁
+func (s *Server) bump() uint64 {
+	s.index++
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return s.index
+}
+
+// block 处理阻塞查询参数：index 不小于当前 index 时等待变更或超时
+func (s *Server) block(r *http.Request) {
+	index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	if index == 0 {
+		return
+	}
+	wait := maxWait
+	if v := r.URL.Query().Get("wait"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d < maxWait {
+			wait = d
+		}
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		current, changed := s.index, s.changed
+		s.mu.Unlock()
+		if current > index {
+			return
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeJSON 写入响应并附带 Consul 的查询元信息头
+func (s *Server) writeJSON(w http.ResponseWriter, v any) {
+	s.mu.Lock()
+	index := s.index
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+	w.Header().Set("X-Consul-KnownLeader", "true")
+	w.Header().Set("X-Consul-LastContact", "0")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) notFound(w http.ResponseWriter, msg string) {
+	s.mu.Lock()
+	index := s.index
+	s.mu.Unlock()
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+	w.Header().Set("X-Consul-KnownLeader", "true")
+	w.Header().Set("X-Consul-LastContact", "0")
+	http.Error(w, msg, http.StatusNotFound)
+}
@@ -0,0 +1,119 @@
+package consultest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+func (s *Server) routeSession(mux *http.ServeMux) {
+	mux.HandleFunc("PUT /v1/session/create", func(w http.ResponseWriter, r *http.Request) {
+		// LockDelay 以字符串形式（如 "15s"）提交，不能直接解码为 SessionEntry
+		var body struct {
+			Name      string
+			TTL       string
+			Behavior  string
+			LockDelay string
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if body.Behavior == "" {
+			body.Behavior = capi.SessionBehaviorRelease
+		}
+		lockDelay, _ := time.ParseDuration(body.LockDelay)
+
+		s.mu.Lock()
+		s.nextID++
+		id := fmt.Sprintf("00000000-0000-0000-0000-%012d", s.nextID)
+		index := s.bump()
+		s.sessions[id] = &capi.SessionEntry{
+			ID:          id,
+			Name:        body.Name,
+			Node:        NodeName,
+			TTL:         body.TTL,
+			Behavior:    body.Behavior,
+			LockDelay:   lockDelay,
+			CreateIndex: index,
+		}
+		s.mu.Unlock()
+		s.writeJSON(w, map[string]string{"ID": id})
+	})
+	mux.HandleFunc("PUT /v1/session/destroy/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.InvalidateSession(r.PathValue("id"))
+		s.writeJSON(w, true)
+	})
+	mux.HandleFunc("PUT /v1/session/renew/{id}", func(w http.ResponseWriter, r *http.Request) {
+		entries := s.sessionEntries(r.PathValue("id"))
+		if len(entries) == 0 {
+			s.notFound(w, "Session id '"+r.PathValue("id")+"' not found")
+			return
+		}
+		s.writeJSON(w, entries)
+	})
+	mux.HandleFunc("GET /v1/session/info/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.block(r)
+		s.writeJSON(w, s.sessionEntries(r.PathValue("id")))
+	})
+	mux.HandleFunc("GET /v1/session/list", func(w http.ResponseWriter, r *http.Request) {
+		s.block(r)
+		s.writeJSON(w, s.sessionEntries(""))
+	})
+}
+
+// sessionEntries 返回指定 Session，id 为空时返回全部
+func (s *Server) sessionEntries(id string) []*capi.SessionEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []*capi.SessionEntry{}
+	for sid, se := range s.sessions {
+		if id == "" || sid == id {
+			sc := *se
+			out = append(out, &sc)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// InvalidateSession 销毁 Session（模拟 TTL 过期或节点失效），
+// 按 Behavior 释放或删除其持有的锁，返回 Session 是否存在
+func (s *Server) InvalidateSession(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	se, ok := s.sessions[id]
+	if !ok {
+		return false
+	}
+	delete(s.sessions, id)
+
+	index := s.bump()
+	for k, p := range s.kv {
+		if p.Session != id {
+			continue
+		}
+		if se.Behavior == capi.SessionBehaviorDelete {
+			delete(s.kv, k)
+			continue
+		}
+		p.Session = ""
+		p.ModifyIndex = index
+	}
+	return true
+}
+
+// Sessions 返回当前存活的 Session 数量
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
@@ -0,0 +1,196 @@
+package discovery
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Strategy 负载均衡策略
+type Strategy string
+
+const (
+	RoundRobin    Strategy = "round_robin"
+	LeastRequests Strategy = "least_requests"
+	Random        Strategy = "random"
+)
+
+// ErrNoInstances 没有可用实例
+var ErrNoInstances = errors.New("discovery: no healthy instances")
+
+// Options 负载均衡参数
+type Options struct {
+	// Strategy 负载均衡策略，默认轮询
+	Strategy Strategy
+	// MaxFailures 连续失败（传输错误或 5xx）达到该次数后摘除实例，默认 5，小于 0 关闭摘除
+	MaxFailures int
+	// EjectionTime 摘除时长，默认 30s
+	EjectionTime time.Duration
+	// Base 底层 RoundTripper，默认 http.DefaultTransport
+	Base http.RoundTripper
+}
+
+// endpoint 单个实例的负载与摘除状态
+type endpoint struct {
+	inflight     atomic.Int64
+	failures     int
+	ejectedUntil time.Time
+}
+
+// Transport 基于服务发现的负载均衡 http.RoundTripper
+//
+// 请求 URL 中的 host 会被替换为选中实例的 host:port，例如 http://demo/demo/health。
+type Transport struct {
+	source Source
+	opts   Options
+	rr     atomic.Uint64
+
+	mu        sync.Mutex
+	endpoints map[string]*endpoint
+}
+
+// NewTransport 创建负载均衡 Transport
+func NewTransport(source Source, opts Options) *Transport {
+	if opts.Strategy == "" {
+		opts.Strategy = RoundRobin
+	}
+	if opts.MaxFailures == 0 {
+		opts.MaxFailures = 5
+	}
+	if opts.EjectionTime <= 0 {
+		opts.EjectionTime = 30 * time.Second
+	}
+	if opts.Base == nil {
+		opts.Base = http.DefaultTransport
+	}
+	return &Transport{
+		source:    source,
+		opts:      opts,
+		endpoints: make(map[string]*endpoint),
+	}
+}
+
+// NewClient 创建使用负载均衡 Transport 的 http.Client
+func NewClient(source Source, opts Options) *http.Client {
+	return &http.Client{Transport: NewTransport(source, opts)}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	inst, ep, err := t.pick()
+	if err != nil {
+		return nil, err
+	}
+
+	out := req.Clone(req.Context())
+	out.URL.Host = inst.Addr()
+	if out.URL.Scheme == "" {
+		out.URL.Scheme = "http"
+	}
+
+	ep.inflight.Add(1)
+	resp, err := t.opts.Base.RoundTrip(out)
+	if err != nil {
+		ep.inflight.Add(-1)
+		t.report(ep, false)
+		return nil, err
+	}
+	t.report(ep, resp.StatusCode < http.StatusInternalServerError)
+
+	// 响应体读取完毕才算请求结束
+	resp.Body = &trackedBody{ReadCloser: resp.Body, ep: ep}
+	return resp, nil
+}
+
+// pick 按策略从未被摘除的实例中选择一个，全部被摘除时退化为在所有实例中选择
+func (t *Transport) pick() (Instance, *endpoint, error) {
+	instances := t.source.Instances()
+	if len(instances) == 0 {
+		return Instance{}, nil, ErrNoInstances
+	}
+
+	now := time.Now()
+	t.mu.Lock()
+	eps := make([]*endpoint, len(instances))
+	candidates := make([]int, 0, len(instances))
+	for i, inst := range instances {
+		ep, ok := t.endpoints[inst.ID]
+		if !ok {
+			ep = &endpoint{}
+			t.endpoints[inst.ID] = ep
+		}
+		eps[i] = ep
+		if now.After(ep.ejectedUntil) {
+			candidates = append(candidates, i)
+		}
+	}
+	// 清理已下线实例的状态
+	if len(t.endpoints) > 2*len(instances) {
+		live := make(map[string]*endpoint, len(instances))
+		for i, inst := range instances {
+			live[inst.ID] = eps[i]
+		}
+		t.endpoints = live
+	}
+	t.mu.Unlock()
+
+	if len(candidates) == 0 {
+		for i := range instances {
+			candidates = append(candidates, i)
+		}
+	}
+
+	var chosen int
+	switch t.opts.Strategy {
+	case Random:
+		chosen = candidates[rand.IntN(len(candidates))]
+	case LeastRequests:
+		// power of two choices：随机取两个，选在途请求少的
+		a := candidates[rand.IntN(len(candidates))]
+		b := candidates[rand.IntN(len(candidates))]
+		chosen = a
+		if eps[b].inflight.Load() < eps[a].inflight.Load() {
+			chosen = b
+		}
+	default:
+		chosen = candidates[(t.rr.Add(1)-1)%uint64(len(candidates))]
+	}
+	return instances[chosen], eps[chosen], nil
+}
+
+// report 记录请求结果，连续失败达到阈值时摘除实例
+func (t *Transport) report(ep *endpoint, ok bool) {
+	if t.opts.MaxFailures < 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ok {
+		ep.failures = 0
+		return
+	}
+	ep.failures++
+	if ep.failures >= t.opts.MaxFailures {
+		ep.failures = 0
+		ep.ejectedUntil = time.Now().Add(t.opts.EjectionTime)
+		ejections.Inc()
+	}
+}
+
+// trackedBody 在响应体关闭时减少在途请求数
+type trackedBody struct {
+	io.ReadCloser
+	ep   *endpoint
+	once sync.Once
+}
+
+func (b *trackedBody) Close() error {
+	b.once.Do(func() { b.ep.inflight.Add(-1) })
+	return b.ReadCloser.Close()
+}
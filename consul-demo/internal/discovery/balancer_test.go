@@ -0,0 +1,85 @@
+package discovery
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newBackend 启动一个返回固定状态码、响应体为 name 的后端
+func newBackend(t *testing.T, name string, code int) Instance {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		io.WriteString(w, name)
+	}))
+	t.Cleanup(srv.Close)
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return Instance{ID: name, Address: host, Port: p}
+}
+
+func get(t *testing.T, c *http.Client) (string, int) {
+	t.Helper()
+	resp, err := c.Get("http://demo/demo/health")
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body), resp.StatusCode
+}
+
+func TestRoundRobin(t *testing.T) {
+	src := Static{newBackend(t, "a", http.StatusOK), newBackend(t, "b", http.StatusOK)}
+	c := NewClient(src, Options{Strategy: RoundRobin})
+
+	hits := map[string]int{}
+	for range 10 {
+		name, _ := get(t, c)
+		hits[name]++
+	}
+	if hits["a"] != 5 || hits["b"] != 5 {
+		t.Fatalf("轮询分布不均: %v", hits)
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	for _, s := range []Strategy{Random, LeastRequests} {
+		src := Static{newBackend(t, "a", http.StatusOK), newBackend(t, "b", http.StatusOK)}
+		c := NewClient(src, Options{Strategy: s})
+		for range 20 {
+			if _, code := get(t, c); code != http.StatusOK {
+				t.Fatalf("%s: 状态码 %d", s, code)
+			}
+		}
+	}
+}
+
+func TestOutlierEjection(t *testing.T) {
+	src := Static{newBackend(t, "good", http.StatusOK), newBackend(t, "bad", http.StatusBadGateway)}
+	c := NewClient(src, Options{Strategy: RoundRobin, MaxFailures: 2, EjectionTime: time.Minute})
+
+	// 前 4 个请求中 bad 连续失败 2 次后被摘除
+	for range 4 {
+		get(t, c)
+	}
+	for range 10 {
+		if name, _ := get(t, c); name != "good" {
+			t.Fatalf("被摘除的实例仍收到请求: %s", name)
+		}
+	}
+}
+
+func TestNoInstances(t *testing.T) {
+	c := NewClient(Static{}, Options{})
+	if _, err := c.Get("http://demo/"); !errors.Is(err, ErrNoInstances) {
+		t.Fatalf("期望 ErrNoInstances，实际 %v", err)
+	}
+}
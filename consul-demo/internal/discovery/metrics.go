@@ -0,0 +1,9 @@
+package discovery
+
+import "github.com/lyonmu/demo/consul-demo/internal/metrics"
+
+var (
+	discoveredInstances = metrics.Consul.GaugeVec("discovery_instances", "Number of healthy instances of a watched service.", "service")
+	resolveErrors       = metrics.Consul.CounterVec("discovery_errors_total", "Total failed health queries for a watched service.", "service")
+	ejections           = metrics.Consul.Counter("discovery_ejections_total", "Total times an instance was ejected after consecutive failures.")
+)
@@ -0,0 +1,133 @@
+package discovery
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/base-demo/pkg/consul"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+)
+
+// Instance 服务实例
+type Instance struct {
+	ID      string
+	Address string
+	Port    int
+	Tags    []string
+	Meta    map[string]string
+}
+
+// Addr 返回 host:port
+func (i Instance) Addr() string {
+	return net.JoinHostPort(i.Address, strconv.Itoa(i.Port))
+}
+
+// Source 实例来源，Resolver 与测试中的静态列表都实现该接口
+type Source interface {
+	Instances() []Instance
+}
+
+// Static 静态实例列表
+type Static []Instance
+
+// Instances 实现 Source
+func (s Static) Instances() []Instance { return s }
+
+// ResolverOptions Resolver 参数
+type ResolverOptions struct {
+	// Tags 实例必须同时包含的标签，如 sentinel
+	Tags []string
+	// WaitTime 阻塞查询的最长等待时间，默认 5m
+	WaitTime time.Duration
+	// RetryInterval 查询失败后的重试间隔，默认 5s
+	RetryInterval time.Duration
+}
+
+// Resolver 通过阻塞查询监听某个服务的健康实例
+type Resolver struct {
+	client  *capi.Client
+	service string
+	opts    ResolverOptions
+
+	instances atomic.Pointer[[]Instance]
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+// NewResolver 创建 Resolver，需调用 Run 开始监听
+func NewResolver(client *capi.Client, service string, opts ResolverOptions) *Resolver {
+	if opts.WaitTime <= 0 {
+		opts.WaitTime = 5 * time.Minute
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+	r := &Resolver{
+		client:  client,
+		service: service,
+		opts:    opts,
+		ready:   make(chan struct{}),
+	}
+	r.instances.Store(&[]Instance{})
+	return r
+}
+
+// Service 返回监听的服务名
+func (r *Resolver) Service() string {
+	return r.service
+}
+
+// Instances 返回当前健康实例快照
+func (r *Resolver) Instances() []Instance {
+	return *r.instances.Load()
+}
+
+// Ready 首次成功查询后关闭
+func (r *Resolver) Ready() <-chan struct{} {
+	return r.ready
+}
+
+// Run 持续监听健康实例，ctx 结束时返回
+func (r *Resolver) Run(ctx context.Context) {
+	log := slog.With(slog.String("service", r.service))
+
+	consul.BlockingQuery(ctx, consul.WatchOptions{
+		WaitTime:      r.opts.WaitTime,
+		RetryInterval: r.opts.RetryInterval,
+		OnError: func(err error) {
+			resolveErrors.WithLabelValues(r.service).Inc()
+			log.Warn("Failed to resolve service", logger.Err(err))
+		},
+	}, func(q *capi.QueryOptions) (*capi.QueryMeta, error) {
+		entries, meta, err := r.client.Health().ServiceMultipleTags(r.service, r.opts.Tags, true, q)
+		if err != nil {
+			return nil, err
+		}
+
+		instances := make([]Instance, 0, len(entries))
+		for _, e := range entries {
+			addr := e.Service.Address
+			if addr == "" {
+				addr = e.Node.Address
+			}
+			instances = append(instances, Instance{
+				ID:      e.Service.ID,
+				Address: addr,
+				Port:    e.Service.Port,
+				Tags:    e.Service.Tags,
+				Meta:    e.Service.Meta,
+			})
+		}
+		r.instances.Store(&instances)
+		discoveredInstances.WithLabelValues(r.service).Set(float64(len(instances)))
+		r.readyOnce.Do(func() { close(r.ready) })
+		log.Debug("Service instances updated", slog.Int("instances", len(instances)))
+		return meta, nil
+	})
+}
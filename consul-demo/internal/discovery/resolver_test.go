@@ -0,0 +1,77 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/consul-demo/internal/consultest"
+)
+
+func register(t *testing.T, client *capi.Client, id string, port int, tags ...string) {
+	t.Helper()
+	err := client.Agent().ServiceRegister(&capi.AgentServiceRegistration{
+		ID:      id,
+		Name:    "demo",
+		Address: "127.0.0.1",
+		Port:    port,
+		Tags:    tags,
+		Check:   &capi.AgentServiceCheck{CheckID: id, TTL: "15s", Status: capi.HealthPassing},
+	})
+	if err != nil {
+		t.Fatalf("注册 %s 失败: %v", id, err)
+	}
+}
+
+func waitInstances(t *testing.T, r *Resolver, want int) []Instance {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		instances := r.Instances()
+		if len(instances) == want {
+			return instances
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("期望 %d 个实例，实际 %v", want, instances)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestResolverWatch(t *testing.T) {
+	srv := consultest.NewServer(t)
+	client := srv.Client(t)
+	register(t, client, "a", 8001, "sentinel")
+	register(t, client, "b", 8002, "sentinel")
+	register(t, client, "c", 8003)
+
+	r := NewResolver(client, "demo", ResolverOptions{Tags: []string{"sentinel"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	select {
+	case <-r.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Resolver 未就绪")
+	}
+	instances := waitInstances(t, r, 2)
+	if instances[0].Addr() != "127.0.0.1:8001" {
+		t.Fatalf("实例地址错误: %s", instances[0].Addr())
+	}
+
+	// 检查失败的实例被剔除，恢复后重新加入
+	srv.SetCheckStatus("a", capi.HealthCritical, "down")
+	if got := waitInstances(t, r, 1); got[0].ID != "b" {
+		t.Fatalf("剩余实例错误: %v", got)
+	}
+	srv.SetCheckStatus("a", capi.HealthPassing, "")
+	waitInstances(t, r, 2)
+
+	// 注销后阻塞查询立即返回
+	if err := client.Agent().ServiceDeregister("b"); err != nil {
+		t.Fatalf("注销失败: %v", err)
+	}
+	waitInstances(t, r, 1)
+}
@@ -0,0 +1,195 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+)
+
+// Options 选主参数
+type Options struct {
+	// Key 锁对应的 KV key，如 service/demo/leader
+	Key string
+	// Value 持有锁时写入的值，通常为实例 ID
+	Value []byte
+	// SessionName Session 名称
+	SessionName string
+	// SessionTTL Session TTL，默认 15s，按 TTL/2 续约
+	SessionTTL string
+	// LockDelay 锁释放后其他实例重新获取前的等待时间，默认 15s（Consul 默认值）
+	LockDelay time.Duration
+	// RetryInterval 选举出错后的重试间隔，默认 5s
+	RetryInterval time.Duration
+	// StepDownTimeout 失去 leader 后等待 OnElected 退出的最长时间，默认 10s；
+	// 超时后不释放锁、不销毁 Session，直接退出进程，锁在 Session 过期与 LockDelay 之后才能被其他实例获取
+	StepDownTimeout time.Duration
+
+	// OnElected 成为 leader 时调用，ctx 在失去 leader 身份或退出时取消，应阻塞直到 ctx 结束
+	OnElected func(ctx context.Context)
+	// OnLost 失去 leader 身份（包括主动退出）且 OnElected 已返回后调用
+	OnLost func()
+}
+
+// exit 退出进程，测试中替换
+var exit = os.Exit
+
+// Elector 基于 Consul Session 与 KV 锁的选主
+type Elector struct {
+	client *capi.Client
+	opts   Options
+	leader atomic.Bool
+	log    *slog.Logger
+}
+
+// New 创建 Elector
+func New(client *capi.Client, opts Options) (*Elector, error) {
+	if opts.Key == "" {
+		return nil, errors.New("election key is required")
+	}
+	if opts.OnElected == nil {
+		return nil, errors.New("OnElected callback is required")
+	}
+	if opts.SessionTTL == "" {
+		opts.SessionTTL = "15s"
+	}
+	if _, err := time.ParseDuration(opts.SessionTTL); err != nil {
+		return nil, err
+	}
+	if opts.SessionName == "" {
+		opts.SessionName = "election: " + opts.Key
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+	if opts.StepDownTimeout <= 0 {
+		opts.StepDownTimeout = 10 * time.Second
+	}
+	return &Elector{
+		client: client,
+		opts:   opts,
+		log:    slog.With(slog.String("election_key", opts.Key)),
+	}, nil
+}
+
+// IsLeader 当前是否为 leader
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run 持续参与选举，ctx 结束时主动让出 leader 并返回
+func (e *Elector) Run(ctx context.Context) {
+	for {
+		err := e.campaign(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			e.log.Warn("Leader election failed, retrying", slog.Duration("retry", e.opts.RetryInterval), logger.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.opts.RetryInterval):
+		}
+	}
+}
+
+// campaign 创建 Session 并竞争锁，持有期间运行 OnElected，直到失去锁或 ctx 结束
+func (e *Elector) campaign(ctx context.Context) error {
+	session := e.client.Session()
+	sessionID, _, err := session.Create(&capi.SessionEntry{
+		Name:      e.opts.SessionName,
+		TTL:       e.opts.SessionTTL,
+		Behavior:  capi.SessionBehaviorRelease,
+		LockDelay: e.opts.LockDelay,
+	}, (&capi.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	// 后台续约；关闭 renewDone 时 RenewPeriodic 会销毁 Session，返回前等待销毁完成
+	renewDone := make(chan struct{})
+	renewStopped := make(chan struct{})
+	renewErr := make(chan error, 1)
+	go func() {
+		defer close(renewStopped)
+		renewErr <- session.RenewPeriodic(e.opts.SessionTTL, sessionID, &capi.WriteOptions{}, renewDone)
+	}()
+	defer func() {
+		close(renewDone)
+		<-renewStopped
+	}()
+
+	lock, err := e.client.LockOpts(&capi.LockOptions{
+		Key:            e.opts.Key,
+		Value:          e.opts.Value,
+		Session:        sessionID,
+		MonitorRetries: 3,
+	})
+	if err != nil {
+		return err
+	}
+
+	lostCh, err := lock.Lock(ctx.Done())
+	if err != nil {
+		return err
+	}
+	if lostCh == nil {
+		// ctx 结束，放弃竞争
+		return nil
+	}
+
+	e.log.Info("Became leader", slog.String("session", sessionID))
+	e.leader.Store(true)
+	leader.WithLabelValues(e.opts.Key).Set(1)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.opts.OnElected(leaderCtx)
+	}()
+
+	var reason error
+	select {
+	case <-lostCh:
+		reason = errors.New("lock lost")
+	case err := <-renewErr:
+		reason = errors.Join(errors.New("session renewal stopped"), err)
+	case <-ctx.Done():
+	}
+
+	// 先停止 leader 任务并等待其退出，再释放锁，避免新旧 leader 同时运行；
+	// 任务迟迟不退出时宁可退出进程，也不能在任务仍在运行时释放锁
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(e.opts.StepDownTimeout):
+		e.log.Error("Leader task did not stop in time, exiting without releasing the lock", slog.Duration("timeout", e.opts.StepDownTimeout))
+		exit(1)
+		<-done
+	}
+	e.leader.Store(false)
+	leader.WithLabelValues(e.opts.Key).Set(0)
+
+	if err := lock.Unlock(); err != nil && !errors.Is(err, capi.ErrLockNotHeld) {
+		e.log.Warn("Failed to release leader lock", logger.Err(err))
+	}
+	if e.opts.OnLost != nil {
+		e.opts.OnLost()
+	}
+
+	if reason != nil {
+		leadershipLost.WithLabelValues(e.opts.Key).Inc()
+		e.log.Warn("Lost leadership", logger.Err(reason))
+	} else {
+		e.log.Info("Stepped down")
+	}
+	return reason
+}
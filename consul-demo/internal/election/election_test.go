@@ -0,0 +1,179 @@
+package election
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/consul-demo/internal/consultest"
+)
+
+const testKey = "service/demo/leader"
+
+// candidate 启动一个参与选举的实例，running 记录其 leader 任务是否在运行
+type candidate struct {
+	elector *Elector
+	running atomic.Bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func startCandidate(t *testing.T, client *capi.Client, name string) *candidate {
+	t.Helper()
+	c := &candidate{done: make(chan struct{})}
+	e, err := New(client, Options{
+		Key:           testKey,
+		Value:         []byte(name),
+		RetryInterval: 20 * time.Millisecond,
+		OnElected: func(ctx context.Context) {
+			c.running.Store(true)
+			<-ctx.Done()
+			c.running.Store(false)
+		},
+	})
+	if err != nil {
+		t.Fatalf("创建 Elector 失败: %v", err)
+	}
+	c.elector = e
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go func() {
+		defer close(c.done)
+		e.Run(ctx)
+	}()
+	t.Cleanup(c.stop)
+	return c
+}
+
+func (c *candidate) stop() {
+	c.cancel()
+	<-c.done
+}
+
+func waitLeader(t *testing.T, candidates ...*candidate) *candidate {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var leaders []*candidate
+		for _, c := range candidates {
+			if c.elector.IsLeader() {
+				leaders = append(leaders, c)
+			}
+		}
+		if len(leaders) > 1 {
+			t.Fatalf("同时存在 %d 个 leader", len(leaders))
+		}
+		if len(leaders) == 1 && leaders[0].running.Load() {
+			return leaders[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("等待 leader 超时")
+	return nil
+}
+
+func TestFailoverOnStepDown(t *testing.T) {
+	srv := consultest.NewServer(t)
+	a := startCandidate(t, srv.Client(t), "a")
+	b := startCandidate(t, srv.Client(t), "b")
+
+	first := waitLeader(t, a, b)
+	other := b
+	if first == b {
+		other = a
+	}
+
+	first.stop()
+	if first.running.Load() || first.elector.IsLeader() {
+		t.Fatalf("退出后仍在运行 leader 任务")
+	}
+	if waitLeader(t, a, b) != other {
+		t.Fatalf("leader 未切换")
+	}
+}
+
+func TestSessionInvalidated(t *testing.T) {
+	srv := consultest.NewServer(t)
+	client := srv.Client(t)
+	c := startCandidate(t, client, "a")
+	waitLeader(t, c)
+
+	pair, _, err := client.KV().Get(testKey, nil)
+	if err != nil || pair == nil || pair.Session == "" {
+		t.Fatalf("锁未被持有: %+v, %v", pair, err)
+	}
+	if string(pair.Value) != "a" {
+		t.Fatalf("锁值错误: %q", pair.Value)
+	}
+
+	// Session 失效后失去 leader，随后用新 Session 重新当选
+	srv.InvalidateSession(pair.Session)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p, _, err := client.KV().Get(testKey, nil)
+		if err == nil && p != nil && p.Session != "" && p.Session != pair.Session && c.running.Load() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Session 失效后未重新当选")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	c.stop()
+	if n := srv.Sessions(); n != 0 {
+		t.Fatalf("退出后仍有 %d 个 Session", n)
+	}
+}
+
+func TestStepDownTimeoutKeepsLock(t *testing.T) {
+	srv := consultest.NewServer(t)
+	client := srv.Client(t)
+
+	exited := make(chan int, 1)
+	exit = func(code int) { exited <- code }
+	t.Cleanup(func() { exit = os.Exit })
+
+	// leader 任务忽略 ctx，直到测试放行
+	running := make(chan struct{})
+	release := make(chan struct{})
+	e, err := New(client, Options{
+		Key:             testKey,
+		StepDownTimeout: 50 * time.Millisecond,
+		OnElected: func(context.Context) {
+			close(running)
+			<-release
+		},
+	})
+	if err != nil {
+		t.Fatalf("创建 Elector 失败: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.Run(ctx)
+	}()
+	<-running
+
+	cancel()
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Fatalf("退出码错误: %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("超时后应退出进程")
+	}
+	// 任务仍在运行，锁不能被释放
+	if pair, _, err := client.KV().Get(testKey, nil); err != nil || pair == nil || pair.Session == "" {
+		t.Fatalf("超时后锁被释放: %+v, %v", pair, err)
+	}
+
+	close(release)
+	<-done
+}
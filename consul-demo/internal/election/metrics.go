@@ -0,0 +1,8 @@
+package election
+
+import "github.com/lyonmu/demo/consul-demo/internal/metrics"
+
+var (
+	leader         = metrics.Consul.GaugeVec("election_leader", "Whether this instance currently holds the leader lock (1) or not (0).", "key")
+	leadershipLost = metrics.Consul.CounterVec("election_lost_total", "Total times leadership was lost unexpectedly.", "key")
+)
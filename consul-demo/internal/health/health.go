@@ -0,0 +1,164 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Status 探针状态
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Kind 探针类型
+type Kind int
+
+const (
+	// Liveness 存活探针，失败意味着进程需要重启
+	Liveness Kind = 1 << iota
+	// Readiness 就绪探针，失败意味着不应再接收流量
+	Readiness
+)
+
+// defaultTimeout 单个探针的默认超时
+const defaultTimeout = 3 * time.Second
+
+// Probe 健康探针，返回 nil 为通过，返回 Warn(err) 为降级，其他 error 为失败
+type Probe func(ctx context.Context) error
+
+type warnError struct{ err error }
+
+func (e warnError) Error() string { return e.err.Error() }
+func (e warnError) Unwrap() error { return e.err }
+
+// Warn 将 error 标记为降级（warn）而非失败
+func Warn(err error) error {
+	if err == nil {
+		return nil
+	}
+	return warnError{err: err}
+}
+
+// Result 单个探针的执行结果
+type Result struct {
+	Name     string `json:"name"`
+	Status   Status `json:"status"`
+	Output   string `json:"output,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report 聚合结果，整体状态取所有探针中最差的一个
+type Report struct {
+	Status Status    `json:"status"`
+	Time   time.Time `json:"time"`
+	Checks []Result  `json:"checks"`
+}
+
+type probe struct {
+	name string
+	kind Kind
+	fn   Probe
+}
+
+// Checker 探针注册表
+type Checker struct {
+	mu      sync.RWMutex
+	probes  []probe
+	timeout time.Duration
+}
+
+// New 创建 Checker
+func New() *Checker {
+	return &Checker{timeout: defaultTimeout}
+}
+
+// Register 注册探针，kind 可以组合，如 Liveness|Readiness
+func (c *Checker) Register(name string, kind Kind, fn Probe) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.probes = slices.DeleteFunc(c.probes, func(p probe) bool { return p.name == name })
+	c.probes = append(c.probes, probe{name: name, kind: kind, fn: fn})
+}
+
+// Check 并发执行指定类型的探针并聚合结果
+func (c *Checker) Check(ctx context.Context, kind Kind) Report {
+	c.mu.RLock()
+	probes := slices.Clone(c.probes)
+	c.mu.RUnlock()
+
+	probes = slices.DeleteFunc(probes, func(p probe) bool { return p.kind&kind == 0 })
+
+	results := make([]Result, len(probes))
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, p)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusPass, Time: time.Now(), Checks: results}
+	for _, r := range results {
+		report.Status = worse(report.Status, r.Status)
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, p probe) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := p.fn(ctx)
+	r := Result{Name: p.name, Status: StatusPass, Duration: time.Since(start).String()}
+
+	var warn warnError
+	switch {
+	case err == nil:
+	case errors.As(err, &warn):
+		r.Status, r.Output = StatusWarn, err.Error()
+	default:
+		r.Status, r.Output = StatusFail, err.Error()
+	}
+	return r
+}
+
+// Handler 返回 gin 处理函数：pass / warn 返回 200，fail 返回 503
+func (c *Checker) Handler(kind Kind) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		report := c.Check(ctx.Request.Context(), kind)
+		code := http.StatusOK
+		if report.Status == StatusFail {
+			code = http.StatusServiceUnavailable
+		}
+		ctx.JSON(code, report)
+	}
+}
+
+// Mount 挂载 /health/live、/health/ready，/health 兼容旧的 Consul HTTP 检查，等同 ready
+func (c *Checker) Mount(group *gin.RouterGroup) {
+	group.GET("/health/live", c.Handler(Liveness))
+	group.GET("/health/ready", c.Handler(Readiness))
+	group.GET("/health", c.Handler(Readiness))
+}
+
+func worse(a, b Status) Status {
+	rank := map[Status]int{StatusPass: 0, StatusWarn: 1, StatusFail: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckAggregate(t *testing.T) {
+	c := New()
+	c.Register("ok", Liveness|Readiness, func(context.Context) error { return nil })
+	c.Register("degraded", Readiness, func(context.Context) error { return Warn(errors.New("slow")) })
+
+	if r := c.Check(context.Background(), Liveness); r.Status != StatusPass || len(r.Checks) != 1 {
+		t.Fatalf("liveness 结果错误: %+v", r)
+	}
+	r := c.Check(context.Background(), Readiness)
+	if r.Status != StatusWarn || len(r.Checks) != 2 {
+		t.Fatalf("readiness 应为 warn: %+v", r)
+	}
+	if out := Output(r); !strings.Contains(out, "degraded: warn (slow)") {
+		t.Fatalf("TTL 输出错误: %q", out)
+	}
+
+	// 同名探针覆盖注册
+	c.Register("degraded", Readiness, func(context.Context) error { return errors.New("down") })
+	if r := c.Check(context.Background(), Readiness); r.Status != StatusFail || len(r.Checks) != 2 {
+		t.Fatalf("readiness 应为 fail: %+v", r)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c := New()
+	c.Register("live", Liveness, func(context.Context) error { return nil })
+	c.Register("ready", Readiness, func(context.Context) error { return errors.New("not ready") })
+
+	r := gin.New()
+	c.Mount(r.Group("demo"))
+
+	cases := map[string]int{
+		"/demo/health/live":  http.StatusOK,
+		"/demo/health/ready": http.StatusServiceUnavailable,
+		"/demo/health":       http.StatusServiceUnavailable,
+	}
+	for path, code := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != code {
+			t.Fatalf("%s: 状态码 %d，期望 %d", path, w.Code, code)
+		}
+		var report Report
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil || len(report.Checks) != 1 {
+			t.Fatalf("%s: 响应体错误: %s", path, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+
+	capi "github.com/hashicorp/consul/api"
+)
+
+// ConsulProbe 检查 Consul 连通性（本地 Agent 可达且集群已选出 leader）
+func ConsulProbe(client *capi.Client) Probe {
+	return func(ctx context.Context) error {
+		leader, err := client.Status().LeaderWithQueryOptions((&capi.QueryOptions{}).WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		if leader == "" {
+			// Agent 可达但集群无 leader，降级而不是直接摘除流量
+			return Warn(errors.New("consul cluster has no leader"))
+		}
+		return nil
+	}
+}
+
+// DiskProbe 检查 path 所在磁盘的剩余空间，低于 warnBytes 降级，低于 failBytes 失败
+func DiskProbe(path string, warnBytes, failBytes uint64) Probe {
+	return func(context.Context) error {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(path, &st); err != nil {
+			return err
+		}
+		free := st.Bavail * uint64(st.Bsize)
+		switch {
+		case free < failBytes:
+			return fmt.Errorf("disk %s free %d MiB below %d MiB", path, free>>20, failBytes>>20)
+		case free < warnBytes:
+			return Warn(fmt.Errorf("disk %s free %d MiB below %d MiB", path, free>>20, warnBytes>>20))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+)
+
+// TTLReporter 定期将就绪探针的聚合结果推送到 Consul TTL 检查
+type TTLReporter struct {
+	checker  *Checker
+	client   *capi.Client
+	checkID  string
+	interval time.Duration
+}
+
+// NewTTLReporter 创建 TTL 上报器，interval 应小于检查的 TTL（一般取 TTL 的 1/3）
+func NewTTLReporter(checker *Checker, client *capi.Client, checkID string, interval time.Duration) *TTLReporter {
+	return &TTLReporter{
+		checker:  checker,
+		client:   client,
+		checkID:  checkID,
+		interval: interval,
+	}
+}
+
+// Run 立即上报一次，之后按间隔上报，ctx 结束时返回
+func (r *TTLReporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Report(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("Failed to update TTL check", slog.String("check_id", r.checkID), logger.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Report 执行一次就绪探针并上报
+func (r *TTLReporter) Report(ctx context.Context) error {
+	report := r.checker.Check(ctx, Readiness)
+
+	q := (&capi.QueryOptions{}).WithContext(ctx)
+	return r.client.Agent().UpdateTTLOpts(r.checkID, Output(report), consulStatus(report.Status), q)
+}
+
+// Output 将聚合结果格式化为 Consul 检查输出，每个探针一行
+func Output(report Report) string {
+	var b strings.Builder
+	for _, c := range report.Checks {
+		fmt.Fprintf(&b, "%s: %s", c.Name, c.Status)
+		if c.Output != "" {
+			fmt.Fprintf(&b, " (%s)", c.Output)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func consulStatus(s Status) string {
+	switch s {
+	case StatusWarn:
+		return capi.HealthWarning
+	case StatusFail:
+		return capi.HealthCritical
+	default:
+		return capi.HealthPassing
+	}
+}
@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/consul-demo/internal/consultest"
+)
+
+func TestTTLReporter(t *testing.T) {
+	srv := consultest.NewServer(t)
+	client := srv.Client(t)
+	err := client.Agent().ServiceRegister(&capi.AgentServiceRegistration{
+		ID:    "demo-1",
+		Name:  "demo",
+		Check: &capi.AgentServiceCheck{CheckID: "demo-1:ttl", TTL: "15s"},
+	})
+	if err != nil {
+		t.Fatalf("注册失败: %v", err)
+	}
+
+	var probeErr error
+	c := New()
+	c.Register("dep", Readiness, func(context.Context) error { return probeErr })
+	r := NewTTLReporter(c, client, "demo-1:ttl", 0)
+
+	for _, tc := range []struct {
+		err    error
+		status string
+	}{
+		{nil, capi.HealthPassing},
+		{Warn(errors.New("slow")), capi.HealthWarning},
+		{errors.New("down"), capi.HealthCritical},
+	} {
+		probeErr = tc.err
+		if err := r.Report(context.Background()); err != nil {
+			t.Fatalf("上报失败: %v", err)
+		}
+		check, _ := srv.Check("demo-1:ttl")
+		if check.Status != tc.status || !strings.HasPrefix(check.Output, "dep: ") {
+			t.Fatalf("期望 %s，实际 %s (%q)", tc.status, check.Status, check.Output)
+		}
+	}
+
+	if err := NewTTLReporter(c, client, "missing", 0).Report(context.Background()); err == nil {
+		t.Fatalf("未知检查应返回错误")
+	}
+}
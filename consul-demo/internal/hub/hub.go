@@ -0,0 +1,218 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/base-demo/pkg/metrics/wsmetrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// heartbeatInterval 广播循环的心跳间隔，用于存活探测
+const heartbeatInterval = time.Second
+
+var tracer = otel.Tracer("github.com/lyonmu/demo/consul-demo/internal/hub")
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		// 允许所有来源的连接，生产环境应该检查具体的来源
+		return true
+	},
+}
+
+// Hub 管理所有活跃的 WebSocket 连接并负责广播
+type Hub struct {
+	mu        sync.RWMutex
+	clients   map[*websocket.Conn]*slog.Logger
+	broadcast chan envelope
+	lastBeat  atomic.Int64
+}
+
+// envelope 广播消息与投递方的 context，广播 span 作为投递方 span 的子 span
+type envelope struct {
+	ctx context.Context
+	msg any
+}
+
+// New 创建 Hub
+func New() *Hub {
+	h := &Hub{
+		clients:   make(map[*websocket.Conn]*slog.Logger),
+		broadcast: make(chan envelope),
+	}
+	h.lastBeat.Store(time.Now().UnixNano())
+	return h
+}
+
+// Clients 返回当前连接数
+func (h *Hub) Clients() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// Broadcast 投递一条广播消息，阻塞直到广播循环接收或 ctx 结束
+func (h *Hub) Broadcast(ctx context.Context, msg any) error {
+	select {
+	case h.broadcast <- envelope{ctx: ctx, msg: msg}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run 广播循环，向所有客户端发送消息，ctx 结束时关闭所有连接
+func (h *Hub) Run(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		h.lastBeat.Store(time.Now().UnixNano())
+
+		select {
+		case <-ctx.Done():
+			h.closeAll()
+			return
+		case <-ticker.C:
+		case e := <-h.broadcast:
+			h.publish(e)
+		}
+	}
+}
+
+// Probe 存活探测：广播循环超过 maxSilence 没有心跳视为卡死
+func (h *Hub) Probe(maxSilence time.Duration) func(context.Context) error {
+	return func(context.Context) error {
+		silence := time.Since(time.Unix(0, h.lastBeat.Load()))
+		if silence > maxSilence {
+			return fmt.Errorf("broadcast loop stalled for %s", silence.Truncate(time.Millisecond))
+		}
+		return nil
+	}
+}
+
+// ServeWS 处理 WebSocket 连接
+//
+// 连接 span 是升级请求 span 的子 span，覆盖整个连接生命周期；每条客户端消息是一个新的根 span，
+// 通过 link 关联到连接 span，避免长连接的 trace 无限增长
+func (h *Hub) ServeWS(c *gin.Context) {
+	// 连接 ID 沿用请求 ID（来自 Envoy 的 X-Request-ID 或中间件生成）
+	connID := logger.RequestIDFrom(c.Request.Context())
+	log := slog.With(slog.String("conn_id", connID))
+
+	ctx, span := tracer.Start(c.Request.Context(), "websocket connection",
+		trace.WithAttributes(attribute.String("websocket.conn_id", connID)))
+	defer span.End()
+
+	// 升级 HTTP 连接为 WebSocket
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "upgrade failed")
+		log.ErrorContext(ctx, "WebSocket upgrade error", logger.Err(err))
+		return
+	}
+	defer conn.Close()
+
+	// 注册新客户端
+	h.mu.Lock()
+	h.clients[conn] = log
+	total := len(h.clients)
+	h.mu.Unlock()
+	// hub 不做鉴权，连接均记为未鉴权
+	wsmetrics.Opened(false)
+	log.InfoContext(ctx, "New client connected", slog.Int("clients", total))
+
+	// 读取客户端消息，直到客户端断开
+	link := trace.LinkFromContext(ctx)
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			span.AddEvent("disconnected", trace.WithAttributes(attribute.String("error", err.Error())))
+			log.InfoContext(ctx, "Client disconnected", logger.Err(err))
+			h.remove(conn)
+			return
+		}
+		wsmetrics.Received(wsmetrics.MessageOther)
+		msgCtx, msgSpan := tracer.Start(ctx, "websocket receive",
+			trace.WithNewRoot(),
+			trace.WithLinks(link),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attribute.String("websocket.conn_id", connID), attribute.Int("websocket.message.size", len(message))))
+		log.DebugContext(msgCtx, "Received from client", slog.String("message", string(message)))
+		msgSpan.End()
+	}
+}
+
+// publish 编码并发送一条广播消息，记录为投递方 span 的子 span
+func (h *Hub) publish(e envelope) {
+	_, span := tracer.Start(e.ctx, "websocket broadcast", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+
+	messageJSON, err := json.Marshal(e.msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "marshal failed")
+		slog.Error("JSON marshal error", logger.Err(err))
+		return
+	}
+	sent, failed := h.send(messageJSON)
+	span.SetAttributes(
+		attribute.Int("websocket.message.size", len(messageJSON)),
+		attribute.Int("websocket.clients.sent", sent),
+		attribute.Int("websocket.clients.failed", failed),
+	)
+}
+
+// send 向所有客户端发送消息，返回发送成功与失败的客户端数
+func (h *Hub) send(message []byte) (sent, failed int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client, log := range h.clients {
+		if err := client.WriteMessage(websocket.TextMessage, message); err != nil {
+			wsmetrics.SendFailed()
+			failed++
+			log.Warn("Write message error", logger.Err(err))
+			// 关闭连接后读循环会返回并移除该客户端
+			client.Close()
+			continue
+		}
+		wsmetrics.Sent()
+		sent++
+	}
+	return sent, failed
+}
+
+func (h *Hub) remove(conn *websocket.Conn) {
+	h.mu.Lock()
+	if _, ok := h.clients[conn]; ok {
+		delete(h.clients, conn)
+		wsmetrics.Closed()
+	}
+	h.mu.Unlock()
+}
+
+func (h *Hub) closeAll() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients {
+		_ = client.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+			time.Now().Add(time.Second))
+		client.Close()
+	}
+}
@@ -0,0 +1,8 @@
+package kvconfig
+
+import "github.com/lyonmu/demo/consul-demo/internal/metrics"
+
+var (
+	kvUpdates     = metrics.Consul.CounterVec("kv_updates_total", "Total KV config changes by result (applied or rejected).", "prefix", "result")
+	kvWatchErrors = metrics.Consul.CounterVec("kv_watch_errors_total", "Total failed KV watch queries.", "prefix")
+)
@@ -0,0 +1,258 @@
+package kvconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/base-demo/pkg/consul"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+)
+
+// TagName 结构体字段与 KV 路径片段的映射 tag，如 `kv:"tick_interval"`
+const TagName = "kv"
+
+// Options Provider 参数
+type Options struct {
+	// Prefix KV 前缀，如 demo/config/
+	Prefix string
+	// CacheFile last-known-good 缓存文件，为空时不缓存
+	CacheFile string
+	// WaitTime 阻塞查询的最长等待时间，默认 5m
+	WaitTime time.Duration
+	// RetryInterval 查询失败后的重试间隔，默认 5s
+	RetryInterval time.Duration
+}
+
+// Provider 从 Consul KV 前缀读取配置并解码为 T，通过阻塞查询监听变更
+//
+// 前缀下的 key 按 / 拆分为嵌套结构，例如 demo/config/ws/tick_interval 对应 T.WS.TickInterval，
+// 缺失的 key 保留默认值。
+type Provider[T any] struct {
+	client   *capi.Client
+	opts     Options
+	defaults T
+
+	mu      sync.RWMutex
+	current T
+	raw     map[string]string
+	// index Load 得到的 X-Consul-Index，作为 Run 首次阻塞查询的 WaitIndex
+	index     uint64
+	callbacks []func(T)
+}
+
+// New 创建 Provider，defaults 为未在 KV 中声明的字段提供默认值
+func New[T any](client *capi.Client, defaults T, opts Options) *Provider[T] {
+	if opts.WaitTime <= 0 {
+		opts.WaitTime = 5 * time.Minute
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+	if opts.Prefix != "" && !strings.HasSuffix(opts.Prefix, "/") {
+		opts.Prefix += "/"
+	}
+	return &Provider[T]{
+		client:   client,
+		opts:     opts,
+		defaults: defaults,
+		current:  defaults,
+	}
+}
+
+// Current 返回当前配置
+func (p *Provider[T]) Current() T {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.current
+}
+
+// OnChange 注册配置变更回调，回调在 watch goroutine 中串行执行
+func (p *Provider[T]) OnChange(fn func(T)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.callbacks = append(p.callbacks, fn)
+}
+
+// Load 读取一次配置；Consul 不可用时回退到 last-known-good 缓存，两者都失败时保留默认值并返回错误
+func (p *Provider[T]) Load(ctx context.Context) error {
+	raw, meta, err := p.fetch((&capi.QueryOptions{}).WithContext(ctx))
+	if err == nil {
+		p.mu.Lock()
+		p.index = meta.LastIndex
+		p.mu.Unlock()
+		return p.apply(raw)
+	}
+
+	cached, cacheErr := p.readCache()
+	if cacheErr != nil {
+		return errors.Join(err, cacheErr)
+	}
+	slog.Warn("Consul KV unavailable, using last-known-good config",
+		slog.String("prefix", p.opts.Prefix), slog.String("cache_file", p.opts.CacheFile), logger.Err(err))
+	return p.apply(cached)
+}
+
+// Run 使用阻塞查询监听前缀变更，ctx 结束时返回
+func (p *Provider[T]) Run(ctx context.Context) {
+	p.mu.RLock()
+	index := p.index
+	p.mu.RUnlock()
+
+	consul.BlockingQuery(ctx, consul.WatchOptions{
+		Index:         index,
+		WaitTime:      p.opts.WaitTime,
+		RetryInterval: p.opts.RetryInterval,
+		OnError: func(err error) {
+			kvWatchErrors.WithLabelValues(p.opts.Prefix).Inc()
+			slog.Warn("Failed to watch Consul KV", slog.String("prefix", p.opts.Prefix), logger.Err(err))
+		},
+	}, func(q *capi.QueryOptions) (*capi.QueryMeta, error) {
+		raw, meta, err := p.fetch(q)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.apply(raw); err != nil {
+			slog.Error("Failed to decode Consul KV config, keeping previous", slog.String("prefix", p.opts.Prefix), logger.Err(err))
+		}
+		return meta, nil
+	})
+}
+
+// fetch 读取前缀下所有 key，返回相对前缀的扁平 key/value
+func (p *Provider[T]) fetch(q *capi.QueryOptions) (map[string]string, *capi.QueryMeta, error) {
+	pairs, meta, err := p.client.KV().List(p.opts.Prefix, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key := strings.TrimPrefix(pair.Key, p.opts.Prefix)
+		// 跳过 "目录" key
+		if key == "" || strings.HasSuffix(key, "/") {
+			continue
+		}
+		raw[key] = string(pair.Value)
+	}
+	return raw, meta, nil
+}
+
+// apply 解码并在内容变化时更新当前配置、写缓存并触发回调
+func (p *Provider[T]) apply(raw map[string]string) error {
+	p.mu.RLock()
+	unchanged := p.raw != nil && maps.Equal(p.raw, raw)
+	p.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	cfg, err := p.decode(raw)
+	if err != nil {
+		kvUpdates.WithLabelValues(p.opts.Prefix, "rejected").Inc()
+		return err
+	}
+	kvUpdates.WithLabelValues(p.opts.Prefix, "applied").Inc()
+
+	p.mu.Lock()
+	p.current = cfg
+	p.raw = raw
+	callbacks := append([]func(T){}, p.callbacks...)
+	p.mu.Unlock()
+
+	if err := p.writeCache(raw); err != nil {
+		slog.Warn("Failed to write KV config cache", slog.String("cache_file", p.opts.CacheFile), logger.Err(err))
+	}
+
+	slog.Info("Consul KV config updated", slog.String("prefix", p.opts.Prefix), slog.Int("keys", len(raw)))
+	for _, fn := range callbacks {
+		fn(cfg)
+	}
+	return nil
+}
+
+// decode 将扁平 key/value 还原为嵌套 map 后解码到默认值的副本上
+func (p *Provider[T]) decode(raw map[string]string) (T, error) {
+	tree := make(map[string]any)
+	for key, value := range raw {
+		parts := strings.Split(key, "/")
+		node := tree
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = value
+	}
+
+	cfg := p.defaults
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          TagName,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		Result: &cfg,
+	})
+	if err != nil {
+		return cfg, err
+	}
+	if err := decoder.Decode(tree); err != nil {
+		return cfg, fmt.Errorf("decode %s: %w", p.opts.Prefix, err)
+	}
+	return cfg, nil
+}
+
+func (p *Provider[T]) readCache() (map[string]string, error) {
+	if p.opts.CacheFile == "" {
+		return nil, errors.New("no cache file configured")
+	}
+	data, err := os.ReadFile(p.opts.CacheFile)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// writeCache 原子写入缓存：先写临时文件再 rename
+func (p *Provider[T]) writeCache(raw map[string]string) error {
+	if p.opts.CacheFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p.opts.CacheFile), filepath.Base(p.opts.CacheFile)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p.opts.CacheFile)
+}
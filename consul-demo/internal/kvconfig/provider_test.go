@@ -0,0 +1,76 @@
+package kvconfig
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/consul-demo/internal/consultest"
+)
+
+type testConfig struct {
+	WS struct {
+		TickInterval time.Duration `kv:"tick_interval"`
+	} `kv:"ws"`
+	Log struct {
+		Level string `kv:"level"`
+	} `kv:"log"`
+	Tags []string `kv:"tags"`
+}
+
+func put(t *testing.T, client *capi.Client, key, value string) {
+	t.Helper()
+	if _, err := client.KV().Put(&capi.KVPair{Key: key, Value: []byte(value)}, nil); err != nil {
+		t.Fatalf("写入 %s 失败: %v", key, err)
+	}
+}
+
+func TestProviderLoadAndWatch(t *testing.T) {
+	srv := consultest.NewServer(t)
+	client := srv.Client(t)
+	put(t, client, "demo/config/ws/tick_interval", "2s")
+	put(t, client, "demo/config/tags", "a,b")
+
+	var defaults testConfig
+	defaults.Log.Level = "info"
+	cache := filepath.Join(t.TempDir(), "cache.json")
+	p := New(client, defaults, Options{Prefix: "demo/config", CacheFile: cache})
+
+	if err := p.Load(context.Background()); err != nil {
+		t.Fatalf("加载失败: %v", err)
+	}
+	cur := p.Current()
+	if cur.WS.TickInterval != 2*time.Second || cur.Log.Level != "info" || len(cur.Tags) != 2 {
+		t.Fatalf("解码结果错误: %+v", cur)
+	}
+
+	changed := make(chan testConfig, 1)
+	p.OnChange(func(c testConfig) { changed <- c })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	put(t, client, "demo/config/log/level", "debug")
+	select {
+	case c := <-changed:
+		if c.Log.Level != "debug" {
+			t.Fatalf("变更未生效: %+v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("未收到变更回调")
+	}
+
+	// Consul 不可用时从缓存恢复
+	cancel()
+	srv.Close()
+	restored := New(client, defaults, Options{Prefix: "demo/config", CacheFile: cache})
+	if err := restored.Load(context.Background()); err != nil {
+		t.Fatalf("从缓存加载失败: %v", err)
+	}
+	if got := restored.Current(); got.Log.Level != "debug" || got.WS.TickInterval != 2*time.Second {
+		t.Fatalf("缓存内容错误: %+v", got)
+	}
+}
@@ -0,0 +1,7 @@
+// Package metrics 声明 consul-demo 各组件共用的指标子系统
+package metrics
+
+import "github.com/lyonmu/demo/base-demo/pkg/metrics"
+
+// Consul 服务注册、服务发现、KV 配置与选主共用的子系统，指标名为 demo_consul_<name>
+var Consul = metrics.NewSubsystem("consul")
@@ -0,0 +1,8 @@
+package registry
+
+import "github.com/lyonmu/demo/consul-demo/internal/metrics"
+
+var (
+	registrations   = metrics.Consul.CounterVec("registrations_total", "Total service registration attempts by result.", "result")
+	reregistrations = metrics.Consul.Counter("reregistrations_total", "Total times the service was found missing from the local agent and re-registered.")
+)
@@ -0,0 +1,135 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+)
+
+// Options 注册器参数
+type Options struct {
+	// InitialBackoff 注册失败后的首次重试间隔，默认 500ms
+	InitialBackoff time.Duration
+	// MaxBackoff 最大重试间隔，默认 30s
+	MaxBackoff time.Duration
+	// ReconcileInterval 检查本地 Agent 是否仍持有服务的间隔，默认 15s
+	ReconcileInterval time.Duration
+}
+
+// Registrar 负责服务注册、带退避的重试、定期校准以及退出时注销
+type Registrar struct {
+	client *capi.Client
+	reg    *capi.AgentServiceRegistration
+	opts   Options
+	log    *slog.Logger
+}
+
+// NewRegistrar 创建注册器
+func NewRegistrar(client *capi.Client, reg *capi.AgentServiceRegistration, opts Options) *Registrar {
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = 500 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	if opts.ReconcileInterval <= 0 {
+		opts.ReconcileInterval = 15 * time.Second
+	}
+	return &Registrar{
+		client: client,
+		reg:    reg,
+		opts:   opts,
+		log:    slog.With(slog.String("service_id", reg.ID)),
+	}
+}
+
+// ServiceID 返回注册的服务 ID
+func (r *Registrar) ServiceID() string {
+	return r.reg.ID
+}
+
+// Register 注册服务，失败时按指数退避重试，直到成功或 ctx 结束
+func (r *Registrar) Register(ctx context.Context) error {
+	backoff := r.opts.InitialBackoff
+	for {
+		err := r.register(ctx)
+		if err == nil {
+			registrations.WithLabelValues("success").Inc()
+			r.log.Info("Service registered", slog.String("address", r.reg.Address), slog.Int("port", r.reg.Port))
+			return nil
+		}
+		registrations.WithLabelValues("failure").Inc()
+		r.log.Warn("Failed to register service, retrying", slog.Duration("backoff", backoff), logger.Err(err))
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, r.opts.MaxBackoff)
+	}
+}
+
+// Deregister 从本地 Agent 注销服务
+func (r *Registrar) Deregister(ctx context.Context) error {
+	q := (&capi.QueryOptions{}).WithContext(ctx)
+	if err := r.client.Agent().ServiceDeregisterOpts(r.reg.ID, q); err != nil {
+		return err
+	}
+	r.log.Info("Service deregistered")
+	return nil
+}
+
+// Run 定期校准：本地 Agent 丢失服务（例如 Agent 重启）时重新注册，ctx 结束时返回
+func (r *Registrar) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		registered, err := r.registered(ctx)
+		if err != nil {
+			r.log.Warn("Failed to query local agent", logger.Err(err))
+			continue
+		}
+		if registered {
+			continue
+		}
+
+		reregistrations.Inc()
+		r.log.Warn("Service missing from local agent, re-registering")
+		if err := r.Register(ctx); err != nil && ctx.Err() == nil {
+			r.log.Error("Failed to re-register service", logger.Err(err))
+		}
+	}
+}
+
+func (r *Registrar) register(ctx context.Context) error {
+	opts := capi.ServiceRegisterOpts{ReplaceExistingChecks: true}.WithContext(ctx)
+	return r.client.Agent().ServiceRegisterOpts(r.reg, opts)
+}
+
+// registered 查询本地 Agent 是否仍持有该服务
+func (r *Registrar) registered(ctx context.Context) (bool, error) {
+	q := (&capi.QueryOptions{}).WithContext(ctx)
+	_, _, err := r.client.Agent().Service(r.reg.ID, q)
+	var statusErr capi.StatusError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
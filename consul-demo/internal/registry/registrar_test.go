@@ -0,0 +1,113 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/consul-demo/internal/config"
+	"github.com/lyonmu/demo/consul-demo/internal/consultest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newRegistration(t *testing.T) *capi.AgentServiceRegistration {
+	t.Helper()
+	reg, err := Build(config.Service{
+		Name:    "demo",
+		Address: "10.0.0.1",
+		Port:    8080,
+		Tags:    []string{"v1"},
+		Checks: []config.Check{
+			{Name: "ready", HTTP: "/demo/health/ready", Interval: "10s"},
+			{Name: "ttl", TTL: "15s"},
+		},
+	}, "")
+	if err != nil {
+		t.Fatalf("生成注册信息失败: %v", err)
+	}
+	return reg
+}
+
+func TestBuild(t *testing.T) {
+	reg := newRegistration(t)
+	if reg.ID != "10.0.0.1:8080" {
+		t.Fatalf("默认服务 ID 错误: %s", reg.ID)
+	}
+	if len(reg.Checks) != 2 {
+		t.Fatalf("检查数量错误: %d", len(reg.Checks))
+	}
+	if got := reg.Checks[0].HTTP; got != "http://10.0.0.1:8080/demo/health/ready" {
+		t.Fatalf("HTTP 检查地址错误: %s", got)
+	}
+	if got := reg.Checks[1].CheckID; got != "10.0.0.1:8080:ttl" {
+		t.Fatalf("默认 CheckID 错误: %s", got)
+	}
+	if reg.Meta["start_time"] == "" {
+		t.Fatalf("缺少 start_time 元数据")
+	}
+}
+
+func TestRegistrarLifecycle(t *testing.T) {
+	srv := consultest.NewServer(t)
+	reg := newRegistration(t)
+	r := NewRegistrar(srv.Client(t), reg, Options{ReconcileInterval: 20 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := r.Register(ctx); err != nil {
+		t.Fatalf("注册失败: %v", err)
+	}
+	if _, ok := srv.Services()[reg.ID]; !ok {
+		t.Fatalf("服务未注册到 Agent")
+	}
+	if _, ok := srv.Check("10.0.0.1:8080:ttl"); !ok {
+		t.Fatalf("TTL 检查未注册")
+	}
+
+	// Agent 重启丢失注册后由 Run 重新注册
+	before := testutil.ToFloat64(reregistrations)
+	go r.Run(ctx)
+	srv.RestartAgent()
+	waitFor(t, func() bool {
+		_, ok := srv.Services()[reg.ID]
+		return ok
+	})
+	if got := testutil.ToFloat64(reregistrations); got != before+1 {
+		t.Fatalf("重新注册计数错误: %v", got-before)
+	}
+
+	cancel()
+	if err := r.Deregister(context.Background()); err != nil {
+		t.Fatalf("注销失败: %v", err)
+	}
+	if len(srv.Services()) != 0 {
+		t.Fatalf("注销后服务仍存在")
+	}
+}
+
+func TestRegisterRetryUntilCancel(t *testing.T) {
+	srv := consultest.NewServer(t)
+	client := srv.Client(t)
+	srv.Close()
+
+	r := NewRegistrar(client, newRegistration(t), Options{InitialBackoff: 10 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := r.Register(ctx); err == nil {
+		t.Fatalf("Agent 不可用时注册应失败")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待条件超时")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,113 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/consul-demo/internal/config"
+)
+
+// Build 根据配置生成服务注册信息，agentAddr 用于在未配置通告地址时自动探测
+func Build(svc config.Service, agentAddr string) (*capi.AgentServiceRegistration, error) {
+	if svc.Name == "" {
+		return nil, errors.New("service name is required")
+	}
+	if svc.Port <= 0 {
+		return nil, fmt.Errorf("invalid service port %d", svc.Port)
+	}
+
+	addr := svc.Address
+	if addr == "" {
+		detected, err := AdvertiseAddr(agentAddr)
+		if err != nil {
+			return nil, fmt.Errorf("detect advertise address: %w", err)
+		}
+		addr = detected
+	}
+
+	id := svc.ID
+	if id == "" {
+		id = net.JoinHostPort(addr, strconv.Itoa(svc.Port))
+	}
+
+	meta := maps.Clone(svc.Meta)
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+	if _, ok := meta["start_time"]; !ok {
+		meta["start_time"] = time.Now().Format(time.DateTime)
+	}
+
+	reg := &capi.AgentServiceRegistration{
+		ID:      id,
+		Name:    svc.Name,
+		Address: addr,
+		Port:    svc.Port,
+		Tags:    svc.Tags,
+		Meta:    meta,
+	}
+
+	base := "http://" + net.JoinHostPort(addr, strconv.Itoa(svc.Port))
+	for i, c := range svc.Checks {
+		name := c.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		checkID := c.ID
+		if checkID == "" {
+			checkID = id + ":" + name
+		}
+
+		check := &capi.AgentServiceCheck{
+			CheckID:                        checkID,
+			Name:                           svc.Name + " " + name,
+			HTTP:                           c.HTTP,
+			Method:                         c.Method,
+			TCP:                            c.TCP,
+			GRPC:                           c.GRPC,
+			TTL:                            c.TTL,
+			Interval:                       c.Interval,
+			Timeout:                        c.Timeout,
+			Notes:                          c.Notes,
+			DeregisterCriticalServiceAfter: c.DeregisterCriticalServiceAfter,
+		}
+		if strings.HasPrefix(check.HTTP, "/") {
+			check.HTTP = base + check.HTTP
+		}
+		reg.Checks = append(reg.Checks, check)
+	}
+
+	return reg, nil
+}
+
+// AdvertiseAddr 探测访问 Consul Agent 时使用的本机地址
+//
+// 通过 UDP "连接" agent 地址让内核完成选路，不会真正发送数据。
+func AdvertiseAddr(agentAddr string) (string, error) {
+	host := agentAddr
+	if strings.Contains(host, "://") {
+		u, err := url.Parse(host)
+		if err != nil {
+			return "", err
+		}
+		host = u.Host
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "8500")
+	}
+
+	conn, err := net.Dial("udp", host)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
@@ -1,31 +1,49 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
 	capi "github.com/hashicorp/consul/api"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/collectors"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/base-demo/pkg/metrics"
+	"github.com/lyonmu/demo/base-demo/pkg/tracing"
+	"github.com/lyonmu/demo/consul-demo/internal/config"
+	"github.com/lyonmu/demo/consul-demo/internal/discovery"
+	"github.com/lyonmu/demo/consul-demo/internal/election"
+	"github.com/lyonmu/demo/consul-demo/internal/health"
+	"github.com/lyonmu/demo/consul-demo/internal/hub"
+	"github.com/lyonmu/demo/consul-demo/internal/kvconfig"
+	"github.com/lyonmu/demo/consul-demo/internal/registry"
 	"github.com/soheilhy/cmux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+// serviceName 链路追踪中的服务名
+const serviceName = "consul-demo"
+
 var (
 	ConsulClient *capi.Client
 	Router       *gin.Engine
-	// 存储所有活跃的 WebSocket 连接
-	Clients   = make(map[*websocket.Conn]bool)
-	Broadcast = make(chan Message)
+	// Hub 管理所有活跃的 WebSocket 连接
+	Hub = hub.New()
+	// Checker 健康探针注册表
+	Checker = health.New()
+	// Peers 通过 Consul 发现的对端实例
+	Peers *discovery.Resolver
+	// PeerClient 对端负载均衡 HTTP 客户端
+	PeerClient *http.Client
 )
 
 // Message 定义推送的消息结构体
@@ -44,83 +62,23 @@ type DataInfo struct {
 	Count  int     `json:"count"`
 }
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		// 允许所有来源的连接，生产环境应该检查具体的来源
-		return true
-	},
-}
-
-// handleWebSocket 处理 WebSocket 连接
-func handleWebSocket(c *gin.Context) {
-	// 升级 HTTP 连接为 WebSocket
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
-		return
-	}
-	defer conn.Close()
-
-	// 注册新客户端
-	Clients[conn] = true
-	log.Printf("New client connected. Total clients: %d", len(Clients))
-
-	// 启动一个 goroutine 来处理从客户端接收的消息
-	go handleClientMessages(conn)
-
-	// 保持连接活跃，等待客户端断开
-	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			log.Printf("Client disconnected: %v", err)
-			delete(Clients, conn)
-			break
-		}
-	}
-}
-
-// handleClientMessages 处理来自客户端的消息
-func handleClientMessages(conn *websocket.Conn) {
-	for {
-		_, message, err := conn.ReadMessage()
-		if err != nil {
-			log.Printf("Read message error: %v", err)
-			break
-		}
-		log.Printf("Received from client: %s", message)
-		// 这里可以处理客户端发送的消息
-	}
-}
+// startTimer 启动定时推送任务，推送间隔可通过 intervals 在运行时调整
+func startTimer(ctx context.Context, interval time.Duration, intervals <-chan time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-// broadcastMessage 向所有客户端广播消息
-func broadcastMessage() {
+	messageID := 0
 	for {
-		message := <-Broadcast
-		messageJSON, err := json.Marshal(message)
-		if err != nil {
-			log.Printf("JSON marshal error: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-intervals:
+			ticker.Reset(d)
+			slog.Info("Tick interval updated", slog.Duration("interval", d))
 			continue
+		case <-ticker.C:
 		}
 
-		// 向所有客户端发送消息
-		for client := range Clients {
-			err := client.WriteMessage(websocket.TextMessage, messageJSON)
-			if err != nil {
-				log.Printf("Write message error: %v", err)
-				client.Close()
-				delete(Clients, client)
-			}
-		}
-	}
-}
-
-// startTimer 启动定时推送任务
-func startTimer() {
-	ticker := time.NewTicker(1 * time.Second) // 每 1 秒推送一次
-	defer ticker.Stop()
-
-	messageID := 0
-	for range ticker.C {
 		messageID++
 		message := Message{
 			ID:        messageID,
@@ -133,43 +91,92 @@ func startTimer() {
 				Count:  messageID * 10,
 			},
 		}
-		Broadcast <- message
-		log.Printf("Broadcasted message ID: %d to %d clients", messageID, len(Clients))
+		if err := Hub.Broadcast(ctx, message); err != nil {
+			return
+		}
+		slog.Debug("Broadcasted message", slog.Int("id", messageID), slog.Int("clients", Hub.Clients()))
 	}
 }
 
-func initConsul() error {
+func initConsul(cfg config.Consul) error {
 
-	config := capi.DefaultConfig()
-	config.Address = "192.168.100.156:8500"
-	config.Token = "0q8Rz0ElbVb2uRsTc4Cj3LTAnwwX4FhmSFAvblwToMw"
-	client, err := capi.NewClient(config)
+	apiCfg := cfg.APIConfig()
+	client, err := capi.NewClient(apiCfg)
 	if err != nil {
 		return err
 	}
+	// NewClient 按地址（含 unix://）与 TLS 配置创建了 HttpClient，客户端与 apiCfg 共享该指针，
+	// 在其 Transport 外包装 otelhttp，所有 Consul API 调用记录客户端 span 并注入 traceparent
+	apiCfg.HttpClient.Transport = otelhttp.NewTransport(apiCfg.HttpClient.Transport,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "consul " + r.Method + " " + r.URL.Path
+		}))
 	ConsulClient = client
 	return nil
 
 }
 
-func RegisterMetrics(engine *gin.Engine) error {
-	reg := prometheus.NewRegistry()
-	collectorsList := []prometheus.Collector{
-		collectors.NewGoCollector(),
-		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+// initDynamicConfig 加载 Consul KV 中的运行时配置并注册变更回调
+func initDynamicConfig(ctx context.Context, cfg config.KV, intervals chan time.Duration) *kvconfig.Provider[config.Dynamic] {
+	provider := kvconfig.New(ConsulClient, config.DefaultDynamic(), kvconfig.Options{
+		Prefix:    cfg.Prefix,
+		CacheFile: cfg.CacheFile,
+	})
+
+	provider.OnChange(func(d config.Dynamic) {
+		if d.Log.Level != "" {
+			if err := logger.SetLevel(d.Log.Level); err != nil {
+				slog.Warn("Invalid log level in KV config", slog.String("level", d.Log.Level), logger.Err(err))
+			}
+		}
+		if d.WS.TickInterval > 0 {
+			// 只保留最新的间隔
+			select {
+			case <-intervals:
+			default:
+			}
+			intervals <- d.WS.TickInterval
+		}
+	})
+
+	if err := provider.Load(ctx); err != nil {
+		slog.Warn("Failed to load dynamic config, using defaults", logger.Err(err))
 	}
-	for _, v := range collectorsList {
-		if err := reg.Register(v); err != nil {
-			return err
+	return provider
+}
+
+// initDiscovery 启动服务发现并创建负载均衡客户端
+func initDiscovery(ctx context.Context, cfg config.Discovery) {
+	Peers = discovery.NewResolver(ConsulClient, cfg.Service, discovery.ResolverOptions{Tags: cfg.Tags})
+	// 链路追踪包装在负载均衡之下，每次尝试以实际选中的实例地址记录客户端 span
+	PeerClient = discovery.NewClient(Peers, discovery.Options{Strategy: discovery.Strategy(cfg.Strategy), Base: tracing.Transport(nil)})
+	PeerClient.Timeout = 5 * time.Second
+	go Peers.Run(ctx)
+}
+
+// handlePeers 列出对端实例，并通过负载均衡客户端调用其中一个的存活接口
+func handlePeers(c *gin.Context) {
+	result := gin.H{"service": Peers.Service(), "instances": Peers.Instances()}
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, "http://"+Peers.Service()+"/demo/health/live", nil)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = PeerClient.Do(req); err == nil {
+			resp.Body.Close()
+			result["probe_status"] = resp.StatusCode
 		}
 	}
+	if err != nil {
+		result["probe_error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, result)
+}
 
-	engine.GET("/metrics", gin.WrapH(promhttp.HandlerFor(reg, promhttp.HandlerOpts{
-		ErrorHandling:     promhttp.ContinueOnError,
-		EnableOpenMetrics: true,
-		Registry:          reg,
-	})))
-	return nil
+// initHealth 注册健康探针
+func initHealth(cfg config.Health) {
+	Checker.Register("consul", health.Readiness, health.ConsulProbe(ConsulClient))
+	Checker.Register("websocket_hub", health.Liveness|health.Readiness, Hub.Probe(5*time.Second))
+	Checker.Register("disk", health.Readiness, health.DiskProbe(cfg.DiskPath, cfg.DiskWarnFreeMB<<20, cfg.DiskFailFreeMB<<20))
 }
 
 func initGin() {
@@ -177,69 +184,95 @@ func initGin() {
 	gin.SetMode(gin.DebugMode)
 
 	router := gin.New()
-	router.Use(gin.Logger())
+	router.Use(tracing.GinMiddleware(serviceName))
+	router.Use(metrics.GinMiddleware(metrics.MiddlewareOptions{}))
+	router.Use(logger.GinMiddleware(slog.Default()))
 	router.Use(gin.ErrorLogger())
 	router.Use(gin.Recovery())
 	router.Use(cors.Default())
 
 	// Router group
 	RouterGroup := router.Group("demo")
-	RouterGroup.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"message": "ok"})
-	})
-	RouterGroup.GET("/ws", handleWebSocket)
-	if err := RegisterMetrics(router); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to register metrics: %v", err)
-		os.Exit(1)
-	}
+	Checker.Mount(RouterGroup)
+	RouterGroup.GET("/ws", Hub.ServeWS)
+	RouterGroup.GET("/peers", handlePeers)
+	metrics.RegisterMetrics(router)
 	Router = router
 
 }
 
+// startTTLReporters 为注册信息中声明了 TTL 的检查启动上报
+func startTTLReporters(ctx context.Context, reg *capi.AgentServiceRegistration) {
+	for _, check := range reg.Checks {
+		if check.TTL == "" {
+			continue
+		}
+		ttl, err := time.ParseDuration(check.TTL)
+		if err != nil {
+			slog.Error("Invalid TTL check", slog.String("check_id", check.CheckID), logger.Err(err))
+			continue
+		}
+		go health.NewTTLReporter(Checker, ConsulClient, check.CheckID, ttl/3).Run(ctx)
+	}
+}
+
 func main() {
 
-	if err := initConsul(); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to initialize Consul: %v", err)
+	configFile := flag.String("config", "", "config file path (default $DEMO_CONFIG or config.yaml)")
+	flag.Parse()
+
+	logger.Init(logger.OptionsFromEnv())
+
+	traceOpts, err := tracing.OptionsFromEnv()
+	if err != nil {
+		slog.Error("Invalid tracing config", logger.Err(err))
 		os.Exit(1)
 	}
+	shutdownTracing, err := tracing.Init(context.Background(), serviceName, traceOpts)
+	if err != nil {
+		slog.Error("Failed to init tracing", logger.Err(err))
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		slog.Error("Failed to load config", logger.Err(err))
+		os.Exit(1)
+	}
+
+	if err := initConsul(cfg.Consul); err != nil {
+		slog.Error("Failed to initialize Consul", logger.Err(err))
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
+	intervals := make(chan time.Duration, 1)
+	dynamic := initDynamicConfig(ctx, cfg.KV, intervals)
+	go dynamic.Run(ctx)
+
+	initDiscovery(ctx, cfg.Discovery)
+	initHealth(cfg.Health)
 	initGin()
-	go broadcastMessage()
-	go startTimer()
+	go Hub.Run(ctx)
 
 	// Main listener
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", 8080))
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.HTTP.Port))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to listen: %v", err)
+		slog.Error("Failed to listen", logger.Err(err))
 		os.Exit(1)
 	}
 
 	// Create a cmux.
 	m := cmux.New(listener)
 
-	// http://127.0.0.1:20416/demo/health
-	regEnvoy := &capi.AgentServiceRegistration{
-		Name:    "demo",
-		Port:    8080,
-		Address: "192.168.8.24",
-		ID:      fmt.Sprintf("%s:%d", "192.168.8.24", 8080),
-		Check: &capi.AgentServiceCheck{
-			HTTP:                           fmt.Sprintf("http://%s:%d/demo/health", "192.168.8.24", 8080),
-			Interval:                       "10s",
-			Timeout:                        "5s",
-			DeregisterCriticalServiceAfter: "10s",
-		},
-		Tags: []string{"demo", "sentinel"},
-		Meta: map[string]string{
-			"version":       "1.0.0",
-			"start_time":    time.Now().Format(time.DateTime),
-			"router_prefix": "demo",
-			"no_auth":       "false",
-		},
+	regEnvoy, err := registry.Build(cfg.Service, cfg.Consul.APIConfig().Address)
+	if err != nil {
+		slog.Error("Failed to build service registration", logger.Err(err))
+		os.Exit(1)
 	}
 
-	ConsulClient.Agent().ServiceRegister(regEnvoy)
-
 	httpL := m.Match(cmux.HTTP1Fast())
 
 	// Create HTTP server with gin router
@@ -250,14 +283,72 @@ func main() {
 	// Run HTTP server in a goroutine to avoid blocking
 	go func() {
 		if err := httpServer.Serve(httpL); err != nil && err != http.ErrServerClosed {
-			fmt.Fprintf(os.Stderr, "Failed to serve HTTP: %v", err)
+			slog.Error("Failed to serve HTTP", logger.Err(err))
+		}
+	}()
+
+	// Start serving! cmux 在 listener 关闭后返回
+	go func() {
+		if err := m.Serve(); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			slog.Error("Failed to serve", logger.Err(err))
+			os.Exit(1)
 		}
 	}()
 
-	// Start serving! This will block and handle all connections
-	if err := m.Serve(); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
-		fmt.Fprintf(os.Stderr, "Failed to serve: %v", err)
+	// 只有 leader 运行定时推送，避免多实例重复广播
+	elector, err := election.New(ConsulClient, election.Options{
+		Key:        cfg.Election.Key,
+		Value:      []byte(regEnvoy.ID),
+		SessionTTL: cfg.Election.SessionTTL,
+		OnElected: func(leaderCtx context.Context) {
+			startTimer(leaderCtx, dynamic.Current().WS.TickInterval, intervals)
+		},
+	})
+	if err != nil {
+		slog.Error("Failed to create leader elector", logger.Err(err))
 		os.Exit(1)
 	}
+	electionDone := make(chan struct{})
+	go func() {
+		defer close(electionDone)
+		elector.Run(ctx)
+	}()
 
+	// 服务已开始监听后再注册，注册失败按退避重试；后台校准 Agent 重启后丢失的注册
+	registrar := registry.NewRegistrar(ConsulClient, regEnvoy, registry.Options{})
+	go func() {
+		if err := registrar.Register(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to register service", logger.Err(err))
+		}
+		startTTLReporters(ctx, regEnvoy)
+		registrar.Run(ctx)
+	}()
+
+	<-ctx.Done()
+	stop()
+	slog.Info("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// 等待让出 leader（停止定时推送并释放锁），其他实例可以尽快接管
+	select {
+	case <-electionDone:
+	case <-shutdownCtx.Done():
+	}
+
+	// 先从 Consul 注销，避免流量继续打到正在关闭的实例
+	if err := registrar.Deregister(shutdownCtx); err != nil {
+		slog.Error("Failed to deregister service", logger.Err(err))
+	}
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shutdown HTTP server", logger.Err(err))
+	}
+	m.Close()
+
+	// 注销与关闭过程中的 span 也需要导出
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", logger.Err(err))
+	}
 }
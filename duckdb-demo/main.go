@@ -1,41 +1,104 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/base-demo/pkg/tracing"
+	"go.opentelemetry.io/otel"
 
 	_ "github.com/duckdb/duckdb-go/v2"
 )
 
 func main() {
-	db, err := sql.Open("duckdb", "./test.db")
+	logger.Init(logger.OptionsFromEnv())
+
+	traceOpts, err := tracing.OptionsFromEnv()
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("Invalid tracing config", logger.Err(err))
+		os.Exit(1)
+	}
+	shutdownTracing, err := tracing.Init(context.Background(), "duckdb-demo", traceOpts)
+	if err != nil {
+		slog.Error("Failed to init tracing", logger.Err(err))
+		os.Exit(1)
 	}
-	defer db.Close()
 
-	_, err = db.Exec(`CREATE TABLE people (id INTEGER, name VARCHAR)`)
+	err = run(context.Background())
+
+	// os.Exit 不会执行 defer，退出前显式刷新 span
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", logger.Err(err))
+	}
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("DuckDB demo failed", logger.Err(err))
+		os.Exit(1)
 	}
-	_, err = db.Exec(`INSERT INTO people VALUES (42, 'John')`)
+}
+
+// run 建表、插入并查询一行数据，每条 SQL 都在 run 的根 span 下创建一个数据库 span
+func run(ctx context.Context) error {
+	ctx, span := otel.Tracer("github.com/lyonmu/demo/duckdb-demo").Start(ctx, "run")
+	defer span.End()
+
+	db, err := sql.Open("duckdb", "./test.db")
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	defer db.Close()
+
+	if err := exec(ctx, db, "CREATE TABLE", `CREATE TABLE people (id INTEGER, name VARCHAR)`); err != nil {
+		return err
+	}
+	if err := exec(ctx, db, "INSERT", `INSERT INTO people VALUES (42, 'John')`); err != nil {
+		return err
 	}
 
-	var (
-		id   int
-		name string
-	)
-	row := db.QueryRow(`SELECT id, name FROM people`)
-	err = row.Scan(&id, &name)
+	id, name, err := queryPerson(ctx, db)
 	if errors.Is(err, sql.ErrNoRows) {
-		log.Println("no rows")
+		slog.Info("no rows")
 	} else if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	fmt.Printf("id: %d, name: %s\n", id, name)
+	return nil
+}
+
+// exec 执行 people 表上的一条语句，span 记录受影响的行数
+func exec(ctx context.Context, db *sql.DB, operation, query string) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "duckdb", operation, "people", query)
+	var n int64
+	defer func() { tracing.EndQuery(span, int(n), err) }()
+
+	res, err := db.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	n, err = res.RowsAffected()
+	return err
+}
+
+// queryPerson 查询 people 表的一行数据，没有数据时返回 sql.ErrNoRows 且不视为 span 错误
+func queryPerson(ctx context.Context, db *sql.DB) (id int, name string, err error) {
+	const query = `SELECT id, name FROM people`
+	ctx, span := tracing.StartQuery(ctx, "duckdb", "SELECT", "people", query)
+	err = db.QueryRowContext(ctx, query).Scan(&id, &name)
+	switch {
+	case err == nil:
+		tracing.EndQuery(span, 1, nil)
+	case errors.Is(err, sql.ErrNoRows):
+		tracing.EndQuery(span, 0, nil)
+	default:
+		tracing.EndQuery(span, 0, err)
+	}
+	return id, name, err
 }
@@ -0,0 +1,100 @@
+// als 实现 Envoy gRPC 访问日志服务（envoy.service.accesslog.v3.AccessLogService），
+// 将 HTTP / TCP 访问日志写入 DuckDB，并提供 HTTP 查询接口
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/base-demo/pkg/tracing"
+	"github.com/lyonmu/demo/envoy-demo/internal/als"
+)
+
+func main() {
+	dbFile := flag.String("db", envOr("ALS_DB", "als.duckdb"), "DuckDB database file, empty for in-memory")
+	grpcListen := flag.String("grpc", ":18090", "gRPC access log service listen address")
+	httpListen := flag.String("http", ":18091", "HTTP query API listen address")
+	retention := flag.Duration("retention", 24*time.Hour, "drop access logs older than this, 0 to keep all")
+	flag.Parse()
+
+	logger.Init(logger.OptionsFromEnv())
+
+	traceOpts, err := tracing.OptionsFromEnv()
+	if err != nil {
+		slog.Error("Invalid tracing config", logger.Err(err))
+		os.Exit(1)
+	}
+	shutdownTracing, err := tracing.Init(context.Background(), "als", traceOpts)
+	if err != nil {
+		slog.Error("Failed to init tracing", logger.Err(err))
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Failed to flush traces", logger.Err(err))
+		}
+	}()
+
+	store, err := als.Open(*dbFile)
+	if err != nil {
+		slog.Error("Failed to open access log database", slog.String("db", *dbFile), logger.Err(err))
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *retention > 0 {
+		go prune(ctx, store, *retention)
+	}
+
+	go func() {
+		if err := als.ListenHTTP(ctx, *httpListen, store); err != nil {
+			slog.Error("Access log query API failed", logger.Err(err))
+			stop()
+		}
+	}()
+
+	if err := als.ListenGRPC(ctx, *grpcListen, als.NewServer(store)); err != nil {
+		slog.Error("Access log server failed", logger.Err(err))
+		os.Exit(1)
+	}
+	slog.Info("Shutdown complete")
+}
+
+// prune 定期删除超过保留时长的日志
+func prune(ctx context.Context, store *als.Store, retention time.Duration) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := store.Prune(ctx, time.Now().Add(-retention))
+			if err != nil {
+				slog.Warn("Failed to prune access logs", logger.Err(err))
+				continue
+			}
+			if n > 0 {
+				slog.Info("Pruned access logs", slog.Int64("count", n))
+			}
+		}
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
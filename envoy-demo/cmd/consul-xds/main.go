@@ -0,0 +1,51 @@
+// consul-xds 监听 Consul 服务目录，通过 ADS 向 Envoy 下发 CDS / EDS / RDS
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/envoy-demo/internal/consulxds"
+	"github.com/lyonmu/demo/envoy-demo/internal/xds"
+)
+
+func main() {
+	configFile := flag.String("config", "", "config file path (default $CONSUL_XDS_CONFIG)")
+	flag.Parse()
+
+	logger.Init(logger.OptionsFromEnv())
+
+	cfg, err := consulxds.LoadConfig(*configFile)
+	if err != nil {
+		slog.Error("Failed to load config", logger.Err(err))
+		os.Exit(1)
+	}
+
+	client, err := capi.NewClient(cfg.Consul.APIConfig())
+	if err != nil {
+		slog.Error("Failed to create Consul client", logger.Err(err))
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	watcher := consulxds.NewWatcher(client, consulxds.WatchOptions{Tag: cfg.Watch.Tag})
+	go watcher.Run(ctx)
+
+	snapshots := xds.NewCache()
+	pub := xds.NewPublisher(snapshots, cfg.XDS.NodeCluster)
+	go consulxds.Sync(ctx, watcher, pub, cfg.Routes.BuildOptions(), cfg.XDS.Debounce)
+
+	if err := xds.Serve(ctx, cfg.XDS.Listen, snapshots); err != nil {
+		slog.Error("xDS server failed", logger.Err(err))
+		os.Exit(1)
+	}
+	slog.Info("Shutdown complete")
+}
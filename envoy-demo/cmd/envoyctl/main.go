@@ -0,0 +1,114 @@
+// envoyctl 维护手写的 Envoy 静态配置：
+//
+//	envoyctl render -spec proxies.yaml -o envoy.yaml   根据代理声明生成静态 bootstrap
+//	envoyctl lint envoy.yaml [...]                       检查已有 bootstrap，存在 error 时退出码为 1
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lyonmu/demo/envoy-demo/internal/bootstrap"
+	"github.com/lyonmu/demo/envoy-demo/internal/proxyspec"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	var code int
+	switch os.Args[1] {
+	case "render":
+		code = render(os.Args[2:])
+	case "lint":
+		code = lint(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		usage(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage(os.Stderr)
+		code = 2
+	}
+	os.Exit(code)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage:")
+	fmt.Fprintln(w, "  envoyctl render -spec proxies.yaml [-o envoy.yaml] [-path-map from=to]")
+	fmt.Fprintln(w, "  envoyctl lint [-path-map from=to] envoy.yaml...")
+}
+
+func render(args []string) int {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	specFile := fs.String("spec", "proxies.yaml", "proxy declaration file")
+	out := fs.String("o", "", "output file, stdout when empty")
+	nodeID := fs.String("node-id", "", "node.id of the rendered bootstrap")
+	nodeCluster := fs.String("node-cluster", "", "node.cluster of the rendered bootstrap")
+	adminPort := fs.Uint("admin-port", 9901, "admin listener port, 0 disables admin")
+	var paths bootstrap.PathMap
+	fs.Var(&paths, "path-map", "rewrite certificate path prefix, from=to (repeatable)")
+	_ = fs.Parse(args)
+
+	spec, err := proxyspec.Load(*specFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	data, err := bootstrap.Render(spec, bootstrap.RenderOptions{
+		NodeID:      *nodeID,
+		NodeCluster: *nodeCluster,
+		AdminPort:   uint32(*adminPort),
+		PathMap:     paths,
+		Source:      *specFile,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*out, data, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func lint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	var paths bootstrap.PathMap
+	fs.Var(&paths, "path-map", "map a path prefix in the config to a local one before checking, from=to (repeatable)")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "no files to lint")
+		return 2
+	}
+
+	code := 0
+	for _, file := range fs.Args() {
+		findings, err := bootstrap.LintFile(file, bootstrap.LintOptions{PathMap: paths})
+		if err != nil {
+			fmt.Printf("%s: error: %v\n", file, err)
+			code = 1
+			continue
+		}
+		for _, f := range findings {
+			fmt.Printf("%s: %s\n", file, f)
+			if f.Severity == bootstrap.SeverityError {
+				code = 1
+			}
+		}
+		if len(findings) == 0 {
+			fmt.Printf("%s: ok\n", file)
+		}
+	}
+	return code
+}
@@ -0,0 +1,50 @@
+// ext-authz 实现 Envoy 外部鉴权服务（gRPC envoy.service.auth.v3.Authorization 与 HTTP 两种协议）
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/envoy-demo/internal/extauthz"
+)
+
+func main() {
+	configFile := flag.String("config", "", "config file path (default $EXT_AUTHZ_CONFIG)")
+	flag.Parse()
+
+	logger.Init(logger.OptionsFromEnv())
+
+	cfg, err := extauthz.LoadConfig(*configFile)
+	if err != nil {
+		slog.Error("Failed to load config", logger.Err(err))
+		os.Exit(1)
+	}
+	authz, err := extauthz.NewAuthorizer(cfg)
+	if err != nil {
+		slog.Error("Invalid config", logger.Err(err))
+		os.Exit(1)
+	}
+	srv := extauthz.NewServer(authz)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.HTTPListen != "" {
+		go func() {
+			if err := extauthz.ListenHTTP(ctx, cfg.HTTPListen, srv); err != nil {
+				slog.Error("ext_authz HTTP server failed", logger.Err(err))
+				stop()
+			}
+		}()
+	}
+	if err := extauthz.ListenGRPC(ctx, cfg.GRPCListen, srv); err != nil {
+		slog.Error("ext_authz gRPC server failed", logger.Err(err))
+		os.Exit(1)
+	}
+	slog.Info("Shutdown complete")
+}
@@ -0,0 +1,81 @@
+// proxy-xds 读取 L4/L7 代理声明文件，通过 ADS 向 Envoy 下发 LDS / CDS / SDS，文件变化后自动生成新版本
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/envoy-demo/internal/proxyspec"
+	"github.com/lyonmu/demo/envoy-demo/internal/xds"
+)
+
+func main() {
+	specFile := flag.String("spec", envOr("PROXY_SPEC", "proxies.yaml"), "proxy declaration file")
+	listen := flag.String("listen", ":18001", "xDS gRPC listen address")
+	nodeCluster := flag.String("node-cluster", "l4-l7-proxy", "Envoy node.cluster to serve")
+	interval := flag.Duration("interval", 2*time.Second, "file change polling interval")
+	flag.Parse()
+
+	logger.Init(logger.OptionsFromEnv())
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	snapshots := xds.NewCache()
+	pub := xds.NewPublisher(snapshots, *nodeCluster)
+
+	// 首次加载成功后才开始监听，避免 Envoy 拿到空配置
+	loaded := make(chan struct{})
+	go proxyspec.Watch(ctx, *specFile, *interval, func(spec proxyspec.Spec, err error) {
+		if err != nil {
+			slog.Error("Failed to load proxy spec, keeping previous snapshot", slog.String("file", *specFile), logger.Err(err))
+			return
+		}
+		resources, err := proxyspec.Build(spec, proxyspec.BuildOptions{SDS: true})
+		if err != nil {
+			slog.Error("Failed to build resources, keeping previous snapshot", logger.Err(err))
+			return
+		}
+		version, changed, err := pub.Publish(ctx, resources)
+		if err != nil {
+			slog.Error("Failed to publish xDS snapshot", logger.Err(err))
+			return
+		}
+		if changed {
+			slog.Info("xDS snapshot published",
+				slog.String("version", version),
+				slog.Int("proxies", len(spec.Proxies)),
+				slog.Int("certificates", len(spec.Certificates)))
+		}
+		select {
+		case <-loaded:
+		default:
+			close(loaded)
+		}
+	})
+
+	select {
+	case <-loaded:
+	case <-ctx.Done():
+		return
+	}
+
+	if err := xds.Serve(ctx, *listen, snapshots); err != nil {
+		slog.Error("xDS server failed", logger.Err(err))
+		os.Exit(1)
+	}
+	slog.Info("Shutdown complete")
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
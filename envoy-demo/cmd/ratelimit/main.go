@@ -0,0 +1,60 @@
+// ratelimit 实现 Envoy 全局限流服务（envoy.service.ratelimit.v3.RateLimitService），限额配置文件变化后自动生效
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/envoy-demo/internal/config"
+	"github.com/lyonmu/demo/envoy-demo/internal/ratelimit"
+)
+
+func main() {
+	configFile := flag.String("config", envOr("RATELIMIT_CONFIG", "ratelimit.yaml"), "rate limit config file")
+	listen := flag.String("listen", ":8081", "gRPC listen address")
+	interval := flag.Duration("interval", 2*time.Second, "config file polling interval")
+	flag.Parse()
+
+	logger.Init(logger.OptionsFromEnv())
+
+	// 首次加载失败直接退出，之后加载失败时保留上一版限额
+	cfg, err := ratelimit.LoadConfig(*configFile)
+	if err != nil {
+		slog.Error("Failed to load rate limit config", logger.Err(err))
+		os.Exit(1)
+	}
+	limiter := ratelimit.NewLimiter(cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go config.Watch(ctx, *configFile, *interval, func() []string {
+		cfg, err := ratelimit.LoadConfig(*configFile)
+		if err != nil {
+			slog.Error("Failed to reload rate limit config, keeping previous limits", logger.Err(err))
+			return nil
+		}
+		limiter.Update(cfg)
+		slog.Info("Rate limit config loaded", slog.String("file", *configFile), slog.Int("domains", len(cfg.Domains)))
+		return nil
+	})
+
+	if err := ratelimit.ListenGRPC(ctx, *listen, ratelimit.NewServer(limiter)); err != nil {
+		slog.Error("Rate limit server failed", logger.Err(err))
+		os.Exit(1)
+	}
+	slog.Info("Shutdown complete")
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,234 @@
+package als
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	accesslogdatav3 "github.com/envoyproxy/go-control-plane/envoy/data/accesslog/v3"
+	alsv3 "github.com/envoyproxy/go-control-plane/envoy/service/accesslog/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// fakeStream 依次返回预设消息，之后返回 io.EOF
+type fakeStream struct {
+	grpc.ServerStream
+	msgs []*alsv3.StreamAccessLogsMessage
+}
+
+func (s *fakeStream) Context() context.Context { return context.Background() }
+
+func (s *fakeStream) Recv() (*alsv3.StreamAccessLogsMessage, error) {
+	if len(s.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return msg, nil
+}
+
+func (s *fakeStream) SendAndClose(*alsv3.StreamAccessLogsResponse) error { return nil }
+
+func socket(host string, port uint32) *corev3.Address {
+	return &corev3.Address{Address: &corev3.Address_SocketAddress{SocketAddress: &corev3.SocketAddress{
+		Address: host, PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: port},
+	}}}
+}
+
+func common(upstream string, d time.Duration) *accesslogdatav3.AccessLogCommon {
+	return &accesslogdatav3.AccessLogCommon{
+		StartTime:               timestamppb.Now(),
+		Duration:                durationpb.New(d),
+		DownstreamLocalAddress:  socket("0.0.0.0", 10000),
+		DownstreamRemoteAddress: socket("10.0.0.1", 50000),
+		UpstreamRemoteAddress:   socket(upstream, 8080),
+		UpstreamCluster:         "web",
+	}
+}
+
+func httpEntry(upstream string, status uint32, d time.Duration) *accesslogdatav3.HTTPAccessLogEntry {
+	return &accesslogdatav3.HTTPAccessLogEntry{
+		CommonProperties: common(upstream, d),
+		Request:          &accesslogdatav3.HTTPRequestProperties{RequestMethod: corev3.RequestMethod_GET, Path: "/"},
+		Response:         &accesslogdatav3.HTTPResponseProperties{ResponseCode: wrapperspb.UInt32(status)},
+	}
+}
+
+// newTestStore 写入 http 监听器的 100 条日志（耗时 1..100ms，10 条 503）与 tcp 监听器的 2 条日志
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open("")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	var entries []*accesslogdatav3.HTTPAccessLogEntry
+	for i := 1; i <= 100; i++ {
+		upstream, code := "10.1.0.1", uint32(200)
+		if i%4 == 0 {
+			upstream = "10.1.0.2"
+		}
+		if i%10 == 0 {
+			code = 503
+		}
+		entries = append(entries, httpEntry(upstream, code, time.Duration(i)*time.Millisecond))
+	}
+	intermediate := httpEntry("10.1.0.9", 200, time.Second)
+	intermediate.CommonProperties.IntermediateLogEntry = true
+	entries = append(entries, intermediate)
+
+	tcp := &accesslogdatav3.TCPAccessLogEntry{
+		CommonProperties:     common("10.2.0.1", 2*time.Second),
+		ConnectionProperties: &accesslogdatav3.ConnectionProperties{ReceivedBytes: 10, SentBytes: 20},
+	}
+
+	s := NewServer(store)
+	streams := []*fakeStream{
+		{msgs: []*alsv3.StreamAccessLogsMessage{
+			{
+				Identifier: &alsv3.StreamAccessLogsMessage_Identifier{Node: &corev3.Node{Id: "envoy-1"}, LogName: "http"},
+				LogEntries: &alsv3.StreamAccessLogsMessage_HttpLogs{HttpLogs: &alsv3.StreamAccessLogsMessage_HTTPAccessLogEntries{LogEntry: entries[:50]}},
+			},
+			// 后续消息不带 identifier
+			{LogEntries: &alsv3.StreamAccessLogsMessage_HttpLogs{HttpLogs: &alsv3.StreamAccessLogsMessage_HTTPAccessLogEntries{LogEntry: entries[50:]}}},
+		}},
+		{msgs: []*alsv3.StreamAccessLogsMessage{{
+			Identifier: &alsv3.StreamAccessLogsMessage_Identifier{Node: &corev3.Node{Id: "envoy-1"}, LogName: "tcp"},
+			LogEntries: &alsv3.StreamAccessLogsMessage_TcpLogs{TcpLogs: &alsv3.StreamAccessLogsMessage_TCPAccessLogEntries{
+				LogEntry: []*accesslogdatav3.TCPAccessLogEntry{tcp, tcp},
+			}},
+		}}},
+	}
+	for _, stream := range streams {
+		if err := s.StreamAccessLogs(stream); err != nil {
+			t.Fatalf("接收日志失败: %v", err)
+		}
+	}
+	return store
+}
+
+func TestStatusCodes(t *testing.T) {
+	store := newTestStore(t)
+	got, err := store.StatusCodes(context.Background(), Filter{Since: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StatusCount{{"http", 200, 90}, {"http", 503, 10}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("状态码统计错误: %+v", got)
+	}
+
+	got, _ = store.StatusCodes(context.Background(), Filter{Since: time.Now().Add(time.Minute)})
+	if len(got) != 0 {
+		t.Fatalf("时间范围之外不应有数据: %+v", got)
+	}
+}
+
+func TestLatencies(t *testing.T) {
+	store := newTestStore(t)
+	got, err := store.Latencies(context.Background(), Filter{Since: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Listener != "http" || got[1].Listener != "tcp" {
+		t.Fatalf("应按监听器分组: %+v", got)
+	}
+	// 1..100ms 的连续分位数
+	if h := got[0]; h.Count != 100 || h.P50 != 50.5 || h.P99 < 99 || h.P99 > 100 || h.Max != 100 {
+		t.Fatalf("http 耗时统计错误: %+v", h)
+	}
+	if tcp := got[1]; tcp.Count != 2 || tcp.P50 != 2000 {
+		t.Fatalf("tcp 耗时统计错误: %+v", tcp)
+	}
+}
+
+func TestTopUpstreamHosts(t *testing.T) {
+	store := newTestStore(t)
+	got, err := store.TopUpstreamHosts(context.Background(), Filter{Since: time.Now().Add(-time.Hour), Kind: KindHTTP}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Host != "10.1.0.1:8080" || got[0].Count != 75 || got[0].Errors != 5 || got[0].Cluster != "web" {
+		t.Fatalf("上游主机统计错误: %+v", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(newTestStore(t)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/upstreams?since=10m&listener=tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var hosts []UpstreamHost
+	if err := json.NewDecoder(resp.Body).Decode(&hosts); err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 1 || hosts[0].Host != "10.2.0.1:8080" || hosts[0].Count != 2 {
+		t.Fatalf("查询接口返回错误: %+v", hosts)
+	}
+
+	for _, path := range []string{"/api/status?since=abc", "/api/latency?kind=udp", "/api/upstreams?limit=0"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s 应返回 400，实际 %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestQuerySpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	store := newTestStore(t)
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	if _, err := store.TopUpstreamHosts(ctx, Filter{Since: time.Now().Add(-time.Hour)}, 2); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	var inserts int
+	var query sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		switch s.Name() {
+		case "INSERT access_logs":
+			inserts++
+		case "SELECT access_logs":
+			query = s
+		}
+	}
+	if inserts != 3 {
+		t.Fatalf("每批写入应记录一个 span，实际 %d", inserts)
+	}
+	if query == nil || query.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("查询 span 应为请求 span 的子 span")
+	}
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range query.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["db.system.name"].AsString() != "duckdb" || attrs["db.response.returned_rows"].AsInt64() != 2 {
+		t.Fatalf("查询 span 属性错误: %v", query.Attributes())
+	}
+}
@@ -0,0 +1,123 @@
+package als
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// DefaultWindow 未指定 since 时的统计时间范围
+const DefaultWindow = time.Hour
+
+// NewHandler 创建查询接口，所有接口均支持以下参数：
+//
+//	since     统计最近多长时间的日志，默认 1h，例如 15m
+//	listener  只统计指定监听器
+//	kind      只统计 http 或 tcp 日志
+//
+// 接口：
+//
+//	GET /api/status              按监听器统计 HTTP 状态码
+//	GET /api/latency             按监听器统计耗时 p50 / p99
+//	GET /api/upstreams?limit=10  请求数最多的上游主机
+func NewHandler(store *Store) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", func(w http.ResponseWriter, r *http.Request) {
+		f, err := parseFilter(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		result, err := store.StatusCodes(r.Context(), f)
+		writeResult(w, result, err)
+	})
+	mux.HandleFunc("GET /api/latency", func(w http.ResponseWriter, r *http.Request) {
+		f, err := parseFilter(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		result, err := store.Latencies(r.Context(), f)
+		writeResult(w, result, err)
+	})
+	mux.HandleFunc("GET /api/upstreams", func(w http.ResponseWriter, r *http.Request) {
+		f, err := parseFilter(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		limit := 10
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", v))
+				return
+			}
+		}
+		result, err := store.TopUpstreamHosts(r.Context(), f, limit)
+		writeResult(w, result, err)
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+func parseFilter(r *http.Request) (Filter, error) {
+	q := r.URL.Query()
+	window := DefaultWindow
+	if v := q.Get("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return Filter{}, fmt.Errorf("invalid since %q", v)
+		}
+		window = d
+	}
+	f := Filter{Since: time.Now().Add(-window), Listener: q.Get("listener"), Kind: Kind(q.Get("kind"))}
+	if f.Kind != "" && f.Kind != KindHTTP && f.Kind != KindTCP {
+		return Filter{}, fmt.Errorf("invalid kind %q", f.Kind)
+	}
+	return f, nil
+}
+
+func writeResult(w http.ResponseWriter, result any, err error) {
+	if err != nil {
+		slog.Error("Access log query failed", logger.Err(err))
+		writeError(w, http.StatusInternalServerError, errors.New("query failed"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// ListenHTTP 在 addr 上启动查询接口，ctx 结束时优雅退出；每个请求记录服务端 span，DuckDB 查询为其子 span
+func ListenHTTP(ctx context.Context, addr string, store *Store) error {
+	handler := otelhttp.NewHandler(NewHandler(store), "als-api")
+	srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Access log query API listening", slog.String("addr", addr))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
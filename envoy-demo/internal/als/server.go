@@ -0,0 +1,193 @@
+package als
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	accesslogdatav3 "github.com/envoyproxy/go-control-plane/envoy/data/accesslog/v3"
+	alsv3 "github.com/envoyproxy/go-control-plane/envoy/service/accesslog/v3"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"google.golang.org/grpc"
+)
+
+// Server 实现 envoy.service.accesslog.v3.AccessLogService
+type Server struct {
+	alsv3.UnimplementedAccessLogServiceServer
+	store *Store
+}
+
+// NewServer 创建访问日志服务
+func NewServer(store *Store) *Server {
+	return &Server{store: store}
+}
+
+// StreamAccessLogs 接收 Envoy 上报的日志流，每条消息中的日志在一个事务中写入；
+// 只有流的第一条消息携带 identifier，后续消息沿用
+func (s *Server) StreamAccessLogs(stream alsv3.AccessLogService_StreamAccessLogsServer) error {
+	var node, logName string
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if id := msg.GetIdentifier(); id != nil {
+			node, logName = id.GetNode().GetId(), id.GetLogName()
+			slog.Debug("Access log stream opened", slog.String("node", node), slog.String("log_name", logName))
+		}
+
+		var records []Record
+		for _, e := range msg.GetHttpLogs().GetLogEntry() {
+			if r, ok := httpRecord(e); ok {
+				r.Node, r.Listener = node, logName
+				records = append(records, r)
+			}
+		}
+		for _, e := range msg.GetTcpLogs().GetLogEntry() {
+			if r, ok := tcpRecord(e); ok {
+				r.Node, r.Listener = node, logName
+				records = append(records, r)
+			}
+		}
+		// 写入失败只记录日志，不断开流，避免 Envoy 反复重连
+		if err := s.store.Write(stream.Context(), records); err != nil {
+			slog.Error("Failed to store access logs", slog.Int("count", len(records)), logger.Err(err))
+		}
+	}
+}
+
+func httpRecord(e *accesslogdatav3.HTTPAccessLogEntry) (Record, bool) {
+	c := e.GetCommonProperties()
+	// 周期性的中间日志会与连接结束时的日志重复计数
+	if c.GetIntermediateLogEntry() {
+		return Record{}, false
+	}
+	r := commonRecord(KindHTTP, c)
+	req, resp := e.GetRequest(), e.GetResponse()
+	if m := req.GetRequestMethod(); m != corev3.RequestMethod_METHOD_UNSPECIFIED {
+		r.Method = m.String()
+	}
+	r.Authority = req.GetAuthority()
+	r.Path = req.GetPath()
+	r.RequestID = req.GetRequestId()
+	r.UserAgent = req.GetUserAgent()
+	r.Status = int(resp.GetResponseCode().GetValue())
+	r.BytesReceived = req.GetRequestHeadersBytes() + req.GetRequestBodyBytes()
+	r.BytesSent = resp.GetResponseHeadersBytes() + resp.GetResponseBodyBytes()
+	return r, true
+}
+
+func tcpRecord(e *accesslogdatav3.TCPAccessLogEntry) (Record, bool) {
+	c := e.GetCommonProperties()
+	if c.GetIntermediateLogEntry() {
+		return Record{}, false
+	}
+	r := commonRecord(KindTCP, c)
+	r.BytesReceived = e.GetConnectionProperties().GetReceivedBytes()
+	r.BytesSent = e.GetConnectionProperties().GetSentBytes()
+	return r, true
+}
+
+func commonRecord(kind Kind, c *accesslogdatav3.AccessLogCommon) Record {
+	r := Record{
+		Kind:            kind,
+		Time:            c.GetStartTime().AsTime(),
+		LocalAddress:    addressString(c.GetDownstreamLocalAddress()),
+		RemoteAddress:   addressString(c.GetDownstreamRemoteAddress()),
+		UpstreamCluster: c.GetUpstreamCluster(),
+		UpstreamHost:    addressString(c.GetUpstreamRemoteAddress()),
+		RouteName:       c.GetRouteName(),
+		ResponseFlags:   responseFlags(c.GetResponseFlags()),
+	}
+	if c.GetStartTime() == nil {
+		r.Time = time.Now()
+	}
+	// duration 为整个请求（连接）的耗时，旧版本 Envoy 未上报时退回最后一个响应字节的时间
+	switch {
+	case c.GetDuration() != nil:
+		r.Duration = c.GetDuration().AsDuration()
+	case c.GetTimeToLastDownstreamTxByte() != nil:
+		r.Duration = c.GetTimeToLastDownstreamTxByte().AsDuration()
+	}
+	return r
+}
+
+func addressString(a *corev3.Address) string {
+	if sa := a.GetSocketAddress(); sa != nil {
+		return net.JoinHostPort(sa.GetAddress(), strconv.FormatUint(uint64(sa.GetPortValue()), 10))
+	}
+	if p := a.GetPipe(); p != nil {
+		return p.GetPath()
+	}
+	return ""
+}
+
+// responseFlags 转换为 %RESPONSE_FLAGS% 中的短名称，多个标志以逗号分隔
+func responseFlags(f *accesslogdatav3.ResponseFlags) string {
+	if f == nil {
+		return ""
+	}
+	var flags []string
+	for _, v := range []struct {
+		set  bool
+		name string
+	}{
+		{f.GetFailedLocalHealthcheck(), "LH"},
+		{f.GetNoHealthyUpstream(), "UH"},
+		{f.GetUpstreamRequestTimeout(), "UT"},
+		{f.GetLocalReset(), "LR"},
+		{f.GetUpstreamRemoteReset(), "UR"},
+		{f.GetUpstreamConnectionFailure(), "UF"},
+		{f.GetUpstreamConnectionTermination(), "UC"},
+		{f.GetUpstreamOverflow(), "UO"},
+		{f.GetNoRouteFound(), "NR"},
+		{f.GetDelayInjected(), "DI"},
+		{f.GetFaultInjected(), "FI"},
+		{f.GetRateLimited(), "RL"},
+		{f.GetUnauthorizedDetails() != nil, "UAEX"},
+		{f.GetRateLimitServiceError(), "RLSE"},
+		{f.GetDownstreamConnectionTermination(), "DC"},
+		{f.GetUpstreamRetryLimitExceeded(), "URX"},
+		{f.GetStreamIdleTimeout(), "SI"},
+		{f.GetDownstreamProtocolError(), "DPE"},
+		{f.GetUpstreamProtocolError(), "UPE"},
+		{f.GetNoClusterFound(), "NC"},
+		{f.GetDurationTimeout(), "DT"},
+		{f.GetDownstreamRemoteReset(), "DR"},
+	} {
+		if v.set {
+			flags = append(flags, v.name)
+		}
+	}
+	return strings.Join(flags, ",")
+}
+
+// ListenGRPC 在 addr 上启动访问日志服务，ctx 结束时优雅退出
+func ListenGRPC(ctx context.Context, addr string, s *Server) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	grpcServer := grpc.NewServer()
+	alsv3.RegisterAccessLogServiceServer(grpcServer, s)
+
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+
+	slog.Info("Access log server listening", slog.String("addr", lis.Addr().String()))
+	if err := grpcServer.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+// Package als 实现 Envoy 访问日志服务 envoy.service.accesslog.v3.AccessLogService，
+// 将 HTTP / TCP 访问日志写入 DuckDB，并提供按监听器统计状态码、耗时分位数与上游主机的查询接口
+package als
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/pkg/tracing"
+
+	_ "github.com/duckdb/duckdb-go/v2"
+)
+
+// Kind 日志类型
+type Kind string
+
+const (
+	KindHTTP Kind = "http"
+	KindTCP  Kind = "tcp"
+)
+
+// Record 一条访问日志
+type Record struct {
+	Time time.Time
+	Kind Kind
+	// Node 上报日志的 Envoy node.id
+	Node string
+	// Listener 日志名称（log_name），约定为监听器名称；为空时使用 LocalAddress
+	Listener      string
+	LocalAddress  string
+	RemoteAddress string
+	// UpstreamCluster、UpstreamHost 实际转发的上游集群与主机
+	UpstreamCluster string
+	UpstreamHost    string
+	RouteName       string
+	// Method、Authority、Path、Status 仅 HTTP 日志有值
+	Method    string
+	Authority string
+	Path      string
+	Status    int
+	// ResponseFlags Envoy 响应标志，如 UH、UF、RL
+	ResponseFlags string
+	Duration      time.Duration
+	BytesReceived uint64
+	BytesSent     uint64
+	RequestID     string
+	UserAgent     string
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS access_logs (
+	ts               TIMESTAMP NOT NULL,
+	kind             VARCHAR NOT NULL,
+	node             VARCHAR,
+	listener         VARCHAR NOT NULL,
+	local_address    VARCHAR,
+	remote_address   VARCHAR,
+	upstream_cluster VARCHAR,
+	upstream_host    VARCHAR,
+	route_name       VARCHAR,
+	method           VARCHAR,
+	authority        VARCHAR,
+	path             VARCHAR,
+	status           INTEGER,
+	response_flags   VARCHAR,
+	duration_ms      DOUBLE,
+	bytes_received   UBIGINT,
+	bytes_sent       UBIGINT,
+	request_id       VARCHAR,
+	user_agent       VARCHAR
+)`
+
+// Store 基于 DuckDB 的访问日志存储
+type Store struct {
+	db *sql.DB
+	// DuckDB 单进程内并发写入会产生事务冲突，写入串行化
+	mu sync.Mutex
+}
+
+// Open 打开（或创建）数据库文件并建表，path 为空时使用内存数据库
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("duckdb", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close 关闭数据库
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+const insertQuery = `INSERT INTO access_logs VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// Write 在一个事务中写入一批日志
+func (s *Store) Write(ctx context.Context, records []Record) (err error) {
+	if len(records) == 0 {
+		return nil
+	}
+	ctx, span := tracing.StartQuery(ctx, "duckdb", "INSERT", "access_logs", insertQuery)
+	defer func() { tracing.EndQuery(span, len(records), err) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range records {
+		var status sql.NullInt32
+		if r.Status != 0 {
+			status = sql.NullInt32{Int32: int32(r.Status), Valid: true}
+		}
+		listener := r.Listener
+		if listener == "" {
+			listener = r.LocalAddress
+		}
+		if _, err := stmt.ExecContext(ctx,
+			r.Time.UTC(), string(r.Kind), r.Node, listener, r.LocalAddress, r.RemoteAddress,
+			r.UpstreamCluster, r.UpstreamHost, r.RouteName,
+			r.Method, r.Authority, r.Path, status, r.ResponseFlags,
+			float64(r.Duration)/float64(time.Millisecond), r.BytesReceived, r.BytesSent,
+			r.RequestID, r.UserAgent,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const pruneQuery = `DELETE FROM access_logs WHERE ts < ?`
+
+// Prune 删除 before 之前的日志，返回删除条数
+func (s *Store) Prune(ctx context.Context, before time.Time) (n int64, err error) {
+	ctx, span := tracing.StartQuery(ctx, "duckdb", "DELETE", "access_logs", pruneQuery)
+	defer func() { tracing.EndQuery(span, int(n), err) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, err := s.db.ExecContext(ctx, pruneQuery, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Filter 查询条件
+type Filter struct {
+	// Since 只统计该时间之后的日志
+	Since time.Time
+	// Listener 为空时统计全部监听器
+	Listener string
+	// Kind 为空时统计全部类型
+	Kind Kind
+}
+
+func (f Filter) where() (string, []any) {
+	clause := "ts >= ?"
+	args := []any{f.Since.UTC()}
+	if f.Listener != "" {
+		clause += " AND listener = ?"
+		args = append(args, f.Listener)
+	}
+	if f.Kind != "" {
+		clause += " AND kind = ?"
+		args = append(args, string(f.Kind))
+	}
+	return clause, args
+}
+
+// StatusCount 某监听器某状态码的请求数
+type StatusCount struct {
+	Listener string `json:"listener"`
+	Status   int    `json:"status"`
+	Count    int64  `json:"count"`
+}
+
+// StatusCodes 按监听器统计 HTTP 状态码
+func (s *Store) StatusCodes(ctx context.Context, f Filter) (out []StatusCount, err error) {
+	where, args := f.where()
+	query := `
+SELECT listener, status, count(*) AS n
+FROM access_logs
+WHERE ` + where + ` AND status IS NOT NULL
+GROUP BY listener, status
+ORDER BY listener, status`
+	ctx, span := tracing.StartQuery(ctx, "duckdb", "SELECT", "access_logs", query)
+	defer func() { tracing.EndQuery(span, len(out), err) }()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out = []StatusCount{}
+	for rows.Next() {
+		var c StatusCount
+		if err := rows.Scan(&c.Listener, &c.Status, &c.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// Latency 某监听器的耗时分位数（毫秒）
+type Latency struct {
+	Listener string  `json:"listener"`
+	Count    int64   `json:"count"`
+	P50      float64 `json:"p50_ms"`
+	P99      float64 `json:"p99_ms"`
+	Max      float64 `json:"max_ms"`
+}
+
+// Latencies 按监听器统计耗时 p50 / p99
+func (s *Store) Latencies(ctx context.Context, f Filter) (out []Latency, err error) {
+	where, args := f.where()
+	query := `
+SELECT listener, count(*) AS n,
+	quantile_cont(duration_ms, 0.5) AS p50,
+	quantile_cont(duration_ms, 0.99) AS p99,
+	max(duration_ms) AS max
+FROM access_logs
+WHERE ` + where + ` AND duration_ms IS NOT NULL
+GROUP BY listener
+ORDER BY listener`
+	ctx, span := tracing.StartQuery(ctx, "duckdb", "SELECT", "access_logs", query)
+	defer func() { tracing.EndQuery(span, len(out), err) }()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out = []Latency{}
+	for rows.Next() {
+		var l Latency
+		if err := rows.Scan(&l.Listener, &l.Count, &l.P50, &l.P99, &l.Max); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// UpstreamHost 上游主机的请求数与错误数
+type UpstreamHost struct {
+	Host    string `json:"upstream_host"`
+	Cluster string `json:"upstream_cluster"`
+	Count   int64  `json:"count"`
+	// Errors 5xx 响应数（TCP 日志不计）
+	Errors int64   `json:"errors"`
+	P99    float64 `json:"p99_ms"`
+}
+
+// TopUpstreamHosts 按请求数降序返回前 limit 个上游主机
+func (s *Store) TopUpstreamHosts(ctx context.Context, f Filter, limit int) (out []UpstreamHost, err error) {
+	if limit <= 0 {
+		return nil, errors.New("limit must be positive")
+	}
+	where, args := f.where()
+	query := `
+SELECT upstream_host, any_value(upstream_cluster), count(*) AS n,
+	count(*) FILTER (WHERE status >= 500) AS errors,
+	coalesce(quantile_cont(duration_ms, 0.99), 0) AS p99
+FROM access_logs
+WHERE ` + where + ` AND upstream_host <> ''
+GROUP BY upstream_host
+ORDER BY n DESC, upstream_host
+LIMIT ?`
+	ctx, span := tracing.StartQuery(ctx, "duckdb", "SELECT", "access_logs", query)
+	defer func() { tracing.EndQuery(span, len(out), err) }()
+
+	rows, err := s.db.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out = []UpstreamHost{}
+	for rows.Next() {
+		var h UpstreamHost
+		if err := rows.Scan(&h.Host, &h.Cluster, &h.Count, &h.Errors, &h.P99); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
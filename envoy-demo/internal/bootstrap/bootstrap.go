@@ -0,0 +1,93 @@
+// Package bootstrap 根据代理声明渲染 Envoy v3 静态 bootstrap，并对已有的 bootstrap 文件做静态检查
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	// 注册 typed_config 中可能出现的扩展类型，解析 Any 时需要
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/stream/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/cors/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/tls_inspector/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/ratelimit/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+
+	bootstrapv3 "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	"github.com/goccy/go-yaml"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// PathMapping 路径前缀映射，例如容器内的 /opt/certs 对应本地的 certs
+type PathMapping struct {
+	From string
+	To   string
+}
+
+// PathMap 按顺序匹配的路径映射，实现 flag.Value，可重复传入 from=to
+type PathMap []PathMapping
+
+// String 实现 flag.Value
+func (m *PathMap) String() string {
+	parts := make([]string, 0, len(*m))
+	for _, p := range *m {
+		parts = append(parts, p.From+"="+p.To)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Set 实现 flag.Value
+func (m *PathMap) Set(v string) error {
+	from, to, ok := strings.Cut(v, "=")
+	if !ok || from == "" || to == "" {
+		return fmt.Errorf("invalid path mapping %q, want from=to", v)
+	}
+	*m = append(*m, PathMapping{From: filepath.Clean(from), To: filepath.Clean(to)})
+	return nil
+}
+
+// Apply 将匹配前缀的路径替换为目标前缀，未匹配时原样返回
+func (m PathMap) Apply(path string) string {
+	clean := filepath.Clean(path)
+	for _, p := range m {
+		if clean == p.From {
+			return p.To
+		}
+		if rest, ok := strings.CutPrefix(clean, p.From+string(filepath.Separator)); ok {
+			return filepath.Join(p.To, rest)
+		}
+	}
+	return path
+}
+
+// MarshalYAML 将 proto 消息输出为 Envoy 使用的 snake_case YAML
+func MarshalYAML(m proto.Message) ([]byte, error) {
+	js, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.JSONToYAML(js)
+}
+
+// Parse 解析 YAML 形式的 bootstrap，未知字段或未注册的扩展类型会返回错误
+func Parse(data []byte) (*bootstrapv3.Bootstrap, error) {
+	js, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var b bootstrapv3.Bootstrap
+	if err := protojson.Unmarshal(js, &b); err != nil {
+		return nil, errors.Join(errors.New("invalid bootstrap"), err)
+	}
+	return &b, nil
+}
@@ -0,0 +1,163 @@
+package bootstrap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyonmu/demo/envoy-demo/internal/proxyspec"
+)
+
+func TestLintExamples(t *testing.T) {
+	for _, file := range []string{
+		"../../../websocket-demo/envoy.yaml",
+		"../../L4-L7-porxy-demo/envoy.yaml",
+		"../../L4-L7-porxy-demo/envoy-xds.yaml",
+		"../../consul-xds-demo/envoy.yaml",
+	} {
+		findings, err := LintFile(file, LintOptions{})
+		if err != nil {
+			t.Fatalf("%s 解析失败: %v", file, err)
+		}
+		for _, f := range findings {
+			if f.Severity == SeverityError {
+				t.Errorf("%s 不应有错误: %s", file, f)
+			}
+		}
+	}
+}
+
+func TestRenderThenLint(t *testing.T) {
+	spec, err := proxyspec.Load("../../L4-L7-porxy-demo/proxies.yaml")
+	if err != nil {
+		t.Fatalf("加载示例声明失败: %v", err)
+	}
+	var paths PathMap
+	if err := paths.Set("../../L4-L7-porxy-demo/certs=/opt/certs"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Render(spec, RenderOptions{AdminPort: 9901, PathMap: paths, Source: "proxies.yaml"})
+	if err != nil {
+		t.Fatalf("渲染失败: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# Code generated") || !strings.Contains(string(data), "/opt/certs/example.com.crt") {
+		t.Fatalf("渲染结果错误:\n%s", data)
+	}
+	// 限流与访问日志服务只由 proxies.yaml 声明，不再有手写的 envoy.yaml 副本
+	for _, want := range []string{"envoy.filters.http.ratelimit", "envoy.access_loggers.http_grpc"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("渲染结果缺少 %s", want)
+		}
+	}
+
+	// 容器内路径不存在，映射回本地目录后应通过检查
+	findings, err := Lint(data, LintOptions{})
+	if err != nil {
+		t.Fatalf("解析渲染结果失败: %v", err)
+	}
+	if !hasFinding(findings, SeverityError, "/opt/certs/example.com.crt") {
+		t.Fatalf("应报告证书文件不存在: %v", findings)
+	}
+	var back PathMap
+	_ = back.Set("/opt/certs=../../L4-L7-porxy-demo/certs")
+	findings, err = Lint(data, LintOptions{PathMap: back})
+	if err != nil {
+		t.Fatalf("解析渲染结果失败: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Fatalf("渲染结果不应有问题: %v", findings)
+	}
+}
+
+func TestLintFindings(t *testing.T) {
+	findings, err := Lint([]byte(`
+admin:
+  address:
+    socket_address: { address: 0.0.0.0, port_value: 9901 }
+static_resources:
+  listeners:
+    - name: web
+      address:
+        socket_address: { address: 0.0.0.0, port_value: 9901 }
+      filter_chains:
+        - filters:
+            - name: envoy.filters.network.http_connection_manager
+              typed_config:
+                "@type": type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager
+                stat_prefix: web
+                route_config:
+                  virtual_hosts:
+                    - name: all
+                      domains: ["*"]
+                      routes:
+                        - match: { prefix: /ws }
+                          route: { cluster: missing }
+                http_filters:
+                  - name: envoy.filters.http.router
+                    typed_config:
+                      "@type": type.googleapis.com/envoy.extensions.filters.http.router.v3.Router
+          transport_socket:
+            name: envoy.transport_sockets.tls
+            typed_config:
+              "@type": type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.DownstreamTlsContext
+              common_tls_context:
+                tls_certificates:
+                  - certificate_chain: { filename: certs/none.crt }
+                    private_key: { filename: certs/none.key }
+  clusters:
+    - name: unused
+      type: STATIC
+      load_assignment:
+        cluster_name: unused
+`), LintOptions{BaseDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	for _, want := range []struct {
+		severity Severity
+		text     string
+	}{
+		{SeverityError, `cluster "missing"`},
+		{SeverityWarning, `cluster "unused" is not referenced`},
+		{SeverityError, "port 9901"},
+		{SeverityError, "websocket upgrade is not enabled"},
+		{SeverityError, "certs/none.crt"},
+		{SeverityError, "certs/none.key"},
+	} {
+		if !hasFinding(findings, want.severity, want.text) {
+			t.Errorf("缺少 %s: %q, 实际: %v", want.severity, want.text, findings)
+		}
+	}
+
+	if _, err := Lint([]byte("static_resources: { listenerz: [] }"), LintOptions{}); err == nil {
+		t.Fatalf("未知字段应返回错误")
+	}
+}
+
+func TestPathMap(t *testing.T) {
+	var m PathMap
+	if err := m.Set("/opt/certs=certs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("bad"); err == nil {
+		t.Fatalf("缺少 = 应返回错误")
+	}
+	for in, want := range map[string]string{
+		"/opt/certs/a.crt":  "certs/a.crt",
+		"/opt/certs":        "certs",
+		"/opt/certs2/a.crt": "/opt/certs2/a.crt",
+		"other/a.crt":       "other/a.crt",
+	} {
+		if got := m.Apply(in); got != want {
+			t.Errorf("Apply(%q) = %q, 期望 %q", in, got, want)
+		}
+	}
+}
+
+func hasFinding(findings []Finding, s Severity, text string) bool {
+	for _, f := range findings {
+		if f.Severity == s && strings.Contains(f.Message, text) {
+			return true
+		}
+	}
+	return false
+}
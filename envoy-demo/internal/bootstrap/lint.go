@@ -0,0 +1,298 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	bootstrapv3 "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Severity 检查结果级别
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding 一条检查结果
+type Finding struct {
+	Severity Severity
+	Message  string
+}
+
+func (f Finding) String() string {
+	return string(f.Severity) + ": " + f.Message
+}
+
+// LintOptions 检查参数
+type LintOptions struct {
+	// BaseDir 相对路径的基准目录，一般为被检查文件所在目录
+	BaseDir string
+	// PathMap 将配置中的路径（如容器内 /opt/certs）映射为本地路径（相对当前目录）后再检查是否存在
+	PathMap PathMap
+}
+
+// LintFile 检查 bootstrap 文件，相对路径以文件所在目录为基准
+func LintFile(path string, opts LintOptions) ([]Finding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if opts.BaseDir == "" {
+		opts.BaseDir = filepath.Dir(path)
+	}
+	return Lint(data, opts)
+}
+
+// Lint 检查 bootstrap：
+//   - 字段与扩展类型合法，并满足 proto 校验规则
+//   - 路由、tcp_proxy、gRPC 服务引用的集群存在，静态集群都被引用
+//   - 监听器与 admin 端口唯一
+//   - 存在 /ws 路由的 HTTP 连接管理器启用了 websocket 升级
+//   - TLS 等配置引用的文件存在
+func Lint(data []byte, opts LintOptions) ([]Finding, error) {
+	b, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	add := func(s Severity, format string, args ...any) {
+		findings = append(findings, Finding{Severity: s, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if err := b.ValidateAll(); err != nil {
+		var multi interface{ AllErrors() []error }
+		if errors.As(err, &multi) {
+			for _, e := range multi.AllErrors() {
+				add(SeverityError, "%v", e)
+			}
+		} else {
+			add(SeverityError, "%v", err)
+		}
+	}
+
+	lintClusters(b, add)
+	lintPorts(b, add)
+	lintWebSocket(b, add)
+	lintFiles(b, opts, add)
+	return findings, nil
+}
+
+type addFunc func(Severity, string, ...any)
+
+// lintClusters 检查集群引用：引用不存在的集群为错误（启用 CDS 时为警告），未被引用的静态集群为警告
+func lintClusters(b *bootstrapv3.Bootstrap, add addFunc) {
+	static := make(map[string]bool)
+	for _, c := range b.GetStaticResources().GetClusters() {
+		if static[c.GetName()] {
+			add(SeverityError, "duplicate cluster %q", c.GetName())
+		}
+		static[c.GetName()] = true
+	}
+
+	referenced := make(map[string][]string)
+	ref := func(name, by string) {
+		if name != "" {
+			referenced[name] = append(referenced[name], by)
+		}
+	}
+	walk(b, func(m proto.Message) {
+		switch v := m.(type) {
+		case *route.Route:
+			ra := v.GetRoute()
+			ref(ra.GetCluster(), "route "+routeLabel(v))
+			for _, w := range ra.GetWeightedClusters().GetClusters() {
+				ref(w.GetName(), "route "+routeLabel(v))
+			}
+		case *tcpproxy.TcpProxy:
+			ref(v.GetCluster(), "tcp_proxy "+v.GetStatPrefix())
+			for _, w := range v.GetWeightedClusters().GetClusters() {
+				ref(w.GetName(), "tcp_proxy "+v.GetStatPrefix())
+			}
+		case *core.GrpcService:
+			ref(v.GetEnvoyGrpc().GetClusterName(), "grpc_service")
+		}
+	})
+
+	dynamic := b.GetDynamicResources().GetCdsConfig() != nil
+	for _, name := range sortedKeys(referenced) {
+		if static[name] {
+			continue
+		}
+		by := strings.Join(slices.Compact(referenced[name]), ", ")
+		if dynamic {
+			add(SeverityWarning, "cluster %q referenced by %s is not static, expecting it from CDS", name, by)
+		} else {
+			add(SeverityError, "cluster %q referenced by %s does not exist", name, by)
+		}
+	}
+	for _, c := range b.GetStaticResources().GetClusters() {
+		if _, ok := referenced[c.GetName()]; !ok {
+			add(SeverityWarning, "cluster %q is not referenced", c.GetName())
+		}
+	}
+}
+
+// lintPorts 检查监听器与 admin 的端口冲突
+func lintPorts(b *bootstrapv3.Bootstrap, add addFunc) {
+	used := make(map[string]string)
+	check := func(owner string, addr *core.SocketAddress) {
+		if addr == nil {
+			return
+		}
+		port := addr.GetPortValue()
+		if port == 0 {
+			add(SeverityError, "%s has no port", owner)
+			return
+		}
+		key := addr.GetProtocol().String() + "/" + strconv.Itoa(int(port))
+		// 0.0.0.0 与具体地址监听同一端口同样冲突，只按协议与端口判断
+		if other, ok := used[key]; ok {
+			add(SeverityError, "port %d of %s is already used by %s", port, owner, other)
+			return
+		}
+		used[key] = owner
+	}
+
+	check("admin", b.GetAdmin().GetAddress().GetSocketAddress())
+	for _, l := range b.GetStaticResources().GetListeners() {
+		check("listener "+strconv.Quote(l.GetName()), l.GetAddress().GetSocketAddress())
+	}
+}
+
+// lintWebSocket 存在 /ws 路由时，HCM 或该路由必须启用 websocket 升级
+func lintWebSocket(b *bootstrapv3.Bootstrap, add addFunc) {
+	walk(b, func(m proto.Message) {
+		manager, ok := m.(*hcm.HttpConnectionManager)
+		if !ok {
+			return
+		}
+		managerWS := slices.ContainsFunc(manager.GetUpgradeConfigs(), func(u *hcm.HttpConnectionManager_UpgradeConfig) bool {
+			return isWebSocket(u.GetUpgradeType(), u.GetEnabled().GetValue(), u.GetEnabled() != nil)
+		})
+		for _, vh := range manager.GetRouteConfig().GetVirtualHosts() {
+			for _, r := range vh.GetRoutes() {
+				if !isWSRoute(r.GetMatch()) {
+					continue
+				}
+				routeWS := slices.ContainsFunc(r.GetRoute().GetUpgradeConfigs(), func(u *route.RouteAction_UpgradeConfig) bool {
+					return isWebSocket(u.GetUpgradeType(), u.GetEnabled().GetValue(), u.GetEnabled() != nil)
+				})
+				if !managerWS && !routeWS {
+					add(SeverityError, "route %s in %q matches /ws but websocket upgrade is not enabled",
+						routeLabel(r), manager.GetStatPrefix())
+				}
+			}
+		}
+	})
+}
+
+// lintFiles 检查 DataSource 引用的文件（证书、私钥、CA 等）是否存在
+func lintFiles(b *bootstrapv3.Bootstrap, opts LintOptions, add addFunc) {
+	seen := make(map[string]bool)
+	walk(b, func(m proto.Message) {
+		ds, ok := m.(*core.DataSource)
+		if !ok || ds.GetFilename() == "" || seen[ds.GetFilename()] {
+			return
+		}
+		seen[ds.GetFilename()] = true
+
+		// 映射后的路径来自命令行，相对当前目录；未映射的相对路径以配置文件所在目录为基准
+		local := opts.PathMap.Apply(ds.GetFilename())
+		if local == ds.GetFilename() && !filepath.IsAbs(local) && opts.BaseDir != "" {
+			local = filepath.Join(opts.BaseDir, local)
+		}
+		if _, err := os.Stat(local); err != nil {
+			if local != ds.GetFilename() {
+				add(SeverityError, "file %q (resolved to %q) does not exist", ds.GetFilename(), local)
+			} else {
+				add(SeverityError, "file %q does not exist", ds.GetFilename())
+			}
+		}
+	})
+}
+
+func isWebSocket(typ string, enabled, set bool) bool {
+	return strings.EqualFold(typ, "websocket") && (!set || enabled)
+}
+
+// isWSRoute 路由匹配 /ws 或 /ws/ 下的路径
+func isWSRoute(m *route.RouteMatch) bool {
+	for _, p := range []string{m.GetPath(), m.GetPrefix(), m.GetPathSeparatedPrefix()} {
+		if p == "/ws" || strings.HasPrefix(p, "/ws/") {
+			return true
+		}
+	}
+	return false
+}
+
+func routeLabel(r *route.Route) string {
+	if r.GetName() != "" {
+		return strconv.Quote(r.GetName())
+	}
+	m := r.GetMatch()
+	switch {
+	case m.GetPath() != "":
+		return "path " + m.GetPath()
+	case m.GetPathSeparatedPrefix() != "":
+		return "prefix " + m.GetPathSeparatedPrefix()
+	default:
+		return "prefix " + m.GetPrefix()
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// walk 深度优先遍历消息及其所有子消息，Any 会被解包后继续遍历
+func walk(m proto.Message, fn func(proto.Message)) {
+	fn(m)
+	if a, ok := m.(*anypb.Any); ok {
+		if inner, err := a.UnmarshalNew(); err == nil {
+			walk(inner, fn)
+		}
+		return
+	}
+
+	m.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			if fd.Kind() == protoreflect.MessageKind {
+				list := v.List()
+				for i := range list.Len() {
+					walk(list.Get(i).Message().Interface(), fn)
+				}
+			}
+		case fd.IsMap():
+			if fd.MapValue().Kind() == protoreflect.MessageKind {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					walk(mv.Message().Interface(), fn)
+					return true
+				})
+			}
+		case fd.Kind() == protoreflect.MessageKind:
+			walk(v.Message().Interface(), fn)
+		}
+		return true
+	})
+}
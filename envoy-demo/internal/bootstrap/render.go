@@ -0,0 +1,74 @@
+package bootstrap
+
+import (
+	"bytes"
+	"fmt"
+
+	bootstrapv3 "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/lyonmu/demo/envoy-demo/internal/proxyspec"
+)
+
+// RenderOptions 渲染参数
+type RenderOptions struct {
+	// NodeID、NodeCluster 为空时不输出 node
+	NodeID      string
+	NodeCluster string
+	// AdminAddress、AdminPort 为 0 时不启用 admin
+	AdminAddress string
+	AdminPort    uint32
+	// PathMap 改写证书路径，如 L4-L7-porxy-demo/certs=/opt/certs
+	PathMap PathMap
+	// Source 写入文件头注释的声明文件名
+	Source string
+}
+
+// Render 将代理声明渲染为静态 bootstrap YAML，证书以文件路径引用
+func Render(spec proxyspec.Spec, opts RenderOptions) ([]byte, error) {
+	res, err := proxyspec.Build(spec, proxyspec.BuildOptions{CertPath: opts.PathMap.Apply})
+	if err != nil {
+		return nil, err
+	}
+
+	b := &bootstrapv3.Bootstrap{StaticResources: &bootstrapv3.Bootstrap_StaticResources{}}
+	if opts.NodeID != "" || opts.NodeCluster != "" {
+		b.Node = &core.Node{Id: opts.NodeID, Cluster: opts.NodeCluster}
+	}
+	if opts.AdminPort != 0 {
+		addr := opts.AdminAddress
+		if addr == "" {
+			addr = "0.0.0.0"
+		}
+		b.Admin = &bootstrapv3.Admin{Address: &core.Address{
+			Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+				Address:       addr,
+				PortSpecifier: &core.SocketAddress_PortValue{PortValue: opts.AdminPort},
+			}},
+		}}
+	}
+	for _, l := range res[resource.ListenerType] {
+		b.StaticResources.Listeners = append(b.StaticResources.Listeners, l.(*listener.Listener))
+	}
+	for _, c := range res[resource.ClusterType] {
+		b.StaticResources.Clusters = append(b.StaticResources.Clusters, c.(*cluster.Cluster))
+	}
+	if err := b.ValidateAll(); err != nil {
+		return nil, err
+	}
+
+	body, err := MarshalYAML(b)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	source := opts.Source
+	if source == "" {
+		source = "a proxy spec"
+	}
+	fmt.Fprintf(&out, "# Code generated by envoyctl render from %s; DO NOT EDIT.\n", source)
+	out.Write(body)
+	return out.Bytes(), nil
+}
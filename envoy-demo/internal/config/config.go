@@ -0,0 +1,40 @@
+// Package config 提供各个命令共用的 YAML 配置加载与 Consul 连接配置
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-yaml"
+	"github.com/lyonmu/demo/base-demo/pkg/consul"
+)
+
+// Load 将 path 指向的 YAML 解析到 v，v 中已有的值作为默认值；
+// path 为空时读取环境变量 env，文件不存在且 optional 为 true 时保留默认值
+func Load(path, env string, optional bool, v any) error {
+	if path == "" && env != "" {
+		path = os.Getenv(env)
+	}
+	if path == "" {
+		if optional {
+			return nil
+		}
+		return errors.New("config file is required")
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && optional:
+		return nil
+	case err != nil:
+		return err
+	}
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// Consul Agent 连接配置，与其他 demo 共用
+type Consul = consul.Config
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"context"
+	"maps"
+	"os"
+	"time"
+)
+
+// stamp 文件的修改时间与大小，用于轮询判断变化
+type stamp struct {
+	modTime time.Time
+	size    int64
+	missing bool
+}
+
+// Watch 立即调用一次 load，之后每隔 interval 检查 path 及 load 返回的文件，任一文件变化时再次调用 load；
+// load 返回本次加载引用的其他文件（如证书），返回 nil 时沿用上一次的列表；ctx 结束时返回
+func Watch(ctx context.Context, path string, interval time.Duration, load func() []string) {
+	var (
+		last  map[string]stamp
+		files = []string{path}
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if current := stamps(files); !maps.Equal(current, last) {
+			if extra := load(); extra != nil {
+				files = append([]string{path}, extra...)
+			}
+			// 重新记录，包含新引用的文件；path 沿用加载前的记录，加载期间的修改会在下一轮生效
+			last = stamps(files)
+			last[path] = current[path]
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func stamps(files []string) map[string]stamp {
+	out := make(map[string]stamp, len(files))
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			out[f] = stamp{missing: true}
+			continue
+		}
+		out[f] = stamp{modTime: fi.ModTime(), size: fi.Size()}
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package consulxds
+
+import (
+	"time"
+
+	"github.com/lyonmu/demo/envoy-demo/internal/config"
+)
+
+// EnvConfigFile 配置文件路径环境变量
+const EnvConfigFile = "CONSUL_XDS_CONFIG"
+
+// Config consul-xds 配置
+type Config struct {
+	XDS    XDS           `yaml:"xds"`
+	Consul config.Consul `yaml:"consul"`
+	Watch  Watch         `yaml:"watch"`
+	Routes Routes        `yaml:"routes"`
+}
+
+// XDS 控制面监听配置
+type XDS struct {
+	// Listen gRPC 监听地址
+	Listen string `yaml:"listen"`
+	// NodeCluster 下发目标 Envoy 的 node.cluster
+	NodeCluster string `yaml:"node_cluster"`
+	// Debounce 合并短时间内多次变更，默认 200ms
+	Debounce time.Duration `yaml:"debounce"`
+}
+
+// Watch Consul 监听配置
+type Watch struct {
+	// Tag 只监听带该标签的服务
+	Tag string `yaml:"tag"`
+}
+
+// Routes 路由生成配置
+type Routes struct {
+	RouteConfigName string        `yaml:"route_config_name"`
+	Domains         []string      `yaml:"domains"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout"`
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() Config {
+	return Config{
+		XDS: XDS{
+			Listen:      ":18000",
+			NodeCluster: "envoy-gateway",
+			Debounce:    200 * time.Millisecond,
+		},
+		Consul: config.Consul{Address: "127.0.0.1:8500", Scheme: "http"},
+		Routes: Routes{
+			RouteConfigName: "consul_routes",
+			Domains:         []string{"*"},
+			ConnectTimeout:  5 * time.Second,
+		},
+	}
+}
+
+// LoadConfig 加载配置文件，path 为空时读取 CONSUL_XDS_CONFIG，文件不存在时使用默认值
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	err := config.Load(path, EnvConfigFile, true, &cfg)
+	return cfg, err
+}
+
+// BuildOptions 转换为资源生成参数
+func (r Routes) BuildOptions() BuildOptions {
+	return BuildOptions{
+		RouteConfigName: r.RouteConfigName,
+		Domains:         r.Domains,
+		ConnectTimeout:  r.ConnectTimeout,
+	}
+}
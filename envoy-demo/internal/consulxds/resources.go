@@ -0,0 +1,159 @@
+// Package consulxds 监听 Consul 目录并将健康实例转换为 Envoy 的 CDS / EDS / RDS 资源
+package consulxds
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	extauthz "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// MetaRouterPrefix 服务元数据中的路由前缀，如 demo 对应 /demo 与 /demo/*
+	MetaRouterPrefix = "router_prefix"
+	// MetaNoAuth 服务元数据中为 true 时该路由跳过 ext_authz
+	MetaNoAuth = "no_auth"
+	// ExtAuthzFilter Envoy 中 ext_authz HTTP 过滤器的名称，需与 bootstrap 中一致
+	ExtAuthzFilter = "envoy.filters.http.ext_authz"
+)
+
+// Endpoint 服务的一个健康实例
+type Endpoint struct {
+	Address string
+	Port    int
+}
+
+// Service 一个 Consul 服务及其路由元数据
+type Service struct {
+	Name string
+	// RouterPrefix 为空时只生成 cluster，不生成路由
+	RouterPrefix string
+	NoAuth       bool
+	Endpoints    []Endpoint
+}
+
+// BuildOptions 资源生成参数
+type BuildOptions struct {
+	// RouteConfigName RDS 路由表名称，需与 bootstrap 中 HCM 的 rds.route_config_name 一致
+	RouteConfigName string
+	// Domains 虚拟主机匹配的域名
+	Domains []string
+	// ConnectTimeout 上游连接超时
+	ConnectTimeout time.Duration
+}
+
+// Build 根据服务列表生成 CDS、EDS、RDS 资源
+func Build(services []Service, opts BuildOptions) map[resource.Type][]types.Resource {
+	services = slices.Clone(services)
+	slices.SortFunc(services, func(a, b Service) int { return strings.Compare(a.Name, b.Name) })
+
+	clusters := make([]types.Resource, 0, len(services))
+	endpoints := make([]types.Resource, 0, len(services))
+	for _, svc := range services {
+		clusters = append(clusters, makeCluster(svc.Name, opts.ConnectTimeout))
+		endpoints = append(endpoints, makeEndpoints(svc))
+	}
+
+	return map[resource.Type][]types.Resource{
+		resource.ClusterType:  clusters,
+		resource.EndpointType: endpoints,
+		resource.RouteType:    {makeRoutes(services, opts)},
+	}
+}
+
+func makeCluster(name string, timeout time.Duration) *cluster.Cluster {
+	return &cluster.Cluster{
+		Name:                 name,
+		ConnectTimeout:       durationpb.New(timeout),
+		ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+		LbPolicy:             cluster.Cluster_ROUND_ROBIN,
+		EdsClusterConfig: &cluster.Cluster_EdsClusterConfig{
+			EdsConfig: &core.ConfigSource{
+				ResourceApiVersion:    core.ApiVersion_V3,
+				ConfigSourceSpecifier: &core.ConfigSource_Ads{Ads: &core.AggregatedConfigSource{}},
+			},
+		},
+	}
+}
+
+func makeEndpoints(svc Service) *endpoint.ClusterLoadAssignment {
+	lbEndpoints := make([]*endpoint.LbEndpoint, 0, len(svc.Endpoints))
+	for _, ep := range svc.Endpoints {
+		lbEndpoints = append(lbEndpoints, &endpoint.LbEndpoint{
+			HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+				Endpoint: &endpoint.Endpoint{
+					Address: &core.Address{
+						Address: &core.Address_SocketAddress{
+							SocketAddress: &core.SocketAddress{
+								Address:       ep.Address,
+								PortSpecifier: &core.SocketAddress_PortValue{PortValue: uint32(ep.Port)},
+							},
+						},
+					},
+				},
+			},
+			HealthStatus: core.HealthStatus_HEALTHY,
+		})
+	}
+	return &endpoint.ClusterLoadAssignment{
+		ClusterName: svc.Name,
+		Endpoints:   []*endpoint.LocalityLbEndpoints{{LbEndpoints: lbEndpoints}},
+	}
+}
+
+// makeRoutes 每个带 router_prefix 的服务生成一条路由，前缀长的优先匹配
+func makeRoutes(services []Service, opts BuildOptions) *route.RouteConfiguration {
+	routed := slices.DeleteFunc(slices.Clone(services), func(s Service) bool { return Prefix(s.RouterPrefix) == "" })
+	slices.SortStableFunc(routed, func(a, b Service) int {
+		return len(Prefix(b.RouterPrefix)) - len(Prefix(a.RouterPrefix))
+	})
+
+	routes := make([]*route.Route, 0, len(routed))
+	for _, svc := range routed {
+		r := &route.Route{
+			Name: svc.Name,
+			Match: &route.RouteMatch{
+				PathSpecifier: &route.RouteMatch_PathSeparatedPrefix{PathSeparatedPrefix: Prefix(svc.RouterPrefix)},
+			},
+			Action: &route.Route_Route{
+				Route: &route.RouteAction{
+					ClusterSpecifier: &route.RouteAction_Cluster{Cluster: svc.Name},
+				},
+			},
+		}
+		if svc.NoAuth {
+			disabled, _ := anypb.New(&extauthz.ExtAuthzPerRoute{
+				Override: &extauthz.ExtAuthzPerRoute_Disabled{Disabled: true},
+			})
+			r.TypedPerFilterConfig = map[string]*anypb.Any{ExtAuthzFilter: disabled}
+		}
+		routes = append(routes, r)
+	}
+
+	return &route.RouteConfiguration{
+		Name: opts.RouteConfigName,
+		VirtualHosts: []*route.VirtualHost{{
+			Name:    "consul",
+			Domains: opts.Domains,
+			Routes:  routes,
+		}},
+	}
+}
+
+// Prefix 将 router_prefix 规范化为 /demo 形式，空前缀返回空串
+func Prefix(p string) string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
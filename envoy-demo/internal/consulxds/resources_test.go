@@ -0,0 +1,94 @@
+package consulxds
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/lyonmu/demo/envoy-demo/internal/xds"
+)
+
+func testServices() []Service {
+	return []Service{
+		{Name: "web", RouterPrefix: "/", Endpoints: []Endpoint{{Address: "10.0.0.3", Port: 80}}},
+		{Name: "demo", RouterPrefix: "demo", Endpoints: []Endpoint{{Address: "10.0.0.1", Port: 8080}, {Address: "10.0.0.2", Port: 8080}}},
+		{Name: "demo-public", RouterPrefix: "demo/public", NoAuth: true},
+		{Name: "internal"},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	opts := DefaultConfig().Routes.BuildOptions()
+	res := Build(testServices(), opts)
+
+	if n := len(res[resource.ClusterType]); n != 4 {
+		t.Fatalf("cluster 数量错误: %d", n)
+	}
+	if n := len(res[resource.EndpointType]); n != 4 {
+		t.Fatalf("endpoint 数量错误: %d", n)
+	}
+
+	rc := res[resource.RouteType][0].(*route.RouteConfiguration)
+	if rc.GetName() != "consul_routes" {
+		t.Fatalf("路由表名称错误: %s", rc.GetName())
+	}
+	routes := rc.GetVirtualHosts()[0].GetRoutes()
+	// 没有前缀的服务不生成路由，"/" 规范化为空前缀
+	if len(routes) != 2 {
+		t.Fatalf("路由数量错误: %d", len(routes))
+	}
+	// 前缀长的优先
+	if got := routes[0].GetMatch().GetPathSeparatedPrefix(); got != "/demo/public" {
+		t.Fatalf("路由顺序错误: %s", got)
+	}
+	if _, ok := routes[0].GetTypedPerFilterConfig()[ExtAuthzFilter]; !ok {
+		t.Fatalf("no_auth 路由未关闭 ext_authz")
+	}
+	if routes[1].GetRoute().GetCluster() != "demo" || routes[1].GetTypedPerFilterConfig() != nil {
+		t.Fatalf("demo 路由错误: %v", routes[1])
+	}
+}
+
+func TestPublisher(t *testing.T) {
+	opts := DefaultConfig().Routes.BuildOptions()
+	pub := xds.NewPublisher(xds.NewCache(), "envoy-gateway")
+
+	v1, changed, err := pub.Publish(context.Background(), Build(testServices(), opts))
+	if err != nil || !changed {
+		t.Fatalf("首次发布失败: %v", err)
+	}
+	// 输入顺序不同但内容相同时版本不变
+	services := testServices()
+	services[0], services[1] = services[1], services[0]
+	if v, changed, _ := pub.Publish(context.Background(), Build(services, opts)); changed || v != v1 {
+		t.Fatalf("内容未变化却重新发布: %s -> %s", v1, v)
+	}
+
+	services[0].Endpoints = services[0].Endpoints[:1]
+	if v, changed, _ := pub.Publish(context.Background(), Build(services, opts)); !changed || v == v1 {
+		t.Fatalf("实例变化后未重新发布")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "xds:\n  listen: :19000\nroutes:\n  connect_timeout: 2s\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.XDS.Listen != ":19000" || cfg.Routes.ConnectTimeout != 2*time.Second {
+		t.Fatalf("配置解析错误: %+v", cfg)
+	}
+	if cfg.XDS.NodeCluster != "envoy-gateway" || cfg.Routes.RouteConfigName != "consul_routes" {
+		t.Fatalf("默认值丢失: %+v", cfg)
+	}
+}
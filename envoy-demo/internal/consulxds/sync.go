@@ -0,0 +1,52 @@
+package consulxds
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/envoy-demo/internal/xds"
+)
+
+// Sync 在 Watcher 通知变化后（合并 debounce 内的多次变化）重新生成并发布快照，ctx 结束时返回
+func Sync(ctx context.Context, w *Watcher, pub *xds.Publisher, opts BuildOptions, debounce time.Duration) {
+	// 先发布一份空快照，Envoy 连接后即可完成初始化
+	publish(ctx, w, pub, opts)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.Changed():
+		}
+
+		if debounce > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(debounce):
+			}
+		}
+		publish(ctx, w, pub, opts)
+	}
+}
+
+func publish(ctx context.Context, w *Watcher, pub *xds.Publisher, opts BuildOptions) {
+	services := w.Services()
+	version, changed, err := pub.Publish(ctx, Build(services, opts))
+	if err != nil {
+		slog.Error("Failed to publish xDS snapshot", logger.Err(err))
+		return
+	}
+	if changed {
+		endpoints := 0
+		for _, svc := range services {
+			endpoints += len(svc.Endpoints)
+		}
+		slog.Info("xDS snapshot published",
+			slog.String("version", version),
+			slog.Int("services", len(services)),
+			slog.Int("endpoints", endpoints))
+	}
+}
@@ -0,0 +1,184 @@
+package consulxds
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/lyonmu/demo/base-demo/pkg/consul"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+)
+
+// WatchOptions 监听参数
+type WatchOptions struct {
+	// Tag 只监听带该标签的服务，为空时监听全部（consul 自身除外）
+	Tag string
+	// WaitTime 阻塞查询的最长等待时间，默认 5m
+	WaitTime time.Duration
+	// RetryInterval 查询失败后的重试间隔，默认 5s
+	RetryInterval time.Duration
+}
+
+// Watcher 通过阻塞查询监听服务目录以及每个服务的健康实例
+type Watcher struct {
+	client *capi.Client
+	opts   WatchOptions
+
+	mu       sync.Mutex
+	services map[string]Service
+	cancels  map[string]context.CancelFunc
+	changed  chan struct{}
+}
+
+// NewWatcher 创建 Watcher，需调用 Run 开始监听
+func NewWatcher(client *capi.Client, opts WatchOptions) *Watcher {
+	if opts.WaitTime <= 0 {
+		opts.WaitTime = 5 * time.Minute
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+	return &Watcher{
+		client:   client,
+		opts:     opts,
+		services: make(map[string]Service),
+		cancels:  make(map[string]context.CancelFunc),
+		changed:  make(chan struct{}, 1),
+	}
+}
+
+// Changed 服务或实例变化时收到通知，多次变化可能合并为一次
+func (w *Watcher) Changed() <-chan struct{} {
+	return w.changed
+}
+
+// Services 返回当前服务快照，按名称排序
+func (w *Watcher) Services() []Service {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	out := make([]Service, 0, len(w.services))
+	for _, svc := range w.services {
+		out = append(out, svc)
+	}
+	slices.SortFunc(out, func(a, b Service) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// Run 监听服务目录，为新出现的服务启动实例监听、为消失的服务停止监听，ctx 结束时返回
+func (w *Watcher) Run(ctx context.Context) {
+	consul.BlockingQuery(ctx, w.watchOptions(func(err error) {
+		slog.Warn("Failed to watch Consul catalog", logger.Err(err))
+	}), func(q *capi.QueryOptions) (*capi.QueryMeta, error) {
+		catalog, meta, err := w.client.Catalog().Services(q)
+		if err != nil {
+			return nil, err
+		}
+		w.sync(ctx, catalog)
+		return meta, nil
+	})
+
+	w.mu.Lock()
+	for _, cancel := range w.cancels {
+		cancel()
+	}
+	w.mu.Unlock()
+}
+
+// sync 按目录内容启动或停止单个服务的监听
+func (w *Watcher) sync(ctx context.Context, catalog map[string][]string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	wanted := make(map[string]bool, len(catalog))
+	for name, tags := range catalog {
+		if name == "consul" || (w.opts.Tag != "" && !slices.Contains(tags, w.opts.Tag)) {
+			continue
+		}
+		wanted[name] = true
+		if _, ok := w.cancels[name]; ok {
+			continue
+		}
+		svcCtx, cancel := context.WithCancel(ctx)
+		w.cancels[name] = cancel
+		go w.watchService(svcCtx, name)
+		slog.Info("Watching service", slog.String("service", name))
+	}
+
+	removed := false
+	for name, cancel := range w.cancels {
+		if wanted[name] {
+			continue
+		}
+		cancel()
+		delete(w.cancels, name)
+		delete(w.services, name)
+		removed = true
+		slog.Info("Service removed from catalog", slog.String("service", name))
+	}
+	if removed {
+		w.notify()
+	}
+}
+
+// watchService 监听单个服务的健康实例
+func (w *Watcher) watchService(ctx context.Context, name string) {
+	consul.BlockingQuery(ctx, w.watchOptions(func(err error) {
+		slog.Warn("Failed to watch service health", slog.String("service", name), logger.Err(err))
+	}), func(q *capi.QueryOptions) (*capi.QueryMeta, error) {
+		entries, meta, err := w.client.Health().Service(name, w.opts.Tag, true, q)
+		if err != nil {
+			return nil, err
+		}
+
+		svc := toService(name, entries)
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		// 服务已被移除时不再写回
+		if _, ok := w.cancels[name]; ok && ctx.Err() == nil {
+			// 没有健康实例时保留原有路由，让 Envoy 返回 503 而不是 404
+			if old, ok := w.services[name]; ok && len(entries) == 0 {
+				svc.RouterPrefix, svc.NoAuth = old.RouterPrefix, old.NoAuth
+			}
+			w.services[name] = svc
+			w.notify()
+		}
+		return meta, nil
+	})
+}
+
+// watchOptions 阻塞查询参数，onError 记录查询失败
+func (w *Watcher) watchOptions(onError func(error)) consul.WatchOptions {
+	return consul.WatchOptions{WaitTime: w.opts.WaitTime, RetryInterval: w.opts.RetryInterval, OnError: onError}
+}
+
+func (w *Watcher) notify() {
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+// toService 汇总健康实例，路由元数据取 ID 最小的实例，保证结果稳定
+func toService(name string, entries []*capi.ServiceEntry) Service {
+	slices.SortFunc(entries, func(a, b *capi.ServiceEntry) int { return strings.Compare(a.Service.ID, b.Service.ID) })
+
+	svc := Service{Name: name, Endpoints: make([]Endpoint, 0, len(entries))}
+	for i, e := range entries {
+		if i == 0 {
+			svc.RouterPrefix = e.Service.Meta[MetaRouterPrefix]
+			svc.NoAuth, _ = strconv.ParseBool(e.Service.Meta[MetaNoAuth])
+		}
+		addr := e.Service.Address
+		if addr == "" {
+			addr = e.Node.Address
+		}
+		svc.Endpoints = append(svc.Endpoints, Endpoint{Address: addr, Port: e.Service.Port})
+	}
+	return svc
+}
@@ -0,0 +1,162 @@
+// Package extauthz 实现 Envoy 外部鉴权服务：从查询参数、请求头或 Cookie 中读取 token，
+// 校验通过后通过 x-user-id、x-client-id 请求头把身份传给上游，失败时返回 401 / 403
+package extauthz
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderUserID 注入上游的用户 ID 请求头
+	HeaderUserID = "x-user-id"
+	// HeaderClientID 注入上游的客户端 ID 请求头
+	HeaderClientID = "x-client-id"
+)
+
+// Request 鉴权所需的原始请求信息
+type Request struct {
+	// Path 原始请求路径，包含查询参数
+	Path string
+	// Headers 请求头，名称为小写
+	Headers map[string]string
+}
+
+// Identity 校验通过的身份
+type Identity struct {
+	UserID   string
+	ClientID string
+}
+
+// Decision 鉴权结果
+type Decision struct {
+	// Status HTTP 状态码：200 放行，401 未认证，403 无权限
+	Status int
+	// Reason 拒绝原因，会返回给客户端
+	Reason string
+	// Identity 放行时的身份
+	Identity Identity
+	// StripQuery 放行时需从上游请求中移除的查询参数
+	StripQuery string
+}
+
+// Allowed 是否放行
+func (d Decision) Allowed() bool { return d.Status == http.StatusOK }
+
+// Authorizer 基于静态 token 表的鉴权器
+type Authorizer struct {
+	cred   Credential
+	tokens map[string]Token
+	now    func() time.Time
+}
+
+// NewAuthorizer 创建鉴权器，token 为空或重复时返回错误
+func NewAuthorizer(cfg Config) (*Authorizer, error) {
+	a := &Authorizer{
+		cred:   cfg.Credential,
+		tokens: make(map[string]Token, len(cfg.Tokens)),
+		now:    time.Now,
+	}
+	for i, t := range cfg.Tokens {
+		switch {
+		case t.Token == "":
+			return nil, fmt.Errorf("tokens[%d]: token is required", i)
+		case t.UserID == "":
+			return nil, fmt.Errorf("tokens[%d]: user_id is required", i)
+		}
+		if _, ok := a.tokens[t.Token]; ok {
+			return nil, fmt.Errorf("tokens[%d]: duplicate token for user %q", i, t.UserID)
+		}
+		a.tokens[t.Token] = t
+	}
+	if a.cred.Query == "" && a.cred.Header == "" && a.cred.Cookie == "" {
+		return nil, errors.New("credential: at least one of query, header and cookie is required")
+	}
+	return a, nil
+}
+
+// Authorize 校验请求中的 token
+func (a *Authorizer) Authorize(req Request) Decision {
+	token, from := a.credential(req)
+	if token == "" {
+		return Decision{Status: http.StatusUnauthorized, Reason: "missing token"}
+	}
+	t, ok := a.tokens[token]
+	if !ok {
+		return Decision{Status: http.StatusUnauthorized, Reason: "invalid token"}
+	}
+	if !t.ExpiresAt.IsZero() && a.now().After(t.ExpiresAt) {
+		return Decision{Status: http.StatusUnauthorized, Reason: "token expired"}
+	}
+	if t.Disabled {
+		return Decision{Status: http.StatusForbidden, Reason: "token disabled"}
+	}
+	if !allowedPath(t.Paths, req.Path) {
+		return Decision{Status: http.StatusForbidden, Reason: "path not allowed"}
+	}
+
+	d := Decision{
+		Status:   http.StatusOK,
+		Identity: Identity{UserID: t.UserID, ClientID: t.ClientID},
+	}
+	if from == "query" && (a.cred.StripQuery == nil || *a.cred.StripQuery) {
+		d.StripQuery = a.cred.Query
+	}
+	return d
+}
+
+// credential 按查询参数、请求头、Cookie 的顺序读取 token，返回 token 与来源
+func (a *Authorizer) credential(req Request) (string, string) {
+	if a.cred.Query != "" {
+		if _, rawQuery, ok := strings.Cut(req.Path, "?"); ok {
+			if q, err := url.ParseQuery(rawQuery); err == nil {
+				if v := strings.TrimSpace(q.Get(a.cred.Query)); v != "" {
+					return bearer(v), "query"
+				}
+			}
+		}
+	}
+	if a.cred.Header != "" {
+		if v := strings.TrimSpace(req.Headers[strings.ToLower(a.cred.Header)]); v != "" {
+			return bearer(v), "header"
+		}
+	}
+	if a.cred.Cookie != "" {
+		if raw := req.Headers["cookie"]; raw != "" {
+			cookies, _ := http.ParseCookie(raw)
+			for _, c := range cookies {
+				if c.Name == a.cred.Cookie && c.Value != "" {
+					return c.Value, "cookie"
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+// bearer 去掉可选的 "Bearer " 前缀，前端配置面板允许直接填写 "Bearer xxx"
+func bearer(v string) string {
+	if len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+		return strings.TrimSpace(v[7:])
+	}
+	return v
+}
+
+// allowedPath 路径前缀按段匹配，/ws 匹配 /ws 与 /ws/...，不匹配 /wsx
+func allowedPath(prefixes []string, path string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	path, _, _ = strings.Cut(path, "?")
+	for _, p := range prefixes {
+		p = strings.TrimSuffix(p, "/")
+		if p == "" || path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package extauthz
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc/codes"
+)
+
+func demoAuthorizer(t *testing.T) *Authorizer {
+	t.Helper()
+	cfg, err := LoadConfig("../../ext-authz-demo/config.yaml")
+	if err != nil {
+		t.Fatalf("加载示例配置失败: %v", err)
+	}
+	a, err := NewAuthorizer(cfg)
+	if err != nil {
+		t.Fatalf("创建鉴权器失败: %v", err)
+	}
+	a.now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+	return a
+}
+
+func TestAuthorize(t *testing.T) {
+	a := demoAuthorizer(t)
+	for _, tc := range []struct {
+		name    string
+		req     Request
+		status  int
+		user    string
+		stripQS string
+	}{
+		{"查询参数", Request{Path: "/ws?token=demo-token&user_id=x"}, 200, "user123", "token"},
+		{"Bearer 查询参数", Request{Path: "/ws?token=Bearer%20demo-token"}, 200, "user123", "token"},
+		{"请求头", Request{Path: "/api", Headers: map[string]string{"authorization": "Bearer demo-token"}}, 200, "user123", ""},
+		{"Cookie", Request{Path: "/api", Headers: map[string]string{"cookie": "a=1; token=demo-token"}}, 200, "user123", ""},
+		{"缺少 token", Request{Path: "/ws"}, 401, "", ""},
+		{"无效 token", Request{Path: "/ws?token=nope"}, 401, "", ""},
+		{"过期", Request{Path: "/ws?token=expired-token"}, 401, "", ""},
+		{"禁用", Request{Path: "/ws?token=disabled-token"}, 403, "", ""},
+		{"路径允许", Request{Path: "/ws/chat?token=ws-only-token"}, 200, "user456", "token"},
+		{"路径不允许", Request{Path: "/wsx?token=ws-only-token"}, 403, "", ""},
+	} {
+		d := a.Authorize(tc.req)
+		if d.Status != tc.status || d.Identity.UserID != tc.user || d.StripQuery != tc.stripQS {
+			t.Errorf("%s: 结果错误 %+v", tc.name, d)
+		}
+	}
+}
+
+func TestNewAuthorizer(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Tokens = []Token{{Token: "a", UserID: "u"}, {Token: "a", UserID: "v"}}
+	if _, err := NewAuthorizer(cfg); err == nil {
+		t.Fatalf("重复 token 应返回错误")
+	}
+	cfg.Tokens = []Token{{Token: "a"}}
+	if _, err := NewAuthorizer(cfg); err == nil {
+		t.Fatalf("缺少 user_id 应返回错误")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := NewServer(demoAuthorizer(t))
+	check := func(path string, headers map[string]string) *authv3.CheckResponse {
+		resp, err := s.Check(context.Background(), &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{Method: "GET", Path: path, Headers: headers},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("Check 失败: %v", err)
+		}
+		return resp
+	}
+
+	resp := check("/ws?token=demo-token", map[string]string{HeaderUserID: "spoofed"})
+	if codes.Code(resp.GetStatus().GetCode()) != codes.OK {
+		t.Fatalf("应放行: %v", resp.GetStatus())
+	}
+	ok := resp.GetOkResponse()
+	got := map[string]string{}
+	for _, h := range ok.GetHeaders() {
+		got[h.GetHeader().GetKey()] = h.GetHeader().GetValue()
+	}
+	if got[HeaderUserID] != "user123" || got[HeaderClientID] != "client-abc" {
+		t.Fatalf("注入的身份头错误: %v", got)
+	}
+	if !slices.Equal(ok.GetQueryParametersToRemove(), []string{"token"}) {
+		t.Fatalf("应移除查询参数中的 token: %v", ok.GetQueryParametersToRemove())
+	}
+
+	resp = check("/ws", nil)
+	if codes.Code(resp.GetStatus().GetCode()) != codes.Unauthenticated || resp.GetDeniedResponse().GetStatus().GetCode() != 401 {
+		t.Fatalf("缺少 token 应返回 401: %v", resp)
+	}
+	resp = check("/ws?token=disabled-token", nil)
+	if codes.Code(resp.GetStatus().GetCode()) != codes.PermissionDenied || resp.GetDeniedResponse().GetStatus().GetCode() != 403 {
+		t.Fatalf("禁用 token 应返回 403: %v", resp)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	srv := httptest.NewServer(NewServer(demoAuthorizer(t)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ws?token=demo-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 || resp.Header.Get(HeaderUserID) != "user123" || resp.Header.Get(HeaderClientID) != "client-abc" {
+		t.Fatalf("应放行并返回身份头: %d %v", resp.StatusCode, resp.Header)
+	}
+
+	resp, err = http.Get(srv.URL + "/ws?token=expired-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 401 || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Fatalf("过期 token 应返回 401: %d %v", resp.StatusCode, resp.Header)
+	}
+}
@@ -0,0 +1,70 @@
+package extauthz
+
+import (
+	"time"
+
+	"github.com/lyonmu/demo/envoy-demo/internal/config"
+)
+
+// EnvConfigFile 配置文件路径环境变量
+const EnvConfigFile = "EXT_AUTHZ_CONFIG"
+
+// Config ext-authz 配置
+type Config struct {
+	// GRPCListen envoy.service.auth.v3.Authorization gRPC 监听地址
+	GRPCListen string `yaml:"grpc_listen"`
+	// HTTPListen HTTP 鉴权服务监听地址，为空时不启用
+	HTTPListen string `yaml:"http_listen"`
+	// Credential token 的读取位置
+	Credential Credential `yaml:"credential"`
+	// Tokens 静态 token 表
+	Tokens []Token `yaml:"tokens"`
+}
+
+// Credential token 依次从查询参数、请求头、Cookie 中读取，名称为空时跳过该位置
+type Credential struct {
+	// Query 查询参数名，浏览器 WebSocket 无法设置请求头时使用，默认 token
+	Query string `yaml:"query"`
+	// Header 请求头名，支持 "Bearer " 前缀，默认 authorization
+	Header string `yaml:"header"`
+	// Cookie Cookie 名，默认 token
+	Cookie string `yaml:"cookie"`
+	// StripQuery 鉴权通过后从转发给上游的请求中移除查询参数中的 token，默认 true
+	StripQuery *bool `yaml:"strip_query"`
+}
+
+// Token 一个可用的 token 及其身份
+type Token struct {
+	Token    string `yaml:"token"`
+	UserID   string `yaml:"user_id"`
+	ClientID string `yaml:"client_id"`
+	// ExpiresAt 过期时间（RFC 3339），为空时不过期
+	ExpiresAt time.Time `yaml:"expires_at"`
+	// Disabled 被禁用的 token 返回 403
+	Disabled bool `yaml:"disabled"`
+	// Paths 允许访问的路径前缀，为空时不限制，其余路径返回 403
+	Paths []string `yaml:"paths"`
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() Config {
+	strip := true
+	return Config{
+		GRPCListen: ":9191",
+		Credential: Credential{
+			Query:      "token",
+			Header:     "authorization",
+			Cookie:     "token",
+			StripQuery: &strip,
+		},
+	}
+}
+
+// LoadConfig 加载配置文件，path 为空时读取 EXT_AUTHZ_CONFIG
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if err := config.Load(path, EnvConfigFile, false, &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,178 @@
+package extauthz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Server 同时提供 gRPC 与 HTTP 两种 Envoy 外部鉴权协议
+type Server struct {
+	authv3.UnimplementedAuthorizationServer
+	authz *Authorizer
+}
+
+// NewServer 创建鉴权服务
+func NewServer(authz *Authorizer) *Server {
+	return &Server{authz: authz}
+}
+
+// Check 实现 envoy.service.auth.v3.Authorization
+func (s *Server) Check(_ context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	httpReq := req.GetAttributes().GetRequest().GetHttp()
+	d := s.decide(Request{Path: httpReq.GetPath(), Headers: httpReq.GetHeaders()}, httpReq.GetMethod())
+
+	if !d.Allowed() {
+		code := codes.PermissionDenied
+		if d.Status == http.StatusUnauthorized {
+			code = codes.Unauthenticated
+		}
+		return &authv3.CheckResponse{
+			Status: &rpcstatus.Status{Code: int32(code), Message: d.Reason},
+			HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: &authv3.DeniedHttpResponse{
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode(d.Status)},
+				Headers: headerOptions(deniedHeaders(d)),
+				Body:    deniedBody(d),
+			}},
+		}, nil
+	}
+
+	ok := &authv3.OkHttpResponse{}
+	// 覆盖客户端自带的同名请求头，上游只能看到鉴权服务写入的身份
+	ok.Headers = headerOptions(map[string]string{HeaderUserID: d.Identity.UserID})
+	if d.Identity.ClientID != "" {
+		ok.Headers = append(ok.Headers, headerOptions(map[string]string{HeaderClientID: d.Identity.ClientID})...)
+	} else {
+		ok.HeadersToRemove = []string{HeaderClientID}
+	}
+	if d.StripQuery != "" {
+		ok.QueryParametersToRemove = []string{d.StripQuery}
+	}
+	return &authv3.CheckResponse{
+		Status:       &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: ok},
+	}, nil
+}
+
+// ServeHTTP 实现 ext_authz 的 HTTP 协议：Envoy 以原始方法、路径与请求头调用本服务，
+// 返回 200 时通过 authorization_response.allowed_upstream_headers 把身份头带给上游，
+// 其余状态码连同响应头、响应体直接返回给客户端
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	headers := make(map[string]string, len(r.Header))
+	for k, v := range r.Header {
+		headers[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	d := s.decide(Request{Path: r.URL.RequestURI(), Headers: headers}, r.Method)
+
+	if !d.Allowed() {
+		for k, v := range deniedHeaders(d) {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(d.Status)
+		_, _ = w.Write([]byte(deniedBody(d)))
+		return
+	}
+
+	w.Header().Set(HeaderUserID, d.Identity.UserID)
+	if d.Identity.ClientID != "" {
+		w.Header().Set(HeaderClientID, d.Identity.ClientID)
+	} else {
+		// HTTP 协议下通过该响应头让 Envoy 删除上游请求中的同名头
+		w.Header().Set("x-envoy-auth-headers-to-remove", HeaderClientID)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) decide(req Request, method string) Decision {
+	d := s.authz.Authorize(req)
+	path, _, _ := strings.Cut(req.Path, "?")
+	if d.Allowed() {
+		slog.Debug("Request authorized",
+			slog.String("method", method),
+			slog.String("path", path),
+			slog.String("user_id", d.Identity.UserID),
+			slog.String("client_id", d.Identity.ClientID))
+	} else {
+		slog.Info("Request denied",
+			slog.String("method", method),
+			slog.String("path", path),
+			slog.Int("status", d.Status),
+			slog.String("reason", d.Reason))
+	}
+	return d
+}
+
+func deniedHeaders(d Decision) map[string]string {
+	h := map[string]string{"content-type": "application/json"}
+	if d.Status == http.StatusUnauthorized {
+		h["www-authenticate"] = `Bearer realm="envoy", error="invalid_token"`
+	}
+	return h
+}
+
+func deniedBody(d Decision) string {
+	body, _ := json.Marshal(map[string]string{"error": d.Reason})
+	return string(body)
+}
+
+func headerOptions(h map[string]string) []*core.HeaderValueOption {
+	opts := make([]*core.HeaderValueOption, 0, len(h))
+	for k, v := range h {
+		opts = append(opts, &core.HeaderValueOption{
+			Header:       &core.HeaderValue{Key: k, Value: v},
+			AppendAction: core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+		})
+	}
+	return opts
+}
+
+// ListenGRPC 在 addr 上启动 gRPC 鉴权服务，ctx 结束时优雅退出
+func ListenGRPC(ctx context.Context, addr string, s *Server) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	grpcServer := grpc.NewServer()
+	authv3.RegisterAuthorizationServer(grpcServer, s)
+
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+
+	slog.Info("ext_authz gRPC server listening", slog.String("addr", lis.Addr().String()))
+	if err := grpcServer.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// ListenHTTP 在 addr 上启动 HTTP 鉴权服务，ctx 结束时优雅退出
+func ListenHTTP(ctx context.Context, addr string, s *Server) error {
+	srv := &http.Server{Addr: addr, Handler: s, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("ext_authz HTTP server listening", slog.String("addr", addr))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,471 @@
+package proxyspec
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	ratelimitconf "github.com/envoyproxy/go-control-plane/envoy/config/ratelimit/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tracecfg "github.com/envoyproxy/go-control-plane/envoy/config/trace/v3"
+	grpcaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	stream "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/stream/v3"
+	ratelimitcommon "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	ratelimithttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+	router "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	ratelimitnet "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/ratelimit/v3"
+	tcpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	upstreamhttp "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// StdoutAccessLog 访问日志扩展名称
+	StdoutAccessLog = "envoy.access_loggers.stdout"
+	// GRPCAccessLogCluster 访问日志服务集群名称
+	GRPCAccessLogCluster = "access_log_service"
+	// HTTPGRPCAccessLog http 模式的 gRPC 访问日志扩展名称
+	HTTPGRPCAccessLog = "envoy.access_loggers.http_grpc"
+	// TCPGRPCAccessLog tcp 模式的 gRPC 访问日志扩展名称
+	TCPGRPCAccessLog = "envoy.access_loggers.tcp_grpc"
+	// RateLimitCluster 限流服务集群名称
+	RateLimitCluster = "rate_limit_service"
+	// RateLimitFilter http 模式的限流过滤器名称
+	RateLimitFilter = "envoy.filters.http.ratelimit"
+	// NetworkRateLimitFilter tcp 模式的限流过滤器名称
+	NetworkRateLimitFilter = "envoy.filters.network.ratelimit"
+	// TracingCluster OTLP 接收端集群名称
+	TracingCluster = "opentelemetry_collector"
+	// OpenTelemetryTracer OpenTelemetry tracer 扩展名称
+	OpenTelemetryTracer = "envoy.tracers.opentelemetry"
+)
+
+// BuildOptions 资源生成参数
+type BuildOptions struct {
+	// SDS 为 true 时证书以 Secret 资源通过 ADS 下发（内容内联），否则监听器直接引用证书文件路径
+	SDS bool
+	// CertPath 非 SDS 模式下改写证书路径（如映射为容器内路径），参数为相对声明文件解析后的路径
+	CertPath func(string) string
+}
+
+// Build 生成 LDS、CDS 资源，SDS 模式下还会读取证书文件生成 Secret 资源
+func Build(spec Spec, opts BuildOptions) (map[resource.Type][]types.Resource, error) {
+	listeners := make([]types.Resource, 0, len(spec.Proxies))
+	clusters := make([]types.Resource, 0, len(spec.Proxies))
+	for _, p := range spec.Proxies {
+		l, err := makeListener(spec, p, opts)
+		if err != nil {
+			return nil, err
+		}
+		c, err := makeCluster(ClusterName(p), p.Upstreams, p.ConnectTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("proxy %s: %w", p.Name, err)
+		}
+		listeners = append(listeners, l)
+		clusters = append(clusters, c)
+	}
+	if spec.RateLimit != nil {
+		c, err := grpcCluster(RateLimitCluster, spec.RateLimit.Address)
+		if err != nil {
+			return nil, fmt.Errorf("rate_limit: %w", err)
+		}
+		clusters = append(clusters, c)
+	}
+	if spec.AccessLogService != nil {
+		c, err := grpcCluster(GRPCAccessLogCluster, spec.AccessLogService.Address)
+		if err != nil {
+			return nil, fmt.Errorf("access_log_service: %w", err)
+		}
+		clusters = append(clusters, c)
+	}
+	if spec.Tracing != nil {
+		c, err := grpcCluster(TracingCluster, spec.Tracing.Address)
+		if err != nil {
+			return nil, fmt.Errorf("tracing: %w", err)
+		}
+		clusters = append(clusters, c)
+	}
+
+	out := map[resource.Type][]types.Resource{
+		resource.ListenerType: listeners,
+		resource.ClusterType:  clusters,
+	}
+	if opts.SDS {
+		secrets := make([]types.Resource, 0, len(spec.Certificates))
+		for _, c := range spec.Certificates {
+			s, err := makeSecret(spec, c)
+			if err != nil {
+				return nil, err
+			}
+			secrets = append(secrets, s)
+		}
+		out[resource.SecretType] = secrets
+	}
+	return out, nil
+}
+
+// ListenerName 监听器名称
+func ListenerName(p Proxy) string { return "listener_" + p.Name }
+
+// ClusterName 集群名称
+func ClusterName(p Proxy) string { return "service_" + p.Name }
+
+func makeListener(spec Spec, p Proxy, opts BuildOptions) (*listener.Listener, error) {
+	var (
+		filters []*listener.Filter
+		err     error
+	)
+	if p.Mode == ModeTCP {
+		filters, err = tcpFilters(spec, p)
+	} else {
+		filters, err = httpFilters(spec, p)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	chain := &listener.FilterChain{Filters: filters}
+	if p.Certificate != "" {
+		ts, err := downstreamTLS(spec, p.Certificate, opts)
+		if err != nil {
+			return nil, err
+		}
+		chain.TransportSocket = ts
+	}
+
+	return &listener.Listener{
+		Name:         ListenerName(p),
+		Address:      socketAddress(p.Address, uint32(p.Listen)),
+		FilterChains: []*listener.FilterChain{chain},
+	}, nil
+}
+
+func httpFilters(spec Spec, p Proxy) ([]*listener.Filter, error) {
+	routerCfg, err := anypb.New(&router.Router{})
+	if err != nil {
+		return nil, err
+	}
+	vhost := &route.VirtualHost{
+		Name:    "local_service_" + p.Name,
+		Domains: []string{"*"},
+		Routes: []*route.Route{{
+			Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"}},
+			Action: &route.Route_Route{Route: &route.RouteAction{
+				ClusterSpecifier: &route.RouteAction_Cluster{Cluster: ClusterName(p)},
+			}},
+		}},
+	}
+	httpFilters := []*hcm.HttpFilter{{
+		Name:       wellknown.Router,
+		ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: routerCfg},
+	}}
+	if rl := spec.RateLimit; rl != nil {
+		rlCfg, err := anypb.New(&ratelimithttp.RateLimit{
+			Domain:                  rl.Domain,
+			Timeout:                 durationpb.New(rl.Timeout),
+			FailureModeDeny:         rl.FailureModeDeny,
+			EnableXRatelimitHeaders: ratelimithttp.RateLimit_DRAFT_VERSION_03,
+			RateLimitService:        rateLimitService(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		httpFilters = append([]*hcm.HttpFilter{{
+			Name:       RateLimitFilter,
+			ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: rlCfg},
+		}}, httpFilters...)
+		// 两组描述符分别计数：每个客户端 IP、每个监听器
+		vhost.RateLimits = []*route.RateLimit{
+			{Actions: []*route.RateLimit_Action{{
+				ActionSpecifier: &route.RateLimit_Action_RemoteAddress_{RemoteAddress: &route.RateLimit_Action_RemoteAddress{}},
+			}}},
+			{Actions: []*route.RateLimit_Action{{
+				ActionSpecifier: &route.RateLimit_Action_GenericKey_{GenericKey: &route.RateLimit_Action_GenericKey{
+					DescriptorKey:   "listener",
+					DescriptorValue: p.Name,
+				}},
+			}}},
+		}
+	}
+
+	manager := &hcm.HttpConnectionManager{
+		StatPrefix: "ingress_http_" + p.Name,
+		CodecType:  hcm.HttpConnectionManager_AUTO,
+		RouteSpecifier: &hcm.HttpConnectionManager_RouteConfig{
+			RouteConfig: &route.RouteConfiguration{
+				Name:         "local_route_" + p.Name,
+				VirtualHosts: []*route.VirtualHost{vhost},
+			},
+		},
+		HttpFilters: httpFilters,
+	}
+	if spec.Tracing != nil {
+		t, err := tracing(spec.Tracing)
+		if err != nil {
+			return nil, err
+		}
+		manager.Tracing = t
+	}
+	if p.WebSocket {
+		manager.UpgradeConfigs = []*hcm.HttpConnectionManager_UpgradeConfig{{UpgradeType: "websocket"}}
+	}
+	if *p.AccessLog {
+		al, err := accessLogs(spec, p)
+		if err != nil {
+			return nil, err
+		}
+		manager.AccessLog = al
+	}
+	f, err := typedFilter(wellknown.HTTPConnectionManager, manager)
+	if err != nil {
+		return nil, err
+	}
+	return []*listener.Filter{f}, nil
+}
+
+// tracing OpenTelemetry tracer 配置，span 通过 EnvoyGrpc 上报到 TracingCluster
+func tracing(t *Tracing) (*hcm.HttpConnectionManager_Tracing, error) {
+	cfg, err := anypb.New(&tracecfg.OpenTelemetryConfig{
+		GrpcService: &core.GrpcService{TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
+			EnvoyGrpc: &core.GrpcService_EnvoyGrpc{ClusterName: TracingCluster},
+		}},
+		ServiceName: t.ServiceName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hcm.HttpConnectionManager_Tracing{
+		RandomSampling: &typev3.Percent{Value: *t.Sampling},
+		Provider: &tracecfg.Tracing_Http{
+			Name:       OpenTelemetryTracer,
+			ConfigType: &tracecfg.Tracing_Http_TypedConfig{TypedConfig: cfg},
+		},
+	}, nil
+}
+
+func tcpFilters(spec Spec, p Proxy) ([]*listener.Filter, error) {
+	var filters []*listener.Filter
+	if rl := spec.RateLimit; rl != nil {
+		// 网络层限流在新建连接时按监听器计数
+		f, err := typedFilter(NetworkRateLimitFilter, &ratelimitnet.RateLimit{
+			StatPrefix: "ratelimit_" + p.Name,
+			Domain:     rl.Domain,
+			Descriptors: []*ratelimitcommon.RateLimitDescriptor{{
+				Entries: []*ratelimitcommon.RateLimitDescriptor_Entry{{Key: "listener", Value: p.Name}},
+			}},
+			Timeout:          durationpb.New(rl.Timeout),
+			FailureModeDeny:  rl.FailureModeDeny,
+			RateLimitService: rateLimitService(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	proxy := &tcpproxy.TcpProxy{
+		StatPrefix:       "ingress_tcp_" + p.Name,
+		ClusterSpecifier: &tcpproxy.TcpProxy_Cluster{Cluster: ClusterName(p)},
+	}
+	if *p.AccessLog {
+		al, err := accessLogs(spec, p)
+		if err != nil {
+			return nil, err
+		}
+		proxy.AccessLog = al
+	}
+	f, err := typedFilter(wellknown.TCPProxy, proxy)
+	if err != nil {
+		return nil, err
+	}
+	return append(filters, f), nil
+}
+
+func rateLimitService() *ratelimitconf.RateLimitServiceConfig {
+	return &ratelimitconf.RateLimitServiceConfig{
+		TransportApiVersion: core.ApiVersion_V3,
+		GrpcService: &core.GrpcService{TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
+			EnvoyGrpc: &core.GrpcService_EnvoyGrpc{ClusterName: RateLimitCluster},
+		}},
+	}
+}
+
+// grpcCluster 限流、访问日志等 gRPC 服务的集群，gRPC 需要 HTTP/2
+func grpcCluster(name, addr string) (*cluster.Cluster, error) {
+	c, err := makeCluster(name, []string{addr}, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := anypb.New(&upstreamhttp.HttpProtocolOptions{
+		UpstreamProtocolOptions: &upstreamhttp.HttpProtocolOptions_ExplicitHttpConfig_{
+			ExplicitHttpConfig: &upstreamhttp.HttpProtocolOptions_ExplicitHttpConfig{
+				ProtocolConfig: &upstreamhttp.HttpProtocolOptions_ExplicitHttpConfig_Http2ProtocolOptions{
+					Http2ProtocolOptions: &core.Http2ProtocolOptions{},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.TypedExtensionProtocolOptions = map[string]*anypb.Any{
+		"envoy.extensions.upstreams.http.v3.HttpProtocolOptions": opts,
+	}
+	return c, nil
+}
+
+func typedFilter(name string, cfg proto.Message) (*listener.Filter, error) {
+	a, err := anypb.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &listener.Filter{Name: name, ConfigType: &listener.Filter_TypedConfig{TypedConfig: a}}, nil
+}
+
+// accessLogs 访问日志输出到 stdout，配置了访问日志服务时同时以监听器名称为 log_name 上报
+func accessLogs(spec Spec, p Proxy) ([]*accesslog.AccessLog, error) {
+	stdout, err := typedAccessLog(StdoutAccessLog, &stream.StdoutAccessLog{})
+	if err != nil {
+		return nil, err
+	}
+	logs := []*accesslog.AccessLog{stdout}
+	if spec.AccessLogService == nil {
+		return logs, nil
+	}
+
+	common := &grpcaccesslog.CommonGrpcAccessLogConfig{
+		LogName:             ListenerName(p),
+		TransportApiVersion: core.ApiVersion_V3,
+		GrpcService: &core.GrpcService{TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
+			EnvoyGrpc: &core.GrpcService_EnvoyGrpc{ClusterName: GRPCAccessLogCluster},
+		}},
+	}
+	var grpcLog *accesslog.AccessLog
+	if p.Mode == ModeTCP {
+		grpcLog, err = typedAccessLog(TCPGRPCAccessLog, &grpcaccesslog.TcpGrpcAccessLogConfig{CommonConfig: common})
+	} else {
+		grpcLog, err = typedAccessLog(HTTPGRPCAccessLog, &grpcaccesslog.HttpGrpcAccessLogConfig{CommonConfig: common})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(logs, grpcLog), nil
+}
+
+func typedAccessLog(name string, cfg proto.Message) (*accesslog.AccessLog, error) {
+	a, err := anypb.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &accesslog.AccessLog{Name: name, ConfigType: &accesslog.AccessLog_TypedConfig{TypedConfig: a}}, nil
+}
+
+func downstreamTLS(spec Spec, name string, opts BuildOptions) (*core.TransportSocket, error) {
+	common := &tls.CommonTlsContext{}
+	if opts.SDS {
+		common.TlsCertificateSdsSecretConfigs = []*tls.SdsSecretConfig{{
+			Name: name,
+			SdsConfig: &core.ConfigSource{
+				ResourceApiVersion:    core.ApiVersion_V3,
+				ConfigSourceSpecifier: &core.ConfigSource_Ads{Ads: &core.AggregatedConfigSource{}},
+			},
+		}}
+	} else {
+		certPath := spec.Path
+		if opts.CertPath != nil {
+			certPath = func(p string) string { return opts.CertPath(spec.Path(p)) }
+		}
+		cert, _ := spec.Certificate(name)
+		common.TlsCertificates = []*tls.TlsCertificate{{
+			CertificateChain: fileSource(certPath(cert.CertFile)),
+			PrivateKey:       fileSource(certPath(cert.KeyFile)),
+		}}
+	}
+
+	a, err := anypb.New(&tls.DownstreamTlsContext{CommonTlsContext: common})
+	if err != nil {
+		return nil, err
+	}
+	return &core.TransportSocket{
+		Name:       wellknown.TransportSocketTLS,
+		ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: a},
+	}, nil
+}
+
+func makeSecret(spec Spec, c Certificate) (*tls.Secret, error) {
+	chain, err := os.ReadFile(spec.Path(c.CertFile))
+	if err != nil {
+		return nil, fmt.Errorf("certificate %q: %w", c.Name, err)
+	}
+	key, err := os.ReadFile(spec.Path(c.KeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("certificate %q: %w", c.Name, err)
+	}
+	return &tls.Secret{
+		Name: c.Name,
+		Type: &tls.Secret_TlsCertificate{TlsCertificate: &tls.TlsCertificate{
+			CertificateChain: &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: chain}},
+			PrivateKey:       &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: key}},
+		}},
+	}, nil
+}
+
+// makeCluster 上游全部为 IP 时使用 STATIC，否则使用 STRICT_DNS
+func makeCluster(name string, upstreams []string, connectTimeout time.Duration) (*cluster.Cluster, error) {
+	discovery := cluster.Cluster_STATIC
+	lbEndpoints := make([]*endpoint.LbEndpoint, 0, len(upstreams))
+	for _, u := range upstreams {
+		host, port, err := splitHostPort(u)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %q: %w", u, err)
+		}
+		if net.ParseIP(host) == nil {
+			discovery = cluster.Cluster_STRICT_DNS
+		}
+		lbEndpoints = append(lbEndpoints, &endpoint.LbEndpoint{
+			HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+				Endpoint: &endpoint.Endpoint{Address: socketAddress(host, port)},
+			},
+		})
+	}
+
+	return &cluster.Cluster{
+		Name:                 name,
+		ConnectTimeout:       durationpb.New(connectTimeout),
+		ClusterDiscoveryType: &cluster.Cluster_Type{Type: discovery},
+		LbPolicy:             cluster.Cluster_ROUND_ROBIN,
+		LoadAssignment: &endpoint.ClusterLoadAssignment{
+			ClusterName: name,
+			Endpoints:   []*endpoint.LocalityLbEndpoints{{LbEndpoints: lbEndpoints}},
+		},
+	}, nil
+}
+
+func socketAddress(host string, port uint32) *core.Address {
+	return &core.Address{
+		Address: &core.Address_SocketAddress{
+			SocketAddress: &core.SocketAddress{
+				Address:       host,
+				PortSpecifier: &core.SocketAddress_PortValue{PortValue: port},
+			},
+		},
+	}
+}
+
+func fileSource(path string) *core.DataSource {
+	return &core.DataSource{Specifier: &core.DataSource_Filename{Filename: path}}
+}
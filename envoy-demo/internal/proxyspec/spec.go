@@ -0,0 +1,323 @@
+// Package proxyspec 定义 L4/L7 代理的精简声明，并将其转换为 Envoy 监听器、集群与证书资源
+//
+// 一条声明对应一组 listener + cluster，例如：
+//
+//	certificates:
+//	  - name: example.com
+//	    cert_file: certs/example.com.crt
+//	    key_file: certs/example.com.key
+//	proxies:
+//	  - listen: 10000
+//	    upstream: 192.168.8.99:30880
+//	    certificate: example.com
+//	    access_log: true
+package proxyspec
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Mode 代理模式
+type Mode string
+
+const (
+	// ModeHTTP L7：http_connection_manager + router
+	ModeHTTP Mode = "http"
+	// ModeTCP L4：tcp_proxy
+	ModeTCP Mode = "tcp"
+)
+
+// Spec 代理声明
+type Spec struct {
+	Defaults     Defaults      `yaml:"defaults"`
+	Certificates []Certificate `yaml:"certificates"`
+	Proxies      []Proxy       `yaml:"proxies"`
+	// RateLimit 全局限流服务，为空时不限流
+	RateLimit *RateLimit `yaml:"rate_limit"`
+	// AccessLogService 访问日志服务，为空时访问日志只输出到 stdout
+	AccessLogService *AccessLogService `yaml:"access_log_service"`
+	// Tracing OpenTelemetry 链路追踪，为空时 Envoy 不创建 span，traceparent 请求头原样透传
+	Tracing *Tracing `yaml:"tracing"`
+
+	// dir 声明文件所在目录，证书相对路径以此为基准
+	dir string
+}
+
+// Defaults 各代理未声明时使用的默认值
+type Defaults struct {
+	// Address 监听地址，默认 0.0.0.0
+	Address string `yaml:"address"`
+	// Mode 默认 http
+	Mode Mode `yaml:"mode"`
+	// ConnectTimeout 上游连接超时，默认 250ms
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// AccessLog 是否输出访问日志到 stdout（配置了 access_log_service 时同时上报）
+	AccessLog bool `yaml:"access_log"`
+}
+
+// Certificate 证书，xDS 模式下通过 SDS 下发，静态模式下按文件路径引用
+type Certificate struct {
+	Name     string `yaml:"name"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// RateLimit 全局限流服务（cmd/ratelimit），http 模式按客户端 IP 与监听器限流，tcp 模式按监听器限制新建连接
+type RateLimit struct {
+	// Address 限流服务 gRPC 地址 host:port
+	Address string `yaml:"address"`
+	// Domain 限流域，默认 l4-l7
+	Domain string `yaml:"domain"`
+	// Timeout 调用限流服务的超时，默认 100ms
+	Timeout time.Duration `yaml:"timeout"`
+	// FailureModeDeny 为 true 时限流服务不可用则拒绝请求，默认放行
+	FailureModeDeny bool `yaml:"failure_mode_deny"`
+}
+
+// AccessLogService gRPC 访问日志服务（cmd/als），开启 access_log 的代理同时上报访问日志，
+// log_name 为监听器名称
+type AccessLogService struct {
+	// Address 访问日志服务 gRPC 地址 host:port
+	Address string `yaml:"address"`
+}
+
+// Tracing http 模式的代理通过 OpenTelemetry tracer 创建 span 并以 OTLP/gRPC 上报：
+// 请求携带 traceparent 时延续上游的 trace 与采样决定，转发给上游时注入 Envoy span 的 traceparent
+type Tracing struct {
+	// Address OTLP/gRPC 接收端地址 host:port，例如 OpenTelemetry Collector 的 4317 端口
+	Address string `yaml:"address"`
+	// ServiceName span 的 service.name，默认 envoy
+	ServiceName string `yaml:"service_name"`
+	// Sampling 未携带 traceparent 的请求的采样百分比 [0, 100]，默认 100
+	Sampling *float64 `yaml:"sampling"`
+}
+
+// Proxy 一条端口映射
+type Proxy struct {
+	// Name 为空时取第一个上游的端口，生成 listener_<name>、service_<name>
+	Name string `yaml:"name"`
+	// Listen 监听端口
+	Listen int `yaml:"listen"`
+	// Address 监听地址，为空时使用 defaults.address
+	Address string `yaml:"address"`
+	// Upstream 单个上游 host:port，与 Upstreams 合并
+	Upstream string `yaml:"upstream"`
+	// Upstreams 多个上游 host:port，按轮询负载均衡
+	Upstreams []string `yaml:"upstreams"`
+	// Certificate 证书名称，为空时监听明文
+	Certificate string `yaml:"certificate"`
+	// Mode 为空时使用 defaults.mode
+	Mode Mode `yaml:"mode"`
+	// AccessLog 为空时使用 defaults.access_log
+	AccessLog *bool `yaml:"access_log"`
+	// ConnectTimeout 为空时使用 defaults.connect_timeout
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// WebSocket 为 true 时允许 WebSocket 升级（仅 http 模式）
+	WebSocket bool `yaml:"websocket"`
+}
+
+// Load 读取并校验声明文件，补全默认值
+func Load(path string) (Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Spec{}, err
+	}
+	spec, err := Parse(data)
+	if err != nil {
+		return Spec{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	spec.dir = filepath.Dir(path)
+	return spec, nil
+}
+
+// Parse 解析并校验声明内容，证书相对路径以当前目录为基准
+func Parse(data []byte) (Spec, error) {
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return Spec{}, err
+	}
+	spec.applyDefaults()
+	if err := spec.Validate(); err != nil {
+		return Spec{}, err
+	}
+	return spec, nil
+}
+
+func (s *Spec) applyDefaults() {
+	if s.Defaults.Address == "" {
+		s.Defaults.Address = "0.0.0.0"
+	}
+	if s.Defaults.Mode == "" {
+		s.Defaults.Mode = ModeHTTP
+	}
+	if s.Defaults.ConnectTimeout <= 0 {
+		s.Defaults.ConnectTimeout = 250 * time.Millisecond
+	}
+
+	if rl := s.RateLimit; rl != nil {
+		if rl.Domain == "" {
+			rl.Domain = "l4-l7"
+		}
+		if rl.Timeout <= 0 {
+			rl.Timeout = 100 * time.Millisecond
+		}
+	}
+
+	if t := s.Tracing; t != nil {
+		if t.ServiceName == "" {
+			t.ServiceName = "envoy"
+		}
+		if t.Sampling == nil {
+			all := 100.0
+			t.Sampling = &all
+		}
+	}
+
+	for i := range s.Proxies {
+		p := &s.Proxies[i]
+		if p.Upstream != "" {
+			p.Upstreams = append([]string{p.Upstream}, p.Upstreams...)
+			p.Upstream = ""
+		}
+		if p.Address == "" {
+			p.Address = s.Defaults.Address
+		}
+		if p.Mode == "" {
+			p.Mode = s.Defaults.Mode
+		}
+		if p.AccessLog == nil {
+			p.AccessLog = &s.Defaults.AccessLog
+		}
+		if p.ConnectTimeout <= 0 {
+			p.ConnectTimeout = s.Defaults.ConnectTimeout
+		}
+		if p.Name == "" && len(p.Upstreams) > 0 {
+			if _, port, err := net.SplitHostPort(p.Upstreams[0]); err == nil {
+				p.Name = port
+			}
+		}
+	}
+}
+
+// Validate 检查端口与名称唯一、上游地址合法以及证书引用存在
+func (s Spec) Validate() error {
+	var errs []error
+
+	certs := make(map[string]bool, len(s.Certificates))
+	for _, c := range s.Certificates {
+		switch {
+		case c.Name == "":
+			errs = append(errs, errors.New("certificate name is required"))
+		case certs[c.Name]:
+			errs = append(errs, fmt.Errorf("duplicate certificate %q", c.Name))
+		case c.CertFile == "" || c.KeyFile == "":
+			errs = append(errs, fmt.Errorf("certificate %q: cert_file and key_file are required", c.Name))
+		}
+		certs[c.Name] = true
+	}
+
+	if s.RateLimit != nil {
+		if _, _, err := splitHostPort(s.RateLimit.Address); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limit: address %q: %w", s.RateLimit.Address, err))
+		}
+	}
+
+	if s.AccessLogService != nil {
+		if _, _, err := splitHostPort(s.AccessLogService.Address); err != nil {
+			errs = append(errs, fmt.Errorf("access_log_service: address %q: %w", s.AccessLogService.Address, err))
+		}
+	}
+
+	if t := s.Tracing; t != nil {
+		if _, _, err := splitHostPort(t.Address); err != nil {
+			errs = append(errs, fmt.Errorf("tracing: address %q: %w", t.Address, err))
+		}
+		if *t.Sampling < 0 || *t.Sampling > 100 {
+			errs = append(errs, fmt.Errorf("tracing: sampling %v out of range [0, 100]", *t.Sampling))
+		}
+	}
+
+	names := make(map[string]bool, len(s.Proxies))
+	ports := make(map[string]string, len(s.Proxies))
+	for i, p := range s.Proxies {
+		label := p.Name
+		if label == "" {
+			label = "#" + strconv.Itoa(i)
+		}
+		if p.Name == "" {
+			errs = append(errs, fmt.Errorf("proxy %s: name is required", label))
+		} else if names[p.Name] {
+			errs = append(errs, fmt.Errorf("duplicate proxy name %q", p.Name))
+		}
+		names[p.Name] = true
+
+		if p.Listen <= 0 || p.Listen > 65535 {
+			errs = append(errs, fmt.Errorf("proxy %s: invalid listen port %d", label, p.Listen))
+		}
+		addr := net.JoinHostPort(p.Address, strconv.Itoa(p.Listen))
+		if other, ok := ports[addr]; ok {
+			errs = append(errs, fmt.Errorf("proxy %s: %s already used by %s", label, addr, other))
+		}
+		ports[addr] = label
+
+		if len(p.Upstreams) == 0 {
+			errs = append(errs, fmt.Errorf("proxy %s: upstream is required", label))
+		}
+		for _, u := range p.Upstreams {
+			if _, _, err := splitHostPort(u); err != nil {
+				errs = append(errs, fmt.Errorf("proxy %s: upstream %q: %w", label, u, err))
+			}
+		}
+		if p.Certificate != "" && !certs[p.Certificate] {
+			errs = append(errs, fmt.Errorf("proxy %s: unknown certificate %q", label, p.Certificate))
+		}
+		if p.Mode != ModeHTTP && p.Mode != ModeTCP {
+			errs = append(errs, fmt.Errorf("proxy %s: unknown mode %q", label, p.Mode))
+		}
+		if p.WebSocket && p.Mode == ModeTCP {
+			errs = append(errs, fmt.Errorf("proxy %s: websocket requires http mode", label))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Path 解析证书路径，相对路径以声明文件所在目录为基准
+func (s Spec) Path(p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(s.dir, p)
+}
+
+// Certificate 按名称查找证书
+func (s Spec) Certificate(name string) (Certificate, bool) {
+	for _, c := range s.Certificates {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Certificate{}, false
+}
+
+func splitHostPort(addr string) (string, uint32, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+	if host == "" {
+		return "", 0, errors.New("missing host")
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || n == 0 {
+		return "", 0, fmt.Errorf("invalid port %q", port)
+	}
+	return host, uint32(n), nil
+}
@@ -0,0 +1,309 @@
+package proxyspec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tracecfg "github.com/envoyproxy/go-control-plane/envoy/config/trace/v3"
+	grpcaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/lyonmu/demo/envoy-demo/internal/xds"
+)
+
+func TestLoadDemoSpec(t *testing.T) {
+	spec, err := Load("../../L4-L7-porxy-demo/proxies.yaml")
+	if err != nil {
+		t.Fatalf("加载示例声明失败: %v", err)
+	}
+	if len(spec.Proxies) != 3 || spec.Proxies[0].Name != "30880" {
+		t.Fatalf("声明解析错误: %+v", spec.Proxies)
+	}
+
+	res, err := Build(spec, BuildOptions{SDS: true})
+	if err != nil {
+		t.Fatalf("生成资源失败: %v", err)
+	}
+	// 3 个代理集群 + 限流服务集群 + 访问日志服务集群
+	if len(res[resource.ListenerType]) != 3 || len(res[resource.ClusterType]) != 5 || len(res[resource.SecretType]) != 1 {
+		t.Fatalf("资源数量错误: %d/%d/%d",
+			len(res[resource.ListenerType]), len(res[resource.ClusterType]), len(res[resource.SecretType]))
+	}
+	l := res[resource.ListenerType][0].(*listener.Listener)
+	if l.GetName() != "listener_30880" || l.GetAddress().GetSocketAddress().GetPortValue() != 10000 {
+		t.Fatalf("监听器错误: %s", l.GetName())
+	}
+	c := res[resource.ClusterType][0].(*cluster.Cluster)
+	if c.GetName() != "service_30880" || c.GetType() != cluster.Cluster_STATIC {
+		t.Fatalf("集群错误: %s %s", c.GetName(), c.GetType())
+	}
+	if rl := res[resource.ClusterType][3].(*cluster.Cluster); rl.GetName() != RateLimitCluster || len(rl.GetTypedExtensionProtocolOptions()) != 1 {
+		t.Fatalf("限流服务集群错误: %s", rl.GetName())
+	}
+	s := res[resource.SecretType][0].(*tls.Secret)
+	if !strings.Contains(string(s.GetTlsCertificate().GetCertificateChain().GetInlineBytes()), "BEGIN CERTIFICATE") {
+		t.Fatalf("证书内容未内联")
+	}
+
+	// 快照需通过一致性校验
+	if _, _, err := xds.NewPublisher(xds.NewCache(), "test").Publish(context.Background(), res); err != nil {
+		t.Fatalf("发布快照失败: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	_, err := Parse([]byte(`
+proxies:
+  - listen: 10000
+    upstream: 10.0.0.1:80
+    certificate: missing
+  - listen: 10000
+    upstream: 10.0.0.2:80
+    mode: udp
+  - listen: 10001
+    upstream: bad
+`))
+	if err == nil {
+		t.Fatalf("非法声明应校验失败")
+	}
+	for _, want := range []string{"unknown certificate", "already used", "unknown mode", "upstream \"bad\""} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("错误信息缺少 %q: %v", want, err)
+		}
+	}
+}
+
+func TestTCPAndDNS(t *testing.T) {
+	spec, err := Parse([]byte(`
+defaults:
+  mode: tcp
+proxies:
+  - name: db
+    listen: 15432
+    upstreams: [db-1.internal:5432, db-2.internal:5432]
+`))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	res, err := Build(spec, BuildOptions{})
+	if err != nil {
+		t.Fatalf("生成资源失败: %v", err)
+	}
+	l := res[resource.ListenerType][0].(*listener.Listener)
+	if got := l.GetFilterChains()[0].GetFilters()[0].GetName(); got != "envoy.filters.network.tcp_proxy" {
+		t.Fatalf("L4 模式应使用 tcp_proxy: %s", got)
+	}
+	c := res[resource.ClusterType][0].(*cluster.Cluster)
+	if c.GetType() != cluster.Cluster_STRICT_DNS || len(c.GetLoadAssignment().GetEndpoints()[0].GetLbEndpoints()) != 2 {
+		t.Fatalf("域名上游应使用 STRICT_DNS: %s", c.GetType())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	spec, err := Parse([]byte(`
+rate_limit:
+  address: ratelimit:8081
+proxies:
+  - name: web
+    listen: 10000
+    upstream: 10.0.0.1:80
+  - name: db
+    listen: 15432
+    upstream: 10.0.0.2:5432
+    mode: tcp
+`))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if spec.RateLimit.Domain != "l4-l7" || spec.RateLimit.Timeout != 100*time.Millisecond {
+		t.Fatalf("默认值错误: %+v", spec.RateLimit)
+	}
+	res, err := Build(spec, BuildOptions{})
+	if err != nil {
+		t.Fatalf("生成资源失败: %v", err)
+	}
+
+	web := res[resource.ListenerType][0].(*listener.Listener)
+	var manager hcm.HttpConnectionManager
+	if err := web.GetFilterChains()[0].GetFilters()[0].GetTypedConfig().UnmarshalTo(&manager); err != nil {
+		t.Fatal(err)
+	}
+	if got := manager.GetHttpFilters()[0].GetName(); got != RateLimitFilter {
+		t.Fatalf("限流过滤器应在 router 之前: %s", got)
+	}
+	if n := len(manager.GetRouteConfig().GetVirtualHosts()[0].GetRateLimits()); n != 2 {
+		t.Fatalf("应生成 2 组限流描述符: %d", n)
+	}
+
+	db := res[resource.ListenerType][1].(*listener.Listener)
+	if got := db.GetFilterChains()[0].GetFilters()[0].GetName(); got != NetworkRateLimitFilter {
+		t.Fatalf("tcp 模式应使用网络层限流: %s", got)
+	}
+	rl := res[resource.ClusterType][2].(*cluster.Cluster)
+	if rl.GetName() != RateLimitCluster || rl.GetType() != cluster.Cluster_STRICT_DNS {
+		t.Fatalf("限流服务集群错误: %s %s", rl.GetName(), rl.GetType())
+	}
+
+	if _, err := Parse([]byte("rate_limit: {address: bad}\nproxies: []\n")); err == nil || !strings.Contains(err.Error(), "rate_limit") {
+		t.Fatalf("非法限流服务地址应校验失败: %v", err)
+	}
+}
+
+func TestAccessLogService(t *testing.T) {
+	spec, err := Parse([]byte(`
+access_log_service:
+  address: 127.0.0.1:18090
+proxies:
+  - name: web
+    listen: 10000
+    upstream: 10.0.0.1:80
+    access_log: true
+  - name: db
+    listen: 15432
+    upstream: 10.0.0.2:5432
+    mode: tcp
+    access_log: true
+  - name: quiet
+    listen: 10001
+    upstream: 10.0.0.3:80
+`))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	res, err := Build(spec, BuildOptions{})
+	if err != nil {
+		t.Fatalf("生成资源失败: %v", err)
+	}
+
+	var manager hcm.HttpConnectionManager
+	if err := res[resource.ListenerType][0].(*listener.Listener).GetFilterChains()[0].GetFilters()[0].GetTypedConfig().UnmarshalTo(&manager); err != nil {
+		t.Fatal(err)
+	}
+	logs := manager.GetAccessLog()
+	if len(logs) != 2 || logs[0].GetName() != StdoutAccessLog || logs[1].GetName() != HTTPGRPCAccessLog {
+		t.Fatalf("http 模式应同时输出 stdout 与 gRPC 访问日志: %v", logs)
+	}
+	var httpLog grpcaccesslog.HttpGrpcAccessLogConfig
+	if err := logs[1].GetTypedConfig().UnmarshalTo(&httpLog); err != nil {
+		t.Fatal(err)
+	}
+	if httpLog.GetCommonConfig().GetLogName() != "listener_web" {
+		t.Fatalf("log_name 应为监听器名称: %s", httpLog.GetCommonConfig().GetLogName())
+	}
+
+	var proxy tcpproxy.TcpProxy
+	if err := res[resource.ListenerType][1].(*listener.Listener).GetFilterChains()[0].GetFilters()[0].GetTypedConfig().UnmarshalTo(&proxy); err != nil {
+		t.Fatal(err)
+	}
+	if logs := proxy.GetAccessLog(); len(logs) != 2 || logs[1].GetName() != TCPGRPCAccessLog {
+		t.Fatalf("tcp 模式应使用 tcp_grpc 访问日志: %v", logs)
+	}
+
+	if err := res[resource.ListenerType][2].(*listener.Listener).GetFilterChains()[0].GetFilters()[0].GetTypedConfig().UnmarshalTo(&manager); err != nil {
+		t.Fatal(err)
+	}
+	if len(manager.GetAccessLog()) != 0 {
+		t.Fatalf("未开启 access_log 的代理不应上报")
+	}
+	if c := res[resource.ClusterType][3].(*cluster.Cluster); c.GetName() != GRPCAccessLogCluster {
+		t.Fatalf("缺少访问日志服务集群: %s", c.GetName())
+	}
+
+	if _, err := Parse([]byte("access_log_service: {address: bad}\nproxies: []\n")); err == nil || !strings.Contains(err.Error(), "access_log_service") {
+		t.Fatalf("非法访问日志服务地址应校验失败: %v", err)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	spec, err := Parse([]byte(`
+tracing:
+  address: 127.0.0.1:4317
+  sampling: 10
+proxies:
+  - name: web
+    listen: 10000
+    upstream: 10.0.0.1:80
+  - name: db
+    listen: 15432
+    upstream: 10.0.0.2:5432
+    mode: tcp
+`))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	res, err := Build(spec, BuildOptions{})
+	if err != nil {
+		t.Fatalf("生成资源失败: %v", err)
+	}
+
+	var manager hcm.HttpConnectionManager
+	if err := res[resource.ListenerType][0].(*listener.Listener).GetFilterChains()[0].GetFilters()[0].GetTypedConfig().UnmarshalTo(&manager); err != nil {
+		t.Fatal(err)
+	}
+	tr := manager.GetTracing()
+	if tr.GetProvider().GetName() != OpenTelemetryTracer || tr.GetRandomSampling().GetValue() != 10 {
+		t.Fatalf("http 模式的 tracing 配置错误: %v", tr)
+	}
+	var otel tracecfg.OpenTelemetryConfig
+	if err := tr.GetProvider().GetTypedConfig().UnmarshalTo(&otel); err != nil {
+		t.Fatal(err)
+	}
+	if otel.GetServiceName() != "envoy" || otel.GetGrpcService().GetEnvoyGrpc().GetClusterName() != TracingCluster {
+		t.Fatalf("OpenTelemetry tracer 配置错误: %v", &otel)
+	}
+	if c := res[resource.ClusterType][2].(*cluster.Cluster); c.GetName() != TracingCluster {
+		t.Fatalf("缺少 OTLP 接收端集群: %s", c.GetName())
+	}
+
+	for _, bad := range []string{"tracing: {address: bad}", "tracing: {address: 127.0.0.1:4317, sampling: 101}"} {
+		if _, err := Parse([]byte(bad + "\nproxies: []\n")); err == nil || !strings.Contains(err.Error(), "tracing") {
+			t.Fatalf("%s 应校验失败: %v", bad, err)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "proxies.yaml")
+	write := func(port string) {
+		data := "proxies:\n  - listen: " + port + "\n    upstream: 10.0.0.1:80\n"
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("10000")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got := make(chan int, 4)
+	go Watch(ctx, path, 10*time.Millisecond, func(s Spec, err error) {
+		if err == nil {
+			got <- s.Proxies[0].Listen
+		}
+	})
+
+	expect := func(port int) {
+		t.Helper()
+		select {
+		case p := <-got:
+			if p != port {
+				t.Fatalf("期望端口 %d，实际 %d", port, p)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("未收到变更")
+		}
+	}
+	expect(10000)
+	// 保证修改时间变化
+	time.Sleep(20 * time.Millisecond)
+	write("10001")
+	expect(10001)
+}
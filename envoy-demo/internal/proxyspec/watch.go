@@ -0,0 +1,30 @@
+package proxyspec
+
+import (
+	"context"
+	"time"
+
+	"github.com/lyonmu/demo/envoy-demo/internal/config"
+)
+
+// Watch 立即加载一次声明文件，之后每隔 interval 检查声明文件及其引用的证书文件，
+// 任一文件变化时重新加载并调用 fn，加载失败时 err 非空；ctx 结束时返回
+func Watch(ctx context.Context, path string, interval time.Duration, fn func(Spec, error)) {
+	config.Watch(ctx, path, interval, func() []string {
+		spec, err := Load(path)
+		fn(spec, err)
+		if err != nil {
+			return nil
+		}
+		return spec.files()
+	})
+}
+
+// files 返回声明引用的证书文件
+func (s Spec) files() []string {
+	out := make([]string, 0, 2*len(s.Certificates))
+	for _, c := range s.Certificates {
+		out = append(out, s.Path(c.CertFile), s.Path(c.KeyFile))
+	}
+	return out
+}
@@ -0,0 +1,169 @@
+// Package ratelimit 实现 Envoy 全局限流服务 envoy.service.ratelimit.v3.RateLimitService：
+// 按 domain 与描述符树匹配限额，计数保存在内存中，支持固定窗口与令牌桶两种算法
+//
+// 配置格式与 envoyproxy/ratelimit 相近，例如：
+//
+//	domains:
+//	  - domain: websocket
+//	    descriptors:
+//	      - key: remote_address
+//	        rate_limit: { unit: minute, requests_per_unit: 60 }
+//	      - key: route_prefix
+//	        value: /ws
+//	        rate_limit: { algorithm: token_bucket, unit: second, requests_per_unit: 100, burst: 200 }
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Algorithm 限流算法
+type Algorithm string
+
+const (
+	// FixedWindow 固定窗口：每个 unit 内最多 requests_per_unit 次，窗口按 unit 对齐
+	FixedWindow Algorithm = "fixed_window"
+	// TokenBucket 令牌桶：每个 unit 补充 requests_per_unit 个令牌，容量为 burst
+	TokenBucket Algorithm = "token_bucket"
+)
+
+// Unit 限额的时间单位
+type Unit string
+
+const (
+	UnitSecond Unit = "second"
+	UnitMinute Unit = "minute"
+	UnitHour   Unit = "hour"
+	UnitDay    Unit = "day"
+)
+
+// Duration 单位对应的时长，未知单位返回 0
+func (u Unit) Duration() time.Duration {
+	switch u {
+	case UnitSecond:
+		return time.Second
+	case UnitMinute:
+		return time.Minute
+	case UnitHour:
+		return time.Hour
+	case UnitDay:
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// Config 限额配置
+type Config struct {
+	Domains []Domain `yaml:"domains"`
+}
+
+// Domain 一个限流域，对应 Envoy 限流过滤器的 domain
+type Domain struct {
+	Domain      string       `yaml:"domain"`
+	Descriptors []Descriptor `yaml:"descriptors"`
+}
+
+// Descriptor 描述符树的一个节点，Value 为空时匹配该 key 的任意值，并按值分别计数
+type Descriptor struct {
+	Key         string       `yaml:"key"`
+	Value       string       `yaml:"value"`
+	RateLimit   *Limit       `yaml:"rate_limit"`
+	Descriptors []Descriptor `yaml:"descriptors"`
+}
+
+// Limit 限额
+type Limit struct {
+	// Name 返回给 Envoy 的限额名称，可选
+	Name string `yaml:"name"`
+	// Algorithm 默认 fixed_window
+	Algorithm       Algorithm `yaml:"algorithm"`
+	Unit            Unit      `yaml:"unit"`
+	RequestsPerUnit uint32    `yaml:"requests_per_unit"`
+	// Burst 令牌桶容量，默认等于 requests_per_unit
+	Burst uint32 `yaml:"burst"`
+}
+
+// LoadConfig 读取并校验限额配置
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ParseConfig 解析并校验限额配置，补全默认值
+func ParseConfig(data []byte) (Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) validate() error {
+	var errs []error
+	domains := make(map[string]bool, len(c.Domains))
+	for i := range c.Domains {
+		d := &c.Domains[i]
+		switch {
+		case d.Domain == "":
+			errs = append(errs, fmt.Errorf("domains[%d]: domain is required", i))
+		case domains[d.Domain]:
+			errs = append(errs, fmt.Errorf("duplicate domain %q", d.Domain))
+		}
+		domains[d.Domain] = true
+		errs = append(errs, validateDescriptors(d.Domain, d.Descriptors)...)
+	}
+	return errors.Join(errs...)
+}
+
+func validateDescriptors(path string, descs []Descriptor) []error {
+	var errs []error
+	seen := make(map[string]bool, len(descs))
+	for i := range descs {
+		d := &descs[i]
+		label := path + "." + d.Key
+		if d.Value != "" {
+			label += "=" + d.Value
+		}
+		switch {
+		case d.Key == "":
+			errs = append(errs, fmt.Errorf("%s: descriptors[%d]: key is required", path, i))
+		case seen[d.Key+"="+d.Value]:
+			errs = append(errs, fmt.Errorf("%s: duplicate descriptor", label))
+		}
+		seen[d.Key+"="+d.Value] = true
+
+		if l := d.RateLimit; l != nil {
+			if l.Algorithm == "" {
+				l.Algorithm = FixedWindow
+			}
+			if l.Burst == 0 {
+				l.Burst = l.RequestsPerUnit
+			}
+			switch {
+			case l.Algorithm != FixedWindow && l.Algorithm != TokenBucket:
+				errs = append(errs, fmt.Errorf("%s: unknown algorithm %q", label, l.Algorithm))
+			case l.Unit.Duration() == 0:
+				errs = append(errs, fmt.Errorf("%s: unknown unit %q", label, l.Unit))
+			case l.RequestsPerUnit == 0:
+				errs = append(errs, fmt.Errorf("%s: requests_per_unit must be positive", label))
+			}
+		}
+		errs = append(errs, validateDescriptors(label, d.Descriptors)...)
+	}
+	return errs
+}
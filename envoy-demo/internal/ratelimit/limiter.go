@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry 请求描述符中的一项
+type Entry struct {
+	Key   string
+	Value string
+}
+
+// Result 单个描述符的限流结果
+type Result struct {
+	// Limit 命中的限额，为 nil 表示未配置限额
+	Limit *Limit
+	// OverLimit 是否超限
+	OverLimit bool
+	// Remaining 剩余次数
+	Remaining uint32
+	// ResetAfter 固定窗口为距离窗口结束的时长，令牌桶为补满令牌所需的时长
+	ResetAfter time.Duration
+}
+
+// Limiter 内存限流器，可并发使用；Update 替换限额后，限额未变的计数会保留
+type Limiter struct {
+	mu      sync.Mutex
+	domains map[string][]Descriptor
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewLimiter 创建限流器
+func NewLimiter(cfg Config) *Limiter {
+	l := &Limiter{buckets: make(map[string]*bucket), now: time.Now}
+	l.Update(cfg)
+	return l
+}
+
+// Update 替换限额配置
+func (l *Limiter) Update(cfg Config) {
+	domains := make(map[string][]Descriptor, len(cfg.Domains))
+	for _, d := range cfg.Domains {
+		domains[d.Domain] = d.Descriptors
+	}
+	l.mu.Lock()
+	l.domains = domains
+	l.mu.Unlock()
+}
+
+// Do 对一个请求描述符扣减 hits 次
+func (l *Limiter) Do(domain string, entries []Entry, hits uint32) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := match(l.domains[domain], entries)
+	if limit == nil {
+		return Result{}
+	}
+	key := bucketKey(domain, entries)
+	b, ok := l.buckets[key]
+	// 限额变化后重新计数
+	if !ok || b.limit != *limit {
+		b = newBucket(*limit, l.now())
+		l.buckets[key] = b
+	}
+	res := b.take(hits, l.now())
+	res.Limit = limit
+	return res
+}
+
+// Sweep 清理已恢复初始状态的计数，返回清理数量
+func (l *Limiter) Sweep() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	n := 0
+	for k, b := range l.buckets {
+		if b.idle(now) {
+			delete(l.buckets, k)
+			n++
+		}
+	}
+	return n
+}
+
+// match 逐项匹配描述符树，精确值优先于通配；全部项都匹配时返回最后一个节点的限额
+func match(nodes []Descriptor, entries []Entry) *Limit {
+	var node *Descriptor
+	for _, e := range entries {
+		node = nil
+		for i := range nodes {
+			n := &nodes[i]
+			if n.Key != e.Key {
+				continue
+			}
+			if n.Value == e.Value {
+				node = n
+				break
+			}
+			if n.Value == "" && node == nil {
+				node = n
+			}
+		}
+		if node == nil {
+			return nil
+		}
+		nodes = node.Descriptors
+	}
+	if node == nil {
+		return nil
+	}
+	return node.RateLimit
+}
+
+func bucketKey(domain string, entries []Entry) string {
+	var b strings.Builder
+	b.WriteString(domain)
+	for _, e := range entries {
+		b.WriteString("|")
+		b.WriteString(e.Key)
+		b.WriteString("=")
+		b.WriteString(e.Value)
+	}
+	return b.String()
+}
+
+// bucket 一个描述符的计数状态
+type bucket struct {
+	limit Limit
+	// 固定窗口
+	window time.Time
+	count  uint32
+	// 令牌桶
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(l Limit, now time.Time) *bucket {
+	return &bucket{limit: l, tokens: float64(l.Burst), last: now}
+}
+
+func (b *bucket) take(hits uint32, now time.Time) Result {
+	unit := b.limit.Unit.Duration()
+	if b.limit.Algorithm == TokenBucket {
+		rate := float64(b.limit.RequestsPerUnit) / unit.Seconds()
+		b.refill(now, rate)
+		over := b.tokens < float64(hits)
+		if !over {
+			b.tokens -= float64(hits)
+		}
+		missing := float64(b.limit.Burst) - b.tokens
+		return Result{
+			OverLimit:  over,
+			Remaining:  uint32(math.Floor(b.tokens)),
+			ResetAfter: time.Duration(missing / rate * float64(time.Second)),
+		}
+	}
+
+	window := now.Truncate(unit)
+	if !window.Equal(b.window) {
+		b.window, b.count = window, 0
+	}
+	over := uint64(b.count)+uint64(hits) > uint64(b.limit.RequestsPerUnit)
+	if !over {
+		b.count += hits
+	}
+	return Result{
+		OverLimit:  over,
+		Remaining:  b.limit.RequestsPerUnit - b.count,
+		ResetAfter: window.Add(unit).Sub(now),
+	}
+}
+
+func (b *bucket) refill(now time.Time, rate float64) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*rate)
+	}
+	b.last = now
+}
+
+// idle 计数已恢复初始状态，删除后重新创建的结果相同
+func (b *bucket) idle(now time.Time) bool {
+	if b.limit.Algorithm == TokenBucket {
+		elapsed := now.Sub(b.last).Seconds()
+		rate := float64(b.limit.RequestsPerUnit) / b.limit.Unit.Duration().Seconds()
+		return b.tokens+elapsed*rate >= float64(b.limit.Burst)
+	}
+	return !now.Truncate(b.limit.Unit.Duration()).Equal(b.window)
+}
@@ -0,0 +1,257 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	ratelimitcommon "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct{ t time.Time }
+
+func newClock() *fakeClock {
+	return &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) now() time.Time      { return c.t }
+func (c *fakeClock) add(d time.Duration) { c.t = c.t.Add(d) }
+
+// entries 按 key、value 交替构造描述符
+func entries(kv ...string) []Entry {
+	out := make([]Entry, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		out = append(out, Entry{Key: kv[i], Value: kv[i+1]})
+	}
+	return out
+}
+
+func mustParse(t *testing.T, data string) Config {
+	t.Helper()
+	cfg, err := ParseConfig([]byte(data))
+	if err != nil {
+		t.Fatalf("解析配置失败: %v", err)
+	}
+	return cfg
+}
+
+func TestLoadDemoConfig(t *testing.T) {
+	cfg, err := LoadConfig("../../ratelimit-demo/ratelimit.yaml")
+	if err != nil {
+		t.Fatalf("加载示例配置失败: %v", err)
+	}
+	if len(cfg.Domains) != 2 || cfg.Domains[0].Descriptors[0].RateLimit.Algorithm != FixedWindow {
+		t.Fatalf("配置解析错误: %+v", cfg.Domains)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+domains:
+  - domain: a
+    descriptors:
+      - key: k
+        rate_limit: { unit: week, requests_per_unit: 1 }
+      - key: k
+      - key: x
+        rate_limit: { unit: second, requests_per_unit: 0, algorithm: leaky }
+  - domain: a
+`))
+	if err == nil {
+		t.Fatalf("非法配置应校验失败")
+	}
+	for _, want := range []string{`unknown unit "week"`, "duplicate descriptor", `unknown algorithm "leaky"`, `duplicate domain "a"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("错误信息缺少 %q: %v", want, err)
+		}
+	}
+}
+
+func TestFixedWindow(t *testing.T) {
+	clock := newClock()
+	l := NewLimiter(mustParse(t, `
+domains:
+  - domain: ws
+    descriptors:
+      - key: remote_address
+        rate_limit: { unit: minute, requests_per_unit: 2 }
+`))
+	l.now = clock.now
+
+	a, b := entries("remote_address", "10.0.0.1"), entries("remote_address", "10.0.0.2")
+	for i, want := range []bool{false, false, true} {
+		if got := l.Do("ws", a, 1).OverLimit; got != want {
+			t.Fatalf("第 %d 次请求超限判断错误: %v", i+1, got)
+		}
+	}
+	// 通配描述符按值分别计数
+	if l.Do("ws", b, 1).OverLimit {
+		t.Fatalf("不同 IP 应分别计数")
+	}
+	res := l.Do("ws", a, 1)
+	if res.ResetAfter != time.Minute {
+		t.Fatalf("窗口剩余时长错误: %v", res.ResetAfter)
+	}
+
+	// 下一个窗口重新计数
+	clock.add(time.Minute)
+	if res := l.Do("ws", a, 1); res.OverLimit || res.Remaining != 1 {
+		t.Fatalf("新窗口应重新计数: %+v", res)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := newClock()
+	l := NewLimiter(mustParse(t, `
+domains:
+  - domain: ws
+    descriptors:
+      - key: user_id
+        rate_limit: { algorithm: token_bucket, unit: second, requests_per_unit: 2, burst: 4 }
+`))
+	l.now = clock.now
+
+	u := entries("user_id", "u1")
+	// 允许突发 4 次
+	for i := range 4 {
+		if l.Do("ws", u, 1).OverLimit {
+			t.Fatalf("第 %d 次请求不应超限", i+1)
+		}
+	}
+	res := l.Do("ws", u, 1)
+	if !res.OverLimit || res.ResetAfter != 2*time.Second {
+		t.Fatalf("令牌耗尽后应超限: %+v", res)
+	}
+
+	// 0.5 秒补充 1 个令牌
+	clock.add(500 * time.Millisecond)
+	if l.Do("ws", u, 1).OverLimit {
+		t.Fatalf("补充令牌后应放行")
+	}
+	if !l.Do("ws", u, 1).OverLimit {
+		t.Fatalf("补充的令牌已用完")
+	}
+
+	clock.add(time.Hour)
+	if n := l.Sweep(); n != 1 {
+		t.Fatalf("令牌补满后应被清理: %d", n)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	l := NewLimiter(mustParse(t, `
+domains:
+  - domain: ws
+    descriptors:
+      - key: route_prefix
+        rate_limit: { unit: second, requests_per_unit: 10 }
+      - key: route_prefix
+        value: /ws
+        rate_limit: { unit: second, requests_per_unit: 1 }
+      - key: route_prefix
+        value: /api
+        descriptors:
+          - key: user_id
+            rate_limit: { unit: second, requests_per_unit: 5 }
+`))
+	for _, tc := range []struct {
+		entries []Entry
+		want    uint32
+	}{
+		{entries("route_prefix", "/ws"), 1},
+		{entries("route_prefix", "/other"), 10},
+		// /api 节点本身没有限额
+		{entries("route_prefix", "/api"), 0},
+		{entries("route_prefix", "/api", "user_id", "u1"), 5},
+		{entries("route_prefix", "/ws", "user_id", "u1"), 0},
+		{entries("unknown", "x"), 0},
+	} {
+		res := l.Do("ws", tc.entries, 1)
+		var got uint32
+		if res.Limit != nil {
+			got = res.Limit.RequestsPerUnit
+		}
+		if got != tc.want {
+			t.Errorf("%v: 期望限额 %d，实际 %d", tc.entries, tc.want, got)
+		}
+	}
+	if l.Do("other", entries("route_prefix", "/ws"), 1).Limit != nil {
+		t.Fatalf("未知 domain 不应限流")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	clock := newClock()
+	cfg := `
+domains:
+  - domain: ws
+    descriptors:
+      - key: remote_address
+        rate_limit: { unit: minute, requests_per_unit: %s }
+`
+	l := NewLimiter(mustParse(t, strings.Replace(cfg, "%s", "2", 1)))
+	l.now = clock.now
+	a := entries("remote_address", "10.0.0.1")
+	l.Do("ws", a, 2)
+
+	// 限额不变时保留计数
+	l.Update(mustParse(t, strings.Replace(cfg, "%s", "2", 1)))
+	if !l.Do("ws", a, 1).OverLimit {
+		t.Fatalf("限额不变时应保留计数")
+	}
+	// 限额变化后重新计数
+	l.Update(mustParse(t, strings.Replace(cfg, "%s", "3", 1)))
+	if res := l.Do("ws", a, 1); res.OverLimit || res.Remaining != 2 {
+		t.Fatalf("限额变化后应重新计数: %+v", res)
+	}
+}
+
+func TestShouldRateLimit(t *testing.T) {
+	l := NewLimiter(mustParse(t, `
+domains:
+  - domain: ws
+    descriptors:
+      - key: remote_address
+        rate_limit: { unit: minute, requests_per_unit: 1 }
+      - key: user_id
+        rate_limit: { unit: minute, requests_per_unit: 10 }
+`))
+	s := NewServer(l)
+	req := &rlsv3.RateLimitRequest{
+		Domain: "ws",
+		Descriptors: []*ratelimitcommon.RateLimitDescriptor{
+			{Entries: []*ratelimitcommon.RateLimitDescriptor_Entry{{Key: "remote_address", Value: "10.0.0.1"}}},
+			{Entries: []*ratelimitcommon.RateLimitDescriptor_Entry{{Key: "user_id", Value: "u1"}}},
+			{Entries: []*ratelimitcommon.RateLimitDescriptor_Entry{{Key: "generic_key", Value: "x"}}},
+		},
+	}
+
+	resp, err := s.ShouldRateLimit(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetOverallCode() != rlsv3.RateLimitResponse_OK || len(resp.GetStatuses()) != 3 {
+		t.Fatalf("首次请求应放行: %v", resp)
+	}
+	st := resp.GetStatuses()[1]
+	if st.GetCurrentLimit().GetUnit() != rlsv3.RateLimitResponse_RateLimit_MINUTE || st.GetLimitRemaining() != 9 {
+		t.Fatalf("描述符状态错误: %v", st)
+	}
+	if resp.GetStatuses()[2].GetCurrentLimit() != nil {
+		t.Fatalf("未配置的描述符不应返回限额")
+	}
+
+	resp, _ = s.ShouldRateLimit(context.Background(), req)
+	if resp.GetOverallCode() != rlsv3.RateLimitResponse_OVER_LIMIT ||
+		resp.GetStatuses()[0].GetCode() != rlsv3.RateLimitResponse_OVER_LIMIT ||
+		resp.GetStatuses()[1].GetCode() != rlsv3.RateLimitResponse_OK {
+		t.Fatalf("IP 超限后整体应返回 OVER_LIMIT: %v", resp)
+	}
+
+	if _, err := s.ShouldRateLimit(context.Background(), &rlsv3.RateLimitRequest{}); err == nil {
+		t.Fatalf("缺少 domain 应返回错误")
+	}
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Server 实现 envoy.service.ratelimit.v3.RateLimitService
+type Server struct {
+	rlsv3.UnimplementedRateLimitServiceServer
+	limiter *Limiter
+}
+
+// NewServer 创建限流服务
+func NewServer(limiter *Limiter) *Server {
+	return &Server{limiter: limiter}
+}
+
+// ShouldRateLimit 逐个描述符扣减，任一描述符超限时整体返回 OVER_LIMIT
+func (s *Server) ShouldRateLimit(_ context.Context, req *rlsv3.RateLimitRequest) (*rlsv3.RateLimitResponse, error) {
+	if req.GetDomain() == "" {
+		return nil, status.Error(codes.InvalidArgument, "domain is required")
+	}
+	hits := req.GetHitsAddend()
+	if hits == 0 {
+		hits = 1
+	}
+
+	resp := &rlsv3.RateLimitResponse{OverallCode: rlsv3.RateLimitResponse_OK}
+	for _, d := range req.GetDescriptors() {
+		entries := make([]Entry, 0, len(d.GetEntries()))
+		for _, e := range d.GetEntries() {
+			entries = append(entries, Entry{Key: e.GetKey(), Value: e.GetValue()})
+		}
+
+		res := s.limiter.Do(req.GetDomain(), entries, hits)
+		st := &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OK}
+		if res.Limit != nil {
+			st.CurrentLimit = &rlsv3.RateLimitResponse_RateLimit{
+				Name:            res.Limit.Name,
+				RequestsPerUnit: res.Limit.RequestsPerUnit,
+				Unit:            protoUnit(res.Limit.Unit),
+			}
+			st.LimitRemaining = res.Remaining
+			st.DurationUntilReset = durationpb.New(res.ResetAfter)
+		}
+		if res.OverLimit {
+			st.Code = rlsv3.RateLimitResponse_OVER_LIMIT
+			resp.OverallCode = rlsv3.RateLimitResponse_OVER_LIMIT
+			slog.Info("Rate limited",
+				slog.String("domain", req.GetDomain()),
+				slog.String("descriptor", describe(entries)),
+				slog.Uint64("limit", uint64(res.Limit.RequestsPerUnit)),
+				slog.String("unit", string(res.Limit.Unit)))
+		}
+		resp.Statuses = append(resp.Statuses, st)
+	}
+	return resp, nil
+}
+
+func protoUnit(u Unit) rlsv3.RateLimitResponse_RateLimit_Unit {
+	switch u {
+	case UnitSecond:
+		return rlsv3.RateLimitResponse_RateLimit_SECOND
+	case UnitMinute:
+		return rlsv3.RateLimitResponse_RateLimit_MINUTE
+	case UnitHour:
+		return rlsv3.RateLimitResponse_RateLimit_HOUR
+	case UnitDay:
+		return rlsv3.RateLimitResponse_RateLimit_DAY
+	}
+	return rlsv3.RateLimitResponse_RateLimit_UNKNOWN
+}
+
+func describe(entries []Entry) string {
+	parts := make([]string, 0, len(entries))
+	for _, e := range entries {
+		parts = append(parts, e.Key+"="+e.Value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ListenGRPC 在 addr 上启动限流服务，并定期清理空闲计数，ctx 结束时优雅退出
+func ListenGRPC(ctx context.Context, addr string, s *Server) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	grpcServer := grpc.NewServer()
+	rlsv3.RegisterRateLimitServiceServer(grpcServer, s)
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				grpcServer.GracefulStop()
+				return
+			case <-ticker.C:
+				if n := s.limiter.Sweep(); n > 0 {
+					slog.Debug("Swept idle rate limit counters", slog.Int("count", n))
+				}
+			}
+		}
+	}()
+
+	slog.Info("Rate limit server listening", slog.String("addr", lis.Addr().String()))
+	if err := grpcServer.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
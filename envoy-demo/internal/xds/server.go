@@ -0,0 +1,113 @@
+// Package xds 封装 go-control-plane 的 gRPC xDS 服务，供各个控制面复用
+package xds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	clusterservice "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	discoverygrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	endpointservice "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	listenerservice "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
+	routeservice "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	secretservice "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/log"
+	"github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// ClusterHash 按 node.cluster 分组下发快照，同一 cluster 下的所有 Envoy 实例共享一份配置
+type ClusterHash struct{}
+
+// ID 实现 cache.NodeHash
+func (ClusterHash) ID(node *core.Node) string {
+	if node == nil {
+		return ""
+	}
+	return node.GetCluster()
+}
+
+// NewCache 创建 ADS 模式的快照缓存
+func NewCache() cache.SnapshotCache {
+	return cache.NewSnapshotCache(true, ClusterHash{}, Logger())
+}
+
+// Logger 将 go-control-plane 日志输出到 slog
+func Logger() log.Logger {
+	l := slog.With(slog.String("component", "xds"))
+	return log.LoggerFuncs{
+		DebugFunc: func(format string, args ...any) { l.Debug(fmt.Sprintf(format, args...)) },
+		InfoFunc:  func(format string, args ...any) { l.Debug(fmt.Sprintf(format, args...)) },
+		WarnFunc:  func(format string, args ...any) { l.Warn(fmt.Sprintf(format, args...)) },
+		ErrorFunc: func(format string, args ...any) { l.Error(fmt.Sprintf(format, args...)) },
+	}
+}
+
+// callbacks 记录 Envoy 连接与 NACK
+func callbacks() server.Callbacks {
+	return server.CallbackFuncs{
+		StreamOpenFunc: func(_ context.Context, id int64, typ string) error {
+			slog.Debug("xDS stream opened", slog.Int64("stream", id), slog.String("type", typ))
+			return nil
+		},
+		StreamClosedFunc: func(id int64, node *core.Node) {
+			slog.Info("xDS stream closed", slog.Int64("stream", id), slog.String("node", node.GetId()))
+		},
+		StreamRequestFunc: func(id int64, req *discoverygrpc.DiscoveryRequest) error {
+			if req.GetErrorDetail() != nil {
+				slog.Warn("Envoy rejected xDS update",
+					slog.String("node", req.GetNode().GetId()),
+					slog.String("type", req.GetTypeUrl()),
+					slog.String("version", req.GetVersionInfo()),
+					slog.String("error", req.GetErrorDetail().GetMessage()))
+			}
+			return nil
+		},
+	}
+}
+
+// Serve 在 addr 上启动 xDS gRPC 服务（ADS 以及独立的 LDS/CDS/EDS/RDS/SDS），ctx 结束时优雅退出
+func Serve(ctx context.Context, addr string, c cache.Cache) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.MaxConcurrentStreams(1000000),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    30 * time.Second,
+			Timeout: 5 * time.Second,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             30 * time.Second,
+			PermitWithoutStream: true,
+		}),
+	)
+
+	srv := server.NewServer(ctx, c, callbacks())
+	discoverygrpc.RegisterAggregatedDiscoveryServiceServer(grpcServer, srv)
+	listenerservice.RegisterListenerDiscoveryServiceServer(grpcServer, srv)
+	clusterservice.RegisterClusterDiscoveryServiceServer(grpcServer, srv)
+	endpointservice.RegisterEndpointDiscoveryServiceServer(grpcServer, srv)
+	routeservice.RegisterRouteDiscoveryServiceServer(grpcServer, srv)
+	secretservice.RegisterSecretDiscoveryServiceServer(grpcServer, srv)
+
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+
+	slog.Info("xDS server listening", slog.String("addr", lis.Addr().String()))
+	if err := grpcServer.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package xds
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+// Publisher 将资源集合发布为某个 node.cluster 的快照，版本号取资源内容的哈希，内容不变时不重复下发
+type Publisher struct {
+	cache cache.SnapshotCache
+	node  string
+
+	mu      sync.Mutex
+	version string
+}
+
+// NewPublisher 创建 Publisher，node 对应 Envoy 的 node.cluster
+func NewPublisher(c cache.SnapshotCache, node string) *Publisher {
+	return &Publisher{cache: c, node: node}
+}
+
+// Version 返回当前快照版本
+func (p *Publisher) Version() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.version
+}
+
+// Publish 校验并发布快照，返回版本号以及是否发生变化
+func (p *Publisher) Publish(ctx context.Context, resources map[resource.Type][]types.Resource) (string, bool, error) {
+	for typ, items := range resources {
+		for _, r := range items {
+			if v, ok := r.(interface{ Validate() error }); ok {
+				if err := v.Validate(); err != nil {
+					return "", false, fmt.Errorf("invalid %s: %w", typ, err)
+				}
+			}
+		}
+	}
+
+	version, err := Version(resources)
+	if err != nil {
+		return "", false, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if version == p.version {
+		return version, false, nil
+	}
+
+	snapshot, err := cache.NewSnapshot(version, resources)
+	if err != nil {
+		return "", false, err
+	}
+	// 监听器写在 bootstrap 中（不走 LDS）时，路由表没有引用方，无法做一致性校验
+	if len(resources[resource.ListenerType]) > 0 {
+		if err := snapshot.Consistent(); err != nil {
+			return "", false, err
+		}
+	}
+	if err := p.cache.SetSnapshot(ctx, p.node, snapshot); err != nil {
+		return "", false, err
+	}
+	p.version = version
+	return version, true, nil
+}
+
+// Version 计算资源集合的内容哈希
+func Version(resources map[resource.Type][]types.Resource) (string, error) {
+	h := sha256.New()
+	opts := proto.MarshalOptions{Deterministic: true}
+	for _, typ := range slices.Sorted(maps.Keys(resources)) {
+		h.Write([]byte(typ))
+		for _, r := range resources[typ] {
+			b, err := opts.Marshal(r)
+			if err != nil {
+				return "", err
+			}
+			h.Write(b)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
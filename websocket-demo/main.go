@@ -1,13 +1,25 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/lyonmu/demo/base-demo/pkg/logger"
+	"github.com/lyonmu/demo/base-demo/pkg/metrics"
+	"github.com/lyonmu/demo/base-demo/pkg/metrics/wsmetrics"
+	"github.com/lyonmu/demo/base-demo/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Message 定义推送的消息结构体
@@ -26,6 +38,12 @@ type DataInfo struct {
 	Count  int     `json:"count"`
 }
 
+// Envoy ext-authz 鉴权通过后注入的身份请求头
+const (
+	headerUserID   = "X-User-Id"
+	headerClientID = "X-Client-Id"
+)
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		// 允许所有来源的连接，生产环境应该检查具体的来源
@@ -33,129 +51,202 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// serviceName 链路追踪中的服务名
+const serviceName = "websocket-demo"
+
+// listenAddr 只监听回环地址：身份请求头仅在请求来自 Envoy（同一主机、network_mode: host）时可信，
+// 其他主机无法绕过 Envoy 直接连接后端并伪造 X-User-Id
+const listenAddr = "127.0.0.1:8080"
+
+var tracer = otel.Tracer("github.com/lyonmu/demo/websocket-demo")
+
 // 存储所有活跃的 WebSocket 连接
 var clients = make(map[*websocket.Conn]bool)
 var broadcast = make(chan Message)
 
 // handleWebSocket 处理 WebSocket 连接
 func handleWebSocket(c *gin.Context) {
-	// 读取查询参数（从 URL 中获取的自定义信息）
+	// 经 Envoy 访问时 token 由 ext-authz 校验，身份通过 x-user-id、x-client-id 传入，客户端自带的同名请求头会被覆盖；
+	// 后端只监听回环地址（见 listenAddr），绕过 Envoy 的只能是本机进程，这两个请求头对本机进程仍可伪造。
+	// 直接访问后端时没有这两个请求头，退回到查询参数，仅用于本地调试
+	userID := c.GetHeader(headerUserID)
+	clientID := c.GetHeader(headerClientID)
+	authenticated := userID != ""
+	if !authenticated {
+		userID = c.Query("user_id")
+		clientID = c.Query("client_id")
+	}
+	// 经 Envoy 访问时查询参数中的 token 已被移除
 	token := c.Query("token")
-	userID := c.Query("user_id")
-	clientID := c.Query("client_id")
 
 	// 读取请求头（如果客户端通过其他方式设置了请求头）
 	authHeader := c.GetHeader("Authorization")
 	customHeader := c.GetHeader("X-Custom-Header")
 
-	// 打印连接信息
-	log.Printf("New WebSocket connection request:")
-	if token != "" {
-		log.Printf("  - Token (from query): %s", token)
-	}
-	if userID != "" {
-		log.Printf("  - User ID (from query): %s", userID)
-	}
-	if clientID != "" {
-		log.Printf("  - Client ID (from query): %s", clientID)
-	}
-	if authHeader != "" {
-		log.Printf("  - Authorization header: %s", authHeader)
-	}
-	if customHeader != "" {
-		log.Printf("  - Custom header: %s", customHeader)
-	}
+	// 连接 ID 沿用请求 ID（来自 Envoy 的 X-Request-ID 或中间件生成）
+	connID := logger.RequestIDFrom(c.Request.Context())
+	log := slog.With(slog.String("conn_id", connID))
+
+	// 连接 span 覆盖整个连接生命周期，父 span 为升级请求（经 Envoy 时延续 Envoy 的 trace）
+	ctx, span := tracer.Start(c.Request.Context(), "websocket connection", trace.WithAttributes(
+		attribute.String("websocket.conn_id", connID),
+		attribute.Bool("websocket.authenticated", authenticated),
+		attribute.String("enduser.id", userID),
+	))
+	defer span.End()
+
+	// 打印连接信息（token、authorization 等敏感字段由 logger 统一脱敏）
+	log.InfoContext(ctx, "New WebSocket connection request",
+		slog.Bool("authenticated", authenticated),
+		slog.String("token", token),
+		slog.String("user_id", userID),
+		slog.String("client_id", clientID),
+		slog.String("authorization", authHeader),
+		slog.String("custom_header", customHeader),
+	)
 
 	// 升级 HTTP 连接为 WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "upgrade failed")
+		log.ErrorContext(ctx, "WebSocket upgrade error", logger.Err(err))
 		return
 	}
 	defer conn.Close()
 
 	// 存储客户端信息（可以扩展为更复杂的客户端管理）
 	clientInfo := map[string]interface{}{
-		"token":       token,
-		"user_id":     userID,
-		"client_id":   clientID,
-		"auth_header": authHeader,
+		"token":         token,
+		"user_id":       userID,
+		"client_id":     clientID,
+		"auth_header":   authHeader,
+		"authenticated": authenticated,
 	}
-	log.Printf("Client info: %+v", clientInfo)
 
 	// 注册新客户端
 	clients[conn] = true
-	log.Printf("New client connected. Total clients: %d", len(clients))
+	wsmetrics.Opened(authenticated)
+	log.InfoContext(ctx, "New client connected", slog.Int("clients", len(clients)))
 
 	// 启动一个 goroutine 来处理从客户端接收的消息
-	go handleClientMessages(conn, clientInfo)
+	go handleClientMessages(ctx, conn, clientInfo, log)
 
 	// 保持连接活跃，等待客户端断开
 	for {
 		_, _, err := conn.ReadMessage()
 		if err != nil {
-			log.Printf("Client disconnected: %v", err)
-			delete(clients, conn)
+			span.AddEvent("disconnected", trace.WithAttributes(attribute.String("error", err.Error())))
+			log.InfoContext(ctx, "Client disconnected", logger.Err(err))
+			if _, ok := clients[conn]; ok {
+				delete(clients, conn)
+				wsmetrics.Closed()
+			}
 			break
 		}
 	}
 }
 
-// handleClientMessages 处理来自客户端的消息
-func handleClientMessages(conn *websocket.Conn, clientInfo map[string]interface{}) {
+// handleClientMessages 处理来自客户端的消息，每条消息是一个新的根 span，通过 link 关联到连接 span
+func handleClientMessages(ctx context.Context, conn *websocket.Conn, clientInfo map[string]interface{}, log *slog.Logger) {
+	link := trace.LinkFromContext(ctx)
 	for {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
-			log.Printf("Read message error: %v", err)
+			log.Debug("Read message error", logger.Err(err))
 			break
 		}
-		log.Printf("Received from client: %s", message)
-
-		// 尝试解析为 JSON 消息
-		var msg map[string]interface{}
-		if err := json.Unmarshal(message, &msg); err == nil {
-			// 处理认证消息
-			if msgType, ok := msg["type"].(string); ok && msgType == "auth" {
-				log.Printf("Received auth message from client:")
-				if token, ok := msg["token"].(string); ok && token != "" {
-					clientInfo["token"] = token
-					log.Printf("  - Updated token: %s", token)
-				}
+		msgCtx, span := tracer.Start(ctx, "websocket receive",
+			trace.WithNewRoot(),
+			trace.WithLinks(link),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attribute.Int("websocket.message.size", len(message))))
+		msgType := handleClientMessage(msgCtx, message, clientInfo, log)
+		span.SetAttributes(attribute.String("websocket.message.type", msgType))
+		span.End()
+	}
+}
+
+// handleClientMessage 处理一条客户端消息，返回消息类型
+func handleClientMessage(ctx context.Context, message []byte, clientInfo map[string]interface{}, log *slog.Logger) string {
+	// 尝试解析为 JSON 消息
+	var msg map[string]interface{}
+	if err := json.Unmarshal(message, &msg); err == nil {
+		// 处理认证消息（消息体包含 token，不直接打印原文）
+		if msgType, ok := msg["type"].(string); ok && msgType == "auth" {
+			wsmetrics.Received(wsmetrics.MessageAuth)
+			if token, ok := msg["token"].(string); ok && token != "" {
+				clientInfo["token"] = token
+			}
+			// 已由 Envoy 鉴权的连接以请求头中的身份为准，忽略消息中的 user_id、client_id
+			if authenticated, _ := clientInfo["authenticated"].(bool); !authenticated {
 				if userID, ok := msg["user_id"].(string); ok && userID != "" {
 					clientInfo["user_id"] = userID
-					log.Printf("  - Updated user_id: %s", userID)
 				}
 				if clientID, ok := msg["client_id"].(string); ok && clientID != "" {
 					clientInfo["client_id"] = clientID
-					log.Printf("  - Updated client_id: %s", clientID)
 				}
-				log.Printf("Updated client info: %+v", clientInfo)
 			}
+			log.InfoContext(ctx, "Received auth message from client",
+				slog.Any("token", clientInfo["token"]),
+				slog.Any("user_id", clientInfo["user_id"]),
+				slog.Any("client_id", clientInfo["client_id"]),
+			)
+			return "auth"
 		}
-		// 这里可以处理其他类型的客户端消息
 	}
+	wsmetrics.Received(wsmetrics.MessageOther)
+	log.DebugContext(ctx, "Received from client", slog.String("message", string(message)))
+	// 这里可以处理其他类型的客户端消息
+	return "other"
 }
 
 // broadcastMessage 向所有客户端广播消息
 func broadcastMessage() {
 	for {
 		message := <-broadcast
-		messageJSON, err := json.Marshal(message)
-		if err != nil {
-			log.Printf("JSON marshal error: %v", err)
-			continue
-		}
+		publish(message)
+	}
+}
+
+// publish 编码并向所有客户端发送一条消息，每条广播记录为一个 span
+func publish(message Message) {
+	_, span := tracer.Start(context.Background(), "websocket broadcast",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.Int("websocket.message.id", message.ID)))
+	defer span.End()
 
-		// 向所有客户端发送消息
-		for client := range clients {
-			err := client.WriteMessage(websocket.TextMessage, messageJSON)
-			if err != nil {
-				log.Printf("Write message error: %v", err)
-				client.Close()
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "marshal failed")
+		slog.Error("JSON marshal error", logger.Err(err))
+		return
+	}
+
+	// 向所有客户端发送消息
+	sent, failed := 0, 0
+	for client := range clients {
+		err := client.WriteMessage(websocket.TextMessage, messageJSON)
+		if err != nil {
+			wsmetrics.SendFailed()
+			failed++
+			slog.Warn("Write message error", logger.Err(err))
+			client.Close()
+			if _, ok := clients[client]; ok {
 				delete(clients, client)
+				wsmetrics.Closed()
 			}
+			continue
 		}
+		wsmetrics.Sent()
+		sent++
 	}
+	span.SetAttributes(
+		attribute.Int("websocket.message.size", len(messageJSON)),
+		attribute.Int("websocket.clients.sent", sent),
+		attribute.Int("websocket.clients.failed", failed),
+	)
 }
 
 // startTimer 启动定时推送任务
@@ -178,11 +269,24 @@ func startTimer() {
 			},
 		}
 		broadcast <- message
-		log.Printf("Broadcasted message ID: %d to %d clients", messageID, len(clients))
+		slog.Debug("Broadcasted message", slog.Int("id", messageID), slog.Int("clients", len(clients)))
 	}
 }
 
 func main() {
+	logger.Init(logger.OptionsFromEnv())
+
+	traceOpts, err := tracing.OptionsFromEnv()
+	if err != nil {
+		slog.Error("Invalid tracing config", logger.Err(err))
+		os.Exit(1)
+	}
+	shutdownTracing, err := tracing.Init(context.Background(), serviceName, traceOpts)
+	if err != nil {
+		slog.Error("Failed to init tracing", logger.Err(err))
+		os.Exit(1)
+	}
+
 	// 启动广播 goroutine
 	go broadcastMessage()
 
@@ -190,7 +294,11 @@ func main() {
 	go startTimer()
 
 	// 创建 Gin 路由
-	r := gin.Default()
+	r := gin.New()
+	r.Use(tracing.GinMiddleware(serviceName))
+	r.Use(metrics.GinMiddleware(metrics.MiddlewareOptions{}))
+	r.Use(logger.GinMiddleware(slog.Default()))
+	r.Use(gin.Recovery())
 
 	// 静态文件服务（用于提供测试页面）
 	r.Static("/static", "./static")
@@ -206,16 +314,42 @@ func main() {
 		})
 	})
 
+	// Prometheus 指标
+	metrics.RegisterMetrics(r)
+
 	// 根路径，返回 HTML 测试页面
 	r.GET("/", func(c *gin.Context) {
 		c.File("./static/index.html")
 	})
 
-	log.Println("WebSocket server starting on :8080")
-	log.Println("WebSocket endpoint: ws://localhost:8080/ws")
-	log.Println("Test page: http://localhost:8080/")
+	slog.Info("WebSocket server starting",
+		slog.String("addr", listenAddr),
+		slog.String("ws_endpoint", "ws://localhost:8080/ws"),
+		slog.String("test_page", "http://localhost:8080/"),
+	)
 
-	if err := r.Run(":8080"); err != nil {
-		log.Fatal("Server failed to start:", err)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: listenAddr, Handler: r}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-serveErr:
+		slog.Error("Server failed to start", logger.Err(err))
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+	slog.Info("Shutting down")
+
+	// WebSocket 连接已被劫持，Shutdown 不会等待它们；退出前刷新剩余的 span
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shutdown HTTP server", logger.Err(err))
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", logger.Err(err))
 	}
 }
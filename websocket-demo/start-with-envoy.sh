@@ -16,6 +16,28 @@ else
     echo "✅ 后端服务已在运行"
 fi
 
+# 检查鉴权服务是否运行（/ws 需经 ext-authz 鉴权）
+if ! lsof -Pi :9191 -sTCP:LISTEN -t >/dev/null ; then
+    echo "📦 启动鉴权服务..."
+    (cd ../envoy-demo && go run ./cmd/ext-authz -config ext-authz-demo/config.yaml) &
+    AUTHZ_PID=$!
+    echo "✅ 鉴权服务已启动 (PID: $AUTHZ_PID)"
+    sleep 2
+else
+    echo "✅ 鉴权服务已在运行"
+fi
+
+# 检查限流服务是否运行（未运行时 Envoy 放行所有请求）
+if ! lsof -Pi :8081 -sTCP:LISTEN -t >/dev/null ; then
+    echo "📦 启动限流服务..."
+    (cd ../envoy-demo && go run ./cmd/ratelimit -config ratelimit-demo/ratelimit.yaml) &
+    RATELIMIT_PID=$!
+    echo "✅ 限流服务已启动 (PID: $RATELIMIT_PID)"
+    sleep 2
+else
+    echo "✅ 限流服务已在运行"
+fi
+
 # 检查 Envoy 是否运行
 if ! docker ps | grep -q envoy-proxy-ws-demo; then
     echo "📦 启动 Envoy 代理..."
@@ -34,9 +56,11 @@ echo "   - 通过 Envoy 代理: http://localhost:19894"
 echo "   - 直接访问后端: http://localhost:8080"
 echo "   - Envoy 管理界面: http://localhost:19901"
 echo ""
+echo "🔑 通过 Envoy 连接 /ws 需要 token，例如 demo-token（见 ../envoy-demo/ext-authz-demo/config.yaml）"
+echo ""
 echo "按 Ctrl+C 停止服务"
 
 # 等待用户中断
-trap "echo ''; echo '🛑 正在停止服务...'; docker-compose -f envoy-compose.yml down; kill $BACKEND_PID 2>/dev/null; exit" INT
+trap "echo ''; echo '🛑 正在停止服务...'; docker-compose -f envoy-compose.yml down; kill $BACKEND_PID $AUTHZ_PID $RATELIMIT_PID 2>/dev/null; exit" INT
 wait
 